@@ -21,6 +21,7 @@ import (
 	"os"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/pkg/errors"
 	hcl2dotnet "github.com/pulumi/pulumi/pkg/v2/codegen/dotnet"
 	hcl2go "github.com/pulumi/pulumi/pkg/v2/codegen/go"
 	"github.com/pulumi/pulumi/pkg/v2/codegen/hcl2"
@@ -31,6 +32,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 	"github.com/spf13/afero"
 
+	"github.com/pulumi/tf2pulumi/gen"
 	"github.com/pulumi/tf2pulumi/il"
 )
 
@@ -71,12 +73,16 @@ func Convert(opts Options) (map[string][]byte, Diagnostics, error) {
 
 	// Attempt to load the config as TF11 first. If this succeeds, use TF11 semantics unless either the config
 	// or the options specify otherwise.
-	generatedFiles, useTF12, tf11Err := convertTF11(opts)
+	generatedFiles, tf11Diags, useTF12, tf11Err := convertTF11(opts)
+	if useTF12 && opts.CheckDelimiters {
+		return nil, Diagnostics{}, errors.New("delimiter checks are only supported when generating TypeScript from " +
+			"TF11 configuration")
+	}
 	if !useTF12 {
 		if tf11Err != nil {
 			return nil, Diagnostics{}, tf11Err
 		}
-		return generatedFiles, Diagnostics{}, nil
+		return generatedFiles, Diagnostics{All: tf11Diags}, nil
 	}
 
 	var tf12Files []*syntax.File
@@ -92,6 +98,7 @@ func Convert(opts Options) (map[string][]byte, Diagnostics, error) {
 		if parser.Diagnostics.HasErrors() {
 			return nil, Diagnostics{All: parser.Diagnostics, files: parser.Files}, nil
 		}
+		diagnostics = append(diagnostics, tf11Diags...)
 		tf12Files, diagnostics = parser.Files, append(diagnostics, parser.Diagnostics...)
 	} else {
 		files, diags := parseTF12(opts)
@@ -174,6 +181,27 @@ type Options struct {
 	TargetSDKVersion string
 	// The version of Terraform targeteds by the input configuration.
 	TerraformVersion string
+	// CheckDelimiters, if true, checks that the literals, comments, and brackets in the generated code are terminated
+	// and balanced. This is not a full syntax check. This is only supported when
+	// generating TypeScript from TF11 configuration; Convert returns an error if it is set for any other conversion.
+	CheckDelimiters bool
+	// InlineFiles, if true, replaces calls to `file` whose paths can be determined at conversion time with the contents
+	// of the referenced files. This is currently only supported when generating TypeScript from TF11 configuration.
+	InlineFiles bool
+	// AnnotateTypes, if true, follows the code generated for each expression with a comment that describes the type
+	// that was inferred for the expression. This is intended for debugging, and is currently only supported when
+	// generating TypeScript from TF11 configuration.
+	AnnotateTypes bool
+	// Statistics, if non-nil, is filled in with statistics about the converted configuration. This is currently only
+	// supported for TF11 configuration.
+	Statistics *il.Statistics
+	// Manifest, if non-nil, is filled in with the mapping from Terraform resource addresses to the generated Pulumi
+	// resources. This is currently only supported when generating TypeScript from TF11 configuration.
+	Manifest *gen.Manifest
+	// StrictBinding, if true, causes conversion to fail at the first interpolation that cannot be bound. Otherwise,
+	// every binding error is reported as a warning, and the parts of the configuration that did bind are still
+	// converted. This is currently only supported for TF11 configuration.
+	StrictBinding bool
 
 	// TargetOptions captures any target-specific options.
 	TargetOptions interface{}
@@ -8,7 +8,9 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hil/ast"
 	"github.com/pkg/errors"
@@ -22,26 +24,31 @@ import (
 	tf11module "github.com/pulumi/tf2pulumi/internal/config/module"
 )
 
-// convertTF11 converts a TF11 graph to a set of TF12 files.
+// convertTF11 converts a TF11 graph to a set of TF12 files. If the graph is instead converted directly to the target
+// language, any diagnostics reported by the code generator are also returned.
 //
 // Note that the output of the conversion process may not be valid input for Terraform itself: in particular, the block
 // structure of the original source code may not be preserved, so entities that were blocks in the input may be
 // attributes in the output. The TF12 -> PCL converter must be able to handle this sort of input.
-func convertTF11(opts Options) (map[string][]byte, bool, error) {
+func convertTF11(opts Options) (map[string][]byte, hcl.Diagnostics, bool, error) {
 	moduleStorage := tf11module.NewStorage(filepath.Join(".terraform", "modules"))
 
 	mod, err := tf11module.NewTreeFs("", opts.Root)
 	if err != nil {
-		return nil, true, fmt.Errorf("failed to create tree: %w", err)
+		return nil, nil, true, fmt.Errorf("failed to create tree: %w", err)
 	}
 
 	if err = mod.Load(moduleStorage); err != nil {
-		return nil, true, fmt.Errorf("failed to load module: %w", err)
+		return nil, nil, true, fmt.Errorf("failed to load module: %w", err)
 	}
 
-	gs, err := buildGraphs(mod, opts)
+	gs, diagnostics, err := buildGraphs(mod, opts)
 	if err != nil {
-		return nil, true, fmt.Errorf("failed to build graphs: %w", err)
+		return nil, nil, true, fmt.Errorf("failed to build graphs: %w", err)
+	}
+
+	if opts.Statistics != nil {
+		*opts.Statistics = *il.ComputeStatistics(gs)
 	}
 
 	if opts.TerraformVersion == "12" || opts.TargetLanguage != "typescript" {
@@ -49,7 +56,7 @@ func convertTF11(opts Options) (map[string][]byte, bool, error) {
 		g := &tf11generator{}
 		g.Emitter = gen.NewEmitter(nil, g)
 		files, err := g.genModules(gs)
-		return files, true, err
+		return files, diagnostics, true, err
 	}
 
 	// Filter resource name properties if requested.
@@ -81,17 +88,29 @@ func convertTF11(opts Options) (map[string][]byte, bool, error) {
 
 	generator, filename, err := newGenerator(&buf, "auto", opts)
 	if err != nil {
-		return nil, false, errors.Wrapf(err, "creating generator")
+		return nil, nil, false, errors.Wrapf(err, "creating generator")
 	}
 
 	if err = gen.Generate(gs, generator); err != nil {
-		return nil, false, err
+		return nil, nil, false, err
+	}
+
+	if reporter, ok := generator.(gen.DiagnosticReporter); ok {
+		diagnostics = append(diagnostics, reporter.Diagnostics()...)
+	}
+	if reporter, ok := generator.(gen.ManifestReporter); ok && opts.Manifest != nil {
+		*opts.Manifest = *reporter.Manifest()
 	}
 
 	files := map[string][]byte{
 		filename: buf.Bytes(),
 	}
-	return files, false, nil
+	if reporter, ok := generator.(gen.AuxiliaryFileReporter); ok {
+		for name, contents := range reporter.AuxiliaryFiles() {
+			files[name] = contents
+		}
+	}
+	return files, diagnostics, false, nil
 }
 
 func addLocationAnnotation(location token.Pos, comments **il.Comments) {
@@ -133,16 +152,22 @@ func addLocationAnnotations(m *il.Graph) {
 	}
 }
 
-func buildGraphs(tree *tf11module.Tree, opts Options) ([]*il.Graph, error) {
+// buildGraphs builds the graphs for the given module and its descendents. Unless opts.StrictBinding is set, binding
+// errors do not fail the build: they are instead returned as warnings alongside the partially-bound graphs, so that
+// the rest of the configuration is still converted.
+func buildGraphs(tree *tf11module.Tree, opts Options) ([]*il.Graph, hcl.Diagnostics, error) {
 	// TODO: move this into the il package and unify modules based on path
 
-	children := []*il.Graph{}
-	for _, c := range tree.Children() {
-		cc, err := buildGraphs(c, opts)
+	// Build the graphs for the module's descendents first. The last graph built for each child is the graph for the
+	// child itself.
+	children, childModules, diagnostics := []*il.Graph{}, map[string]*il.Graph{}, hcl.Diagnostics(nil)
+	for name, c := range tree.Children() {
+		cc, diags, err := buildGraphs(c, opts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		children = append(children, cc...)
+		children, childModules[name] = append(children, cc...), cc[len(cc)-1]
+		diagnostics = append(diagnostics, diags...)
 	}
 
 	buildOpts := il.BuildOptions{
@@ -151,13 +176,63 @@ func buildGraphs(tree *tf11module.Tree, opts Options) ([]*il.Graph, error) {
 		AllowMissingComments:  opts.AllowMissingComments,
 		ProviderInfoSource:    opts.ProviderInfoSource,
 		Logger:                opts.Logger,
+		ChildModules:          childModules,
+		AccumulateErrors:      !opts.StrictBinding,
+	}
+	modulePrefix := func(summary string) string {
+		if path := tree.Path(); len(path) > 0 {
+			return fmt.Sprintf("module.%s: %s", strings.Join(path, ".module."), summary)
+		}
+		return summary
 	}
+
 	g, err := il.BuildGraph(tree, &buildOpts)
-	if err != nil {
-		return nil, err
+	if merr, ok := err.(*multierror.Error); ok && g != nil {
+		for _, err := range merr.Errors {
+			diagnostics = append(diagnostics, &hcl.Diagnostic{Severity: hcl.DiagWarning, Summary: modulePrefix(err.Error())})
+		}
+	} else if err != nil {
+		return nil, nil, err
 	}
 
-	return append(children, g), nil
+	// Provisioners and connection blocks are not converted, and neither are the self-references that Terraform allows
+	// within them. Warn about each resource that uses them so that their omission is not silent.
+	diagnostics = append(diagnostics, provisionerWarnings(g, modulePrefix)...)
+
+	return append(children, g), diagnostics, nil
+}
+
+// provisionerWarnings returns a warning for each resource in the given graph that has provisioners or connection
+// settings. The prefix function is applied to the summary of each warning.
+func provisionerWarnings(g *il.Graph, prefix func(string) string) hcl.Diagnostics {
+	names := make([]string, 0, len(g.Resources))
+	for name, r := range g.Resources {
+		if len(r.Config.Provisioners) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diagnostics hcl.Diagnostics
+	for _, name := range names {
+		r := g.Resources[name]
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary: prefix(fmt.Sprintf("%s: provisioners and connection settings are not supported, and have not "+
+				"been converted", name)),
+			Detail: "Terraform allows references to `self` within provisioner and connection blocks. These " +
+				"references have no equivalent in the converted resource's inputs, which cannot depend on its own outputs.",
+		}
+		if loc := r.GetLocation(); loc.IsValid() {
+			diag.Subject = &hcl.Range{
+				Filename: loc.Filename,
+				Start:    hcl.Pos{Line: loc.Line, Column: loc.Column, Byte: loc.Offset},
+				End:      hcl.Pos{Line: loc.Line, Column: loc.Column, Byte: loc.Offset},
+			}
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	return diagnostics
 }
 
 func newGenerator(w io.Writer, projectName string, opts Options) (gen.Generator, string, error) {
@@ -167,7 +242,10 @@ func newGenerator(w io.Writer, projectName string, opts Options) (gen.Generator,
 		if !ok && opts.TargetOptions != nil {
 			return nil, "", errors.Errorf("invalid target options of type %T", opts.TargetOptions)
 		}
-		g, err := nodejs.New(projectName, opts.TargetSDKVersion, nodeOpts.UsePromptDataSources, w)
+		nodeOpts.CheckDelimiters = nodeOpts.CheckDelimiters || opts.CheckDelimiters
+		nodeOpts.InlineFiles = nodeOpts.InlineFiles || opts.InlineFiles
+		nodeOpts.AnnotateTypes = nodeOpts.AnnotateTypes || opts.AnnotateTypes
+		g, err := nodejs.NewWithOptions(projectName, opts.TargetSDKVersion, nodeOpts, w)
 		if err != nil {
 			return nil, "", err
 		}
@@ -499,6 +577,13 @@ func (g *tf11generator) GenArithmetic(w io.Writer, n *il.BoundArithmetic) {
 	case ast.ArithmeticOpGreaterThanOrEqual:
 		op = ">="
 	}
+	if len(n.Exprs) == 1 {
+		if n.Op == ast.ArithmeticOpEqual {
+			op = "!"
+		}
+		g.Fgenf(w, "(%s%v)", op, n.Exprs[0])
+		return
+	}
 	op = fmt.Sprintf(" %s ", op)
 
 	g.Fgen(w, "(")
@@ -523,6 +608,9 @@ func (g *tf11generator) GenCall(w io.Writer, n *il.BoundCall) {
 		}
 		g.Fgenf(w, "%v", arg)
 	}
+	if n.ExpandFinal {
+		g.Fgenf(w, "...")
+	}
 	g.Fgenf(w, ")")
 }
 
@@ -534,12 +622,14 @@ func (g *tf11generator) GenConditional(w io.Writer, n *il.BoundConditional) {
 	g.Fgenf(w, "(%v ? %v : %v)", n.CondExpr, n.TrueExpr, n.FalseExpr)
 }
 
-// GenError generates code for a single error expression.
+// GenError generates code for a single error expression. The code generators used by the TF12 pipeline cannot express
+// a runtime error without failing the conversion, so the error is generated as a string literal that describes the
+// error. The error itself is reported as a diagnostic when the graph is built.
 func (g *tf11generator) GenError(w io.Writer, n *il.BoundError) {
 	g.pushExpr(n)
 	defer g.popExpr()
 
-	g.Fgenf(w, "error(%q)", n.Error.Error())
+	g.genStringLiteral(w, "tf2pulumi error: "+n.Error.Error())
 }
 
 // GenIndex generates code for a single index expression.
@@ -0,0 +1,241 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/tf2pulumi/gen"
+	"github.com/pulumi/tf2pulumi/internal/testutil"
+)
+
+func TestBindingErrors(t *testing.T) {
+	const source = `
+resource "test_instance" "web" {
+  instance_type = "${whisper("t2.micro")}"
+
+  tags {
+    Name  = "${shout("web")}"
+    Owner = "ops"
+  }
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	opts := Options{
+		Root:                 fs,
+		ProviderInfoSource:   testProviderInfoSource{},
+		AllowMissingComments: true,
+		TargetLanguage:       LanguageTypescript,
+		TerraformVersion:     "11",
+	}
+
+	// Every binding error is reported, and the rest of the configuration is still converted.
+	files, diags, err := Convert(opts)
+	if err != nil {
+		t.Fatalf("could not convert source: %v", err)
+	}
+	assert.False(t, diags.All.HasErrors(), "%v", diags.All)
+	var summaries []string
+	for _, d := range diags.All {
+		summaries = append(summaries, d.Summary)
+	}
+	assert.ElementsMatch(t, []string{
+		"test_instance.web.instance_type: main.tf:3:22: NYI: call to whisper",
+		"test_instance.web.tags[0].Name: main.tf:6:16: NYI: call to shout",
+	}, summaries)
+	code := string(files["index.ts"])
+	assert.Contains(t, code, `const web = new test.Instance("web", {`)
+	assert.Contains(t, code, `Owner: "ops",`)
+}
+
+func TestBindingErrorsTF12(t *testing.T) {
+	const source = `
+resource "test_instance" "web" {
+  instance_type = "${whisper("t2.micro")}"
+
+  tags {
+    Name  = "${shout("web")}"
+    Owner = "ops"
+  }
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	// When the configuration is converted using the TF12 pipeline, binding errors are reported as warnings and the
+	// rest of the configuration is still converted.
+	opts := Options{
+		Root:                 fs,
+		PluginHost:           testPluginHost(),
+		ProviderInfoSource:   testProviderInfoSource{},
+		AllowMissingComments: true,
+		TargetLanguage:       LanguagePython,
+	}
+	files, diags, err := Convert(opts)
+	if err != nil {
+		t.Fatalf("could not convert source: %v", err)
+	}
+	assert.False(t, diags.All.HasErrors(), "%v", diags.All)
+	var summaries []string
+	for _, d := range diags.All {
+		summaries = append(summaries, d.Summary)
+	}
+	assert.ElementsMatch(t, []string{
+		"test_instance.web.instance_type: main.tf:3:22: NYI: call to whisper",
+		"test_instance.web.tags[0].Name: main.tf:6:16: NYI: call to shout",
+	}, summaries)
+	code := string(files["__main__.py"])
+	assert.Contains(t, code, `instance_type="tf2pulumi error: main.tf:3:22: NYI: call to whisper",`)
+	assert.Contains(t, code, `"Name": "tf2pulumi error: main.tf:6:16: NYI: call to shout",`)
+	assert.Contains(t, code, `"Owner": "ops",`)
+}
+
+func TestCheckDelimiters(t *testing.T) {
+	const source = `
+resource "test_instance" "web" {
+  instance_type = "t2.micro"
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	convert := func(targetLanguage, terraformVersion string) error {
+		_, _, err := Convert(Options{
+			Root:                 fs,
+			ProviderInfoSource:   testProviderInfoSource{},
+			AllowMissingComments: true,
+			TargetLanguage:       targetLanguage,
+			TerraformVersion:     terraformVersion,
+			CheckDelimiters:      true,
+		})
+		return err
+	}
+
+	// Delimiter checks are only supported when generating TypeScript directly from TF11 configuration.
+	assert.NoError(t, convert(LanguageTypescript, "11"))
+	const message = "delimiter checks are only supported when generating TypeScript from TF11 configuration"
+	assert.EqualError(t, convert(LanguageTypescript, "12"), message)
+	assert.EqualError(t, convert(LanguagePython, "11"), message)
+}
+
+func TestProvisionerWarnings(t *testing.T) {
+	const source = `
+resource "test_instance" "web" {
+  instance_type = "t2.micro"
+
+  connection {
+    host = "${self.private_ip}"
+  }
+
+  provisioner "remote-exec" {
+    inline = ["echo ${self.private_ip}"]
+  }
+}
+
+resource "test_instance" "db" {
+  instance_type = "t2.micro"
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	opts := Options{
+		Root:                 fs,
+		ProviderInfoSource:   testProviderInfoSource{},
+		AllowMissingComments: true,
+		TargetLanguage:       LanguageTypescript,
+		TerraformVersion:     "11",
+	}
+
+	// Provisioners and connection settings, and the self-references within them, are not converted. Each resource that
+	// uses them is reported, and the rest of the resource is still converted.
+	files, diags, err := Convert(opts)
+	if err != nil {
+		t.Fatalf("could not convert source: %v", err)
+	}
+	assert.False(t, diags.All.HasErrors(), "%v", diags.All)
+	if assert.Len(t, diags.All, 1) {
+		d := diags.All[0]
+		assert.Equal(t, hcl.DiagWarning, d.Severity)
+		assert.Equal(t, "test_instance.web: provisioners and connection settings are not supported, and have not "+
+			"been converted", d.Summary)
+		assert.Contains(t, d.Detail, "self")
+		if assert.NotNil(t, d.Subject) {
+			assert.Equal(t, 2, d.Subject.Start.Line)
+		}
+	}
+	code := string(files["index.ts"])
+	assert.Contains(t, code, `const web = new test.Instance("web", {`)
+	assert.NotContains(t, code, "self")
+}
+
+func TestGenerateModuleOutputTraversals(t *testing.T) {
+	const childSource = `
+resource "test_vpc" "main" {}
+
+output "vpc_id" {
+  value = "${test_vpc.main.id}"
+}
+`
+	const source = `
+module "network" {
+  source = "./network"
+}
+
+resource "test_instance" "web" {
+  vpc_id = "${module.network.vpc_id}"
+  name   = "web-${module.network.vpc_id}"
+}
+`
+	tree := testutil.LoadModuleTree(t, map[string]string{
+		"main.tf":         source,
+		"network/main.tf": childSource,
+	})
+
+	gs, diags, err := buildGraphs(tree, Options{ProviderInfoSource: testProviderInfoSource{}, AllowMissingComments: true})
+	if err != nil {
+		t.Fatalf("could not build graphs: %v", err)
+	}
+	assert.Empty(t, diags)
+
+	// References to module outputs are generated as traversals of the module. The root graph is the last graph.
+	g := &tf11generator{}
+	g.Emitter = gen.NewEmitter(nil, g)
+	files, err := g.genModules(gs[len(gs)-1:])
+	if err != nil {
+		t.Fatalf("could not generate TF12 source: %v", err)
+	}
+	code := string(files["main.tf"])
+	assert.Contains(t, code, "vpc_id = module.network.vpc_id")
+	assert.Contains(t, code, `name = "web-${module.network.vpc_id}"`)
+}
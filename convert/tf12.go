@@ -852,14 +852,20 @@ func (b *tf12binder) bindResource(r *resource) hcl.Diagnostics {
 
 			rangeExpr = count.Value
 			b.annotateExpressionsWithSchemas(count)
+			if s, ok := b.exprToSchemas[rangeExpr]; ok {
+				b.variableToSchemas[r.rangeVariable] = func() il.Schemas {
+					return s
+				}
+			}
 		} else {
 			forEach, _ := block.Body.Attribute("for_each")
 			rangeExpr = forEach.Value
 			b.annotateExpressionsWithSchemas(forEach)
-		}
-		if s, ok := b.exprToSchemas[rangeExpr]; ok {
+
+			collection := b.exprToSchemas[rangeExpr]
+			valueType := r.rangeVariable.VariableType.(*model.ObjectType).Properties["value"]
 			b.variableToSchemas[r.rangeVariable] = func() il.Schemas {
-				return s
+				return forEachSchemas(collection, valueType)
 			}
 		}
 	}
@@ -869,6 +875,65 @@ func (b *tf12binder) bindResource(r *resource) hcl.Diagnostics {
 	return diagnostics
 }
 
+// forEachSchemas returns the Schemas for the `each` variable of a resource that uses `for_each` to iterate over a
+// collection with the given Schemas and element type. `each.key` and `each.value` are not renamed. If the collection's
+// Schemas do not describe its elements, the names of the fields of object-typed elements are also preserved.
+func forEachSchemas(collection il.Schemas, valueType model.Type) il.Schemas {
+	value := collection.ElemSchemas()
+
+	var valueTF *schema.Schema
+	switch {
+	case value.TF != nil:
+		valueTF = value.TF
+	case value.TFRes != nil:
+		valueTF = &schema.Schema{Type: schema.TypeList, MaxItems: 1, Elem: value.TFRes}
+	}
+
+	valueInfo := typeSchemaInfo(valueType)
+	if value.Pulumi != nil {
+		info := *value.Pulumi
+		valueInfo = &info
+	}
+	valueInfo.Name = "value"
+
+	return il.Schemas{
+		TFRes: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"key":   {Type: schema.TypeString},
+				"value": valueTF,
+			},
+		},
+		Pulumi: &tfbridge.SchemaInfo{
+			Fields: map[string]*tfbridge.SchemaInfo{
+				"key":   {Name: "key"},
+				"value": valueInfo,
+			},
+		},
+	}
+}
+
+// typeSchemaInfo returns schema information that preserves the names of the fields of the given type and of the
+// fields of its elements.
+func typeSchemaInfo(t model.Type) *tfbridge.SchemaInfo {
+	info := &tfbridge.SchemaInfo{}
+	switch t := t.(type) {
+	case *model.ObjectType:
+		info.Fields = map[string]*tfbridge.SchemaInfo{}
+		for name, t := range t.Properties {
+			field := typeSchemaInfo(t)
+			field.Name = name
+			info.Fields[name] = field
+		}
+	case *model.ListType:
+		info.Elem = typeSchemaInfo(t.ElementType)
+	case *model.MapType:
+		info.Elem = typeSchemaInfo(t.ElementType)
+	case *model.SetType:
+		info.Elem = typeSchemaInfo(t.ElementType)
+	}
+	return info
+}
+
 func (b *tf12binder) genBodyItem(w io.Writer, item *bodyItem) hcl.Diagnostics {
 	_, err := fmt.Fprintf(w, "%v", item.item)
 	contract.IgnoreError(err)
@@ -1362,18 +1427,149 @@ func (b *tf12binder) rewriteExpression(n model.Expression, resource *resource) (
 	return model.VisitExpression(n, model.IdentityVisitor, visitor)
 }
 
-func (b *tf12binder) rewriteFunctionCall(
-	n *model.FunctionCallExpression) (*model.FunctionCallExpression, hcl.Diagnostics) {
-
+func (b *tf12binder) rewriteFunctionCall(n *model.FunctionCallExpression) (model.Expression, hcl.Diagnostics) {
 	switch n.Name {
+	case "can":
+		if len(n.Args) == 1 {
+			if guard, ok := b.indexGuard(n.Args[0]); ok {
+				if guard == nil {
+					guard = &model.LiteralValueExpression{Value: cty.True}
+				}
+				return replaceFunctionCall(n, guard), nil
+			}
+		}
 	case "file":
 		n.Name = "readFile"
 	case "jsonencode":
 		n.Name = "toJSON"
+	case "try":
+		if len(n.Args) > 1 {
+			if x, ok := b.rewriteTry(n.Args); ok {
+				return replaceFunctionCall(n, x), nil
+			}
+		}
 	}
 	return n, nil
 }
 
+// replaceFunctionCall moves the trivia of the given call to its replacement. The trailing trivia of the call's
+// arguments is discarded, as the arguments may be rearranged by the replacement.
+func replaceFunctionCall(n *model.FunctionCallExpression, x model.Expression) model.Expression {
+	leadingTrivia, trailingTrivia := n.GetLeadingTrivia(), n.GetTrailingTrivia()
+	for _, arg := range n.Args {
+		arg.SetTrailingTrivia(nil)
+	}
+	x.SetLeadingTrivia(leadingTrivia)
+	x.SetTrailingTrivia(trailingTrivia)
+	return x
+}
+
+// rewriteTry rewrites a call to `try` into a chain of conditionals that check the bounds of each list index in the
+// call's arguments, e.g. `try(local.names[0], "")` becomes `length(names) > 0 ? names[0] : ""`. This covers the common
+// use of `try` to guard indexing into a possibly-empty list. If any argument other than the last does not index into a
+// list or may fail for some other reason, rewriteTry returns false.
+func (b *tf12binder) rewriteTry(args []model.Expression) (model.Expression, bool) {
+	if len(args) == 1 {
+		return args[0], true
+	}
+
+	guard, ok := b.indexGuard(args[0])
+	if !ok || guard == nil {
+		return nil, false
+	}
+	falseResult, ok := b.rewriteTry(args[1:])
+	if !ok {
+		return nil, false
+	}
+	args[0].SetLeadingTrivia(syntax.TriviaList{syntax.NewWhitespace(' ')})
+	falseResult.SetLeadingTrivia(syntax.TriviaList{syntax.NewWhitespace(' ')})
+	return &model.ConditionalExpression{
+		Condition:   guard,
+		TrueResult:  args[0],
+		FalseResult: falseResult,
+	}, true
+}
+
+// indexGuard returns an expression that evaluates to true if each list index in the given traversal is in bounds. The
+// returned guard is nil if the traversal does not index into any lists. indexGuard returns false if the expression is
+// not a scope traversal or contains a non-numeric index.
+//
+// The bounds of a counted resource are checked against the resource's count rather than the length of its list of
+// instances: the latter observes the instances' outputs, which would force the indexed access to be evaluated before
+// the guard.
+func (b *tf12binder) indexGuard(x model.Expression) (model.Expression, bool) {
+	traversal, ok := x.(*model.ScopeTraversalExpression)
+	if !ok {
+		return nil, false
+	}
+
+	var guard model.Expression
+	for i, traverser := range traversal.Traversal {
+		index, ok := traverser.(hcl.TraverseIndex)
+		if !ok {
+			continue
+		}
+		if index.Key.Type() != cty.Number {
+			return nil, false
+		}
+
+		var length model.Expression
+		if r, ok := traversal.Parts[0].(*resource); ok && r.isCounted && i == 1 {
+			count, ok := b.resourceCount(r)
+			if !ok {
+				return nil, false
+			}
+			length = count
+		} else {
+			length = &model.FunctionCallExpression{
+				Name: "length",
+				Args: []model.Expression{&model.ScopeTraversalExpression{
+					RootName:  traversal.RootName,
+					Traversal: traversal.Traversal[:i],
+					Parts:     traversal.Parts[:i],
+				}},
+			}
+		}
+		inBounds := &model.BinaryOpExpression{
+			LeftOperand:  length,
+			Operation:    hclsyntax.OpGreaterThan,
+			RightOperand: &model.LiteralValueExpression{Value: index.Key},
+		}
+		inBounds.RightOperand.SetLeadingTrivia(syntax.TriviaList{syntax.NewWhitespace(' ')})
+		if guard == nil {
+			guard = inBounds
+		} else {
+			guard = &model.BinaryOpExpression{
+				LeftOperand:  guard,
+				Operation:    hclsyntax.OpLogicalAnd,
+				RightOperand: inBounds,
+			}
+		}
+	}
+	return guard, true
+}
+
+// resourceCount binds and rewrites a fresh copy of the given counted resource's count. The copy is necessary because
+// the resource's own count may not have been rewritten yet, and an expression may not appear twice in the rewritten
+// program. resourceCount returns false if the count could not be bound or rewritten.
+func (b *tf12binder) resourceCount(r *resource) (model.Expression, bool) {
+	attr, ok := r.syntax.Body.Attributes["count"]
+	if !ok {
+		return nil, false
+	}
+	count, diags := model.BindExpression(attr.Expr, b.root, b.tokens, b.hcl2Options...)
+	if diags.HasErrors() {
+		return nil, false
+	}
+	count, diags = b.rewriteExpression(count, nil)
+	if diags.HasErrors() {
+		return nil, false
+	}
+	count.SetLeadingTrivia(nil)
+	count.SetTrailingTrivia(nil)
+	return count, true
+}
+
 func internalTrivia(traversal []syntax.TraverserTokens) (syntax.TriviaList, syntax.TriviaList) {
 	var leadingTrivia, trailingTrivia syntax.TriviaList
 	for i, traverser := range traversal {
@@ -1758,6 +1954,13 @@ func (b *tf12binder) providerType(providerName string,
 }
 
 var tf12builtins = map[string]*model.Function{
+	"can": model.NewFunction(model.StaticFunctionSignature{
+		Parameters: []model.Parameter{{
+			Name: "expression",
+			Type: model.DynamicType,
+		}},
+		ReturnType: model.BoolType,
+	}),
 	"cidrsubnet": model.NewFunction(model.StaticFunctionSignature{
 		Parameters: []model.Parameter{
 			{
@@ -1908,4 +2111,21 @@ var tf12builtins = map[string]*model.Function{
 		},
 		ReturnType: model.NewListType(model.StringType),
 	}),
+	"try": model.NewFunction(model.GenericFunctionSignature(
+		func(args []model.Expression) (model.StaticFunctionSignature, hcl.Diagnostics) {
+			var signature model.StaticFunctionSignature
+			var returnType model.Type
+			for _, arg := range args {
+				signature.Parameters = append(signature.Parameters, model.Parameter{
+					Name: "expression",
+					Type: arg.Type(),
+				})
+				_, returnType = model.UnifyTypes(returnType, arg.Type())
+			}
+			if returnType == nil {
+				returnType = model.DynamicType
+			}
+			signature.ReturnType = returnType
+			return signature, nil
+		})),
 }
@@ -0,0 +1,247 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
+	"github.com/pulumi/pulumi/pkg/v2/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/plugin"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// testProviderSchema is the Pulumi schema for the "test" provider.
+const testProviderSchema = `{
+	"name": "test",
+	"resources": {
+		"test:index/instance:Instance": {
+			"inputProperties": {
+				"instanceType": {"type": "string"},
+				"tags": {"type": "object", "additionalProperties": {"type": "string"}}
+			},
+			"properties": {
+				"instanceType": {"type": "string"},
+				"tags": {"type": "object", "additionalProperties": {"type": "string"}}
+			}
+		}
+	}
+}`
+
+// testProviderInfoSource serves the Terraform schema for the "test" provider.
+type testProviderInfoSource struct{}
+
+func (testProviderInfoSource) GetProviderInfo(tfProviderName string) (*tfbridge.ProviderInfo, error) {
+	if tfProviderName != "test" {
+		return nil, errors.Errorf("no provider info for %s", tfProviderName)
+	}
+	return &tfbridge.ProviderInfo{
+		P: &schema.Provider{
+			ResourcesMap: map[string]*schema.Resource{
+				"test_instance": {
+					Schema: map[string]*schema.Schema{
+						"instance_type": {Type: schema.TypeString, Optional: true},
+						"tags":          {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+					},
+				},
+			},
+		},
+		Resources: map[string]*tfbridge.ResourceInfo{
+			"test_instance": {Tok: "test:index/instance:Instance"},
+		},
+	}, nil
+}
+
+// testPluginHost returns a plugin host that serves the Pulumi schema for the "test" provider.
+func testPluginHost() plugin.Host {
+	loader := deploytest.NewProviderLoader("test", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+		return &deploytest.Provider{
+			GetSchemaF: func(version int) ([]byte, error) {
+				return []byte(testProviderSchema), nil
+			},
+		}, nil
+	})
+	return deploytest.NewPluginHost(nil, nil, nil, loader)
+}
+
+// convertTF12Source converts the given TF12 source to the given target language using the "test" provider.
+func convertTF12Source(t *testing.T, source, targetLanguage string) map[string][]byte {
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	files, diags, err := Convert(Options{
+		Root:               fs,
+		PluginHost:         testPluginHost(),
+		ProviderInfoSource: testProviderInfoSource{},
+		TargetLanguage:     targetLanguage,
+		TerraformVersion:   "12",
+	})
+	if err != nil {
+		t.Fatalf("could not convert source: %v", err)
+	}
+	if !assert.False(t, diags.All.HasErrors(), "%v", diags.All) {
+		t.FailNow()
+	}
+	return files
+}
+
+func TestForEachObjectValues(t *testing.T) {
+	const source = `
+variable "instances" {
+  type = map(object({
+    instance_type = string
+    owner_name    = string
+  }))
+}
+
+resource "test_instance" "web" {
+  for_each      = var.instances
+  instance_type = each.value.instance_type
+
+  tags = {
+    Name  = each.key
+    Owner = each.value.owner_name
+  }
+}
+`
+	files := convertTF12Source(t, source, LanguagePulumi)
+	code := string(files["main.tf.pp"])
+
+	// The fields of each element of the map are accessed using their original names.
+	assert.Contains(t, code, "instanceType = range.value.instance_type")
+	assert.Contains(t, code, "Owner = range.value.owner_name")
+	assert.Contains(t, code, "Name  = range.key")
+
+	files = convertTF12Source(t, source, LanguageTypescript)
+	assert.Contains(t, string(files["index.ts"]), "instanceType: range.value.instance_type,")
+}
+
+func TestTryIndex(t *testing.T) {
+	const source = `
+variable "instance_count" {
+  type = number
+}
+
+resource "test_instance" "web" {
+  count         = var.instance_count
+  instance_type = "t2.micro"
+}
+
+output "first_id" {
+  value = try(test_instance.web[0].id, "none")
+}
+
+output "has_instances" {
+  value = can(test_instance.web[0].id)
+}
+`
+	files := convertTF12Source(t, source, LanguagePulumi)
+	code := string(files["main.tf.pp"])
+
+	// Indices into the counted resource are guarded by a bounds check against its count.
+	assert.Contains(t, code, `value = instanceCount > 0 ? web[0].id : "none"`)
+	assert.Contains(t, code, "value = instanceCount > 0\n")
+
+	// The guard must be checked before the resource is indexed.
+	files = convertTF12Source(t, source, LanguageTypescript)
+	assert.Contains(t, string(files["index.ts"]), `export const firstId = instanceCount > 0 ? web[0].id : "none";`)
+}
+
+func TestTryUnguardedAttribute(t *testing.T) {
+	const source = `
+variable "settings" {
+}
+
+output "setting" {
+  value = try(var.settings.name, "none")
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	_, diags, err := Convert(Options{
+		Root:               fs,
+		PluginHost:         testPluginHost(),
+		ProviderInfoSource: testProviderInfoSource{},
+		TargetLanguage:     LanguagePulumi,
+		TerraformVersion:   "12",
+	})
+	if err != nil {
+		t.Fatalf("could not convert source: %v", err)
+	}
+
+	// A try that cannot be rewritten into a bounds check is reported rather than losing its fallback.
+	assert.True(t, diags.All.HasErrors())
+	assert.Contains(t, diags.All.Error(), "unknown function 'try'")
+}
+
+func TestMixedInterpolationSyntax(t *testing.T) {
+	const source = `
+variable "name" {
+  type = string
+}
+
+resource "test_instance" "quoted" {
+  instance_type = "${var.name}"
+
+  tags = {
+    Name   = "${var.name}-web"
+    Length = "${length(var.name)}"
+  }
+}
+
+resource "test_instance" "bare" {
+  instance_type = var.name
+
+  tags = {
+    Name   = "${var.name}-web"
+    Length = length(var.name)
+  }
+}
+`
+	// Quoted interpolations that consist of a single expression are converted in the same way as bare expressions.
+	files := convertTF12Source(t, source, LanguagePulumi)
+	code := string(files["main.tf.pp"])
+	const body = `"test:index/instance:Instance" {
+  instanceType = name
+  tags = {
+    Name   = "${name}-web"
+    Length = length(name)
+  }
+}`
+	assert.Contains(t, code, "resource quoted "+body)
+	assert.Contains(t, code, "resource bare "+body)
+
+	files = convertTF12Source(t, source, LanguageTypescript)
+	const args = `{
+    instanceType: name,
+    tags: {
+        Name: ` + "`${name}-web`" + `,
+        Length: name.length,
+    },
+});`
+	assert.Contains(t, string(files["index.ts"]), `const quoted = new test.index.instance.Instance("quoted", `+args)
+	assert.Contains(t, string(files["index.ts"]), `const bare = new test.index.instance.Instance("bare", `+args)
+}
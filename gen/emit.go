@@ -9,31 +9,8 @@ import (
 	"github.com/pulumi/tf2pulumi/il"
 )
 
-// HILGenerator is an interface that
-type HILGenerator interface {
-	// GenArithmetic generates code for the indicated arithmetic node to the given writer.
-	GenArithmetic(w io.Writer, v *il.BoundArithmetic)
-	// GenCall generates code for the indicated call node to the given writer.
-	GenCall(w io.Writer, v *il.BoundCall)
-	// GenConditional generates code for the indicated conditional node to the given writer.
-	GenConditional(w io.Writer, v *il.BoundConditional)
-	// GenError generates code for the indicated error node to the given writer.
-	GenError(w io.Writer, v *il.BoundError)
-	// GenIndex generates code for the indicated index node to the given writer.
-	GenIndex(w io.Writer, v *il.BoundIndex)
-	// GenListProperty generates code for the indicated list property to the given writer.
-	GenListProperty(w io.Writer, v *il.BoundListProperty)
-	// GenLiteral generates code for the indicated literal node to the given writer.
-	GenLiteral(w io.Writer, v *il.BoundLiteral)
-	// GenMapProperty generates code for the indicated map property to the given writer.
-	GenMapProperty(w io.Writer, v *il.BoundMapProperty)
-	// GenOutput generates code for the indicated output node to the given writer.
-	GenOutput(w io.Writer, v *il.BoundOutput)
-	// GenPropertyValue generates code for the indicated property value node to the given writer.
-	GenPropertyValue(w io.Writer, v *il.BoundPropertyValue)
-	// GenVariableAccess generates code for the indicated variable access node to the given writer.
-	GenVariableAccess(w io.Writer, v *il.BoundVariableAccess)
-}
+// HILGenerator generates code for bound nodes. It is an alias for il.BoundNodeGenerator.
+type HILGenerator = il.BoundNodeGenerator
 
 // Emitter is a convenience type that implements a number of common utilities used to emit source code. It implements
 // the io.Writer interface.
@@ -90,35 +67,15 @@ func (e *Emitter) Gen(vs ...interface{}) {
 }
 
 // Fgen generates code for a list of strings and expression trees. The former are written directly to the destination;
-// the latter are recursively generated using the appropriate gen* functions.
+// the latter are recursively generated by dispatching to the appropriate method of the emitter's HILGenerator.
 func (e *Emitter) Fgen(w io.Writer, vs ...interface{}) {
 	for _, v := range vs {
 		switch v := v.(type) {
 		case string:
 			_, err := fmt.Fprint(w, v)
 			contract.IgnoreError(err)
-		case *il.BoundArithmetic:
-			e.g.GenArithmetic(w, v)
-		case *il.BoundCall:
-			e.g.GenCall(w, v)
-		case *il.BoundConditional:
-			e.g.GenConditional(w, v)
-		case *il.BoundIndex:
-			e.g.GenIndex(w, v)
-		case *il.BoundLiteral:
-			e.g.GenLiteral(w, v)
-		case *il.BoundOutput:
-			e.g.GenOutput(w, v)
-		case *il.BoundPropertyValue:
-			e.g.GenPropertyValue(w, v)
-		case *il.BoundVariableAccess:
-			e.g.GenVariableAccess(w, v)
-		case *il.BoundListProperty:
-			e.g.GenListProperty(w, v)
-		case *il.BoundMapProperty:
-			e.g.GenMapProperty(w, v)
-		case *il.BoundError:
-			e.g.GenError(w, v)
+		case il.BoundNode:
+			v.Generate(w, e.g)
 		default:
 			contract.Failf("unexpected type in gen: %T", v)
 		}
@@ -0,0 +1,62 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/tf2pulumi/il"
+)
+
+// kindGen is an HILGenerator that generates the kind of each node.
+type kindGen struct{}
+
+// genKind writes the given node kind to the given writer.
+func genKind(w io.Writer, kind string) {
+	_, err := io.WriteString(w, kind)
+	contract.IgnoreError(err)
+}
+
+func (kindGen) GenArithmetic(w io.Writer, v *il.BoundArithmetic)         { genKind(w, "arithmetic") }
+func (kindGen) GenCall(w io.Writer, v *il.BoundCall)                     { genKind(w, "call") }
+func (kindGen) GenConditional(w io.Writer, v *il.BoundConditional)       { genKind(w, "conditional") }
+func (kindGen) GenError(w io.Writer, v *il.BoundError)                   { genKind(w, "error") }
+func (kindGen) GenIndex(w io.Writer, v *il.BoundIndex)                   { genKind(w, "index") }
+func (kindGen) GenListProperty(w io.Writer, v *il.BoundListProperty)     { genKind(w, "list") }
+func (kindGen) GenLiteral(w io.Writer, v *il.BoundLiteral)               { genKind(w, "literal") }
+func (kindGen) GenMapProperty(w io.Writer, v *il.BoundMapProperty)       { genKind(w, "map") }
+func (kindGen) GenOutput(w io.Writer, v *il.BoundOutput)                 { genKind(w, "output") }
+func (kindGen) GenPropertyValue(w io.Writer, v *il.BoundPropertyValue)   { genKind(w, "value") }
+func (kindGen) GenVariableAccess(w io.Writer, v *il.BoundVariableAccess) { genKind(w, "variable") }
+
+func TestFgenDispatch(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEmitter(&b, kindGen{})
+
+	e.Fgen(&b, "(", &il.BoundArithmetic{}, ", ", &il.BoundCall{}, ", ", &il.BoundConditional{}, ", ", &il.BoundError{},
+		", ", &il.BoundIndex{}, ", ", &il.BoundListProperty{}, ", ", &il.BoundLiteral{}, ", ", &il.BoundMapProperty{},
+		", ", &il.BoundOutput{}, ", ", &il.BoundPropertyValue{}, ", ", &il.BoundVariableAccess{}, ")")
+	assert.Equal(t, "(arithmetic, call, conditional, error, index, list, literal, map, output, value, variable)",
+		b.String())
+
+	b.Reset()
+	e.Fgenf(&b, "f(%v)", &il.BoundLiteral{})
+	assert.Equal(t, "f(literal)", b.String())
+}
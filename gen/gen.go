@@ -17,6 +17,7 @@ package gen
 import (
 	"sort"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/tf2pulumi/il"
@@ -46,6 +47,27 @@ type Generator interface {
 	GenerateOutputs(os []*il.OutputNode) error
 }
 
+// DiagnosticReporter is implemented by Generators that report diagnostics (e.g. warnings about potentially-lossy
+// conversions) for the code they generate.
+type DiagnosticReporter interface {
+	// Diagnostics returns the diagnostics reported for the generated code.
+	Diagnostics() hcl.Diagnostics
+}
+
+// AuxiliaryFileReporter is implemented by Generators that produce files in addition to the generated program, e.g. a
+// module that defines helper functions used by the program.
+type AuxiliaryFileReporter interface {
+	// AuxiliaryFiles returns the contents of each additional file, keyed by filename.
+	AuxiliaryFiles() map[string][]byte
+}
+
+// ManifestReporter is implemented by Generators that record the correspondence between Terraform resources and the
+// Pulumi resources they generate.
+type ManifestReporter interface {
+	// Manifest returns the manifest for the generated code.
+	Manifest() *Manifest
+}
+
 // sortNodesBySourceOrder sorts the given slice of nodes by file, then line, then column, then node ID.
 func sortNodesBySourceOrder(n []il.Node) []il.Node {
 	sort.Slice(n, func(i, j int) bool {
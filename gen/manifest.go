@@ -0,0 +1,51 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ManifestEntry maps a Terraform resource to the Pulumi resource that is generated for it.
+type ManifestEntry struct {
+	// Address is the Terraform address of the resource, e.g. "aws_instance.web" or "module.vpc.aws_vpc.main".
+	Address string `json:"address"`
+	// Type is the Pulumi type token of the resource, if known.
+	Type string `json:"type,omitempty"`
+	// Variable is the name of the variable that holds the resource in the generated code. The variables that hold the
+	// resources in a child module are local to the module's factory function.
+	Variable string `json:"variable"`
+	// Name is the logical name of the Pulumi resource.
+	Name string `json:"name"`
+	// Counted is true if the resource has multiple instances. The address of each instance is the resource's address
+	// followed by the instance's index in brackets, and the name of each instance is the resource's name followed by a
+	// hyphen and the instance's index.
+	Counted bool `json:"counted,omitempty"`
+}
+
+// Manifest maps the Terraform resources in a converted configuration to the Pulumi resources that are generated for
+// them. A manifest can be used to import existing Terraform state into Pulumi or to review a conversion.
+type Manifest struct {
+	// Resources is the list of resource mappings, sorted by address.
+	Resources []ManifestEntry `json:"resources"`
+}
+
+// WriteJSON writes the manifest to the given writer as indented JSON.
+func (m *Manifest) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(m)
+}
@@ -0,0 +1,92 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+
+	"github.com/pulumi/tf2pulumi/il"
+)
+
+// typeAnnotator is an HILGenerator that follows the code generated for each bound node with a comment that describes
+// the node's bound type, e.g. `main.id /* :output<string> */`. The annotations are intended to help debug the binder
+// and the transforms that are applied to bound nodes prior to code generation.
+type typeAnnotator struct {
+	g *generator
+}
+
+// annotate writes a comment that describes the type of the given node.
+func (a typeAnnotator) annotate(w io.Writer, n il.BoundNode) {
+	_, err := fmt.Fprintf(w, " /* :%v */", n.Type())
+	contract.IgnoreError(err)
+}
+
+func (a typeAnnotator) GenArithmetic(w io.Writer, n *il.BoundArithmetic) {
+	a.g.GenArithmetic(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenCall(w io.Writer, n *il.BoundCall) {
+	a.g.GenCall(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenConditional(w io.Writer, n *il.BoundConditional) {
+	a.g.GenConditional(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenError(w io.Writer, n *il.BoundError) {
+	a.g.GenError(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenIndex(w io.Writer, n *il.BoundIndex) {
+	a.g.GenIndex(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenListProperty(w io.Writer, n *il.BoundListProperty) {
+	a.g.GenListProperty(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenLiteral(w io.Writer, n *il.BoundLiteral) {
+	a.g.GenLiteral(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenMapProperty(w io.Writer, n *il.BoundMapProperty) {
+	a.g.GenMapProperty(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenOutput(w io.Writer, n *il.BoundOutput) {
+	a.g.GenOutput(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenPropertyValue(w io.Writer, n *il.BoundPropertyValue) {
+	a.g.GenPropertyValue(w, n)
+	a.annotate(w, n)
+}
+
+func (a typeAnnotator) GenVariableAccess(w io.Writer, n *il.BoundVariableAccess) {
+	a.g.GenVariableAccess(w, n)
+	a.annotate(w, n)
+}
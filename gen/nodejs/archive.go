@@ -120,13 +120,15 @@ func (g *generator) generateArchive(r *il.ResourceNode) error {
 		if err != nil {
 			return err
 		}
-		inputs, err := g.computeArchiveInputs(r, true, "i")
+		index := g.pushTemporary("i")
+		defer g.popTemporary()
+		inputs, err := g.computeArchiveInputs(r, true, index)
 		if err != nil {
 			return err
 		}
 
 		g.Printf("const %s: pulumi.asset.AssetArchive[] = [];\n", name)
-		g.Printf("for (let i = 0; i < %s; i++) {\n", count)
+		g.Printf("for (let %s = 0; %s < %s; %s++) {\n", index, index, count, index)
 		g.Printf("    %s.push(new pulumi.asset.AssetArchive(%s));\n", name, inputs)
 		g.Printf("}")
 	}
@@ -29,6 +29,7 @@ type nameTable struct {
 	names        map[il.Node]string
 	assigned     map[string]bool
 	isRootModule bool
+	typeMapper   ResourceTypeMapper
 }
 
 // isReservedWord returns true if s is a reserved word as per ECMA-262.
@@ -163,7 +164,7 @@ func (nt *nameTable) disambiguateResourceName(n *il.ResourceNode) string {
 
 	// Determine the resource's NodeJS package, module, and type name. These will be used during the disambiguation
 	// process. If these names cannot be determined, return an ugly name comprised of the TF type and name.
-	packageName, moduleName, typeName, err := resourceTypeName(n)
+	packageName, moduleName, typeName, err := resourceTypeName(n, nt.typeMapper)
 	if err != nil {
 		return cleanName(n.Type + "_" + n.Name)
 	}
@@ -197,11 +198,14 @@ func (nt *nameTable) assignResource(n *il.ResourceNode) {
 	nt.names[n], nt.assigned[name] = name, true
 }
 
-func assignNames(g *il.Graph, importNames map[string]bool, isRootModule bool) map[il.Node]string {
+func assignNames(g *il.Graph, importNames map[string]bool, isRootModule bool,
+	typeMapper ResourceTypeMapper) map[il.Node]string {
+
 	nt := &nameTable{
 		names:        make(map[il.Node]string),
 		assigned:     make(map[string]bool),
 		isRootModule: isRootModule,
+		typeMapper:   typeMapper,
 	}
 
 	// Seed the set of assigned names with the names of imported modules.
@@ -242,8 +246,8 @@ func assignNames(g *il.Graph, importNames map[string]bool, isRootModule bool) ma
 		name, _ := nt.tsName(n.Name)
 		resourceGroups[name] = append(resourceGroups[name], n)
 	}
-	for name, group := range resourceGroups {
-		if len(group) == 1 {
+	for _, name := range gen.SortedKeys(resourceGroups) {
+		if group := resourceGroups[name]; len(group) == 1 {
 			// If there is only one resource in this group, allow disambiguation to happen normally.
 			nt.assignResource(group[0])
 		} else {
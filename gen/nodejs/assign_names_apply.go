@@ -103,6 +103,10 @@ func (nt *applyNameTable) disambiguateArgName(n *il.BoundVariableAccess, bestNam
 	}
 }
 
+// assignApplyArgNames assigns names to the parameters of the callback passed to an apply. Each parameter is named after
+// the output it receives (e.g. `cidrBlock` for `aws_vpc.vpc.cidr_block`). If that name is ambiguous, it is qualified
+// with the name of the referenced resource or module (e.g. `vpcId` and `webId` for `aws_vpc.vpc.id` and
+// `aws_instance.web.id`) and then, if necessary, with a numeric suffix.
 func (g *generator) assignApplyArgNames(applyArgs []*il.BoundVariableAccess, then il.BoundExpr) []string {
 	nt := &applyNameTable{
 		g:          g,
@@ -0,0 +1,67 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// pushTemporary assigns a name to a temporary that is introduced by generated code (e.g. the parameter of a callback
+// passed to `.map`) and brings it into scope. The name is the given base name with an integer suffix starting with 1
+// appended if necessary in order to avoid shadowing any name that is already in scope. Names are assigned
+// deterministically, so nested temporaries always receive the same names. The temporary must be removed from scope
+// by calling popTemporary once the code that references it has been generated.
+func (g *generator) pushTemporary(base string) string {
+	name := base
+	for i := 1; g.isNameInScope(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.temporaries = append(g.temporaries, name)
+	return name
+}
+
+// popTemporary removes the most recently pushed temporary from scope.
+func (g *generator) popTemporary() {
+	contract.Assert(len(g.temporaries) > 0)
+	g.temporaries = g.temporaries[:len(g.temporaries)-1]
+}
+
+// isNameInScope returns true if the given name refers to an import, a top-level node, the current count variable, an
+// apply argument, or a temporary in the current scope.
+func (g *generator) isNameInScope(name string) bool {
+	if g.importNames[name] || name == g.countIndex {
+		return true
+	}
+	for _, n := range g.nameTable {
+		if n == name {
+			return true
+		}
+	}
+	if g.applyArgs != nil {
+		for _, n := range g.applyArgNames {
+			if n == name {
+				return true
+			}
+		}
+	}
+	for _, n := range g.temporaries {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -137,7 +137,7 @@ func TestAssignNames(t *testing.T) {
 		}),
 	}
 
-	names := assignNames(g, map[string]bool{}, true)
+	names := assignNames(g, map[string]bool{}, true, nil)
 
 	assert.Equal(t, "vpcId", names[g.Outputs["vpc_id"]])
 	assert.Equal(t, "securityGroupId", names[g.Outputs["security_group_id"]])
@@ -221,7 +221,7 @@ func TestAssignApplyNames(t *testing.T) {
 		}),
 	}
 
-	g := &generator{nameTable: assignNames(m, map[string]bool{}, true)}
+	g := &generator{nameTable: assignNames(m, map[string]bool{}, true, nil)}
 
 	args := []*il.BoundVariableAccess{
 		boundRef("local.name", il.TypeString.OutputOf(), m.Locals["name"]),
@@ -0,0 +1,522 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pulumi/tf2pulumi/gen"
+	"github.com/pulumi/tf2pulumi/il"
+)
+
+// A FunctionGenerator generates TypeScript for a call to a Terraform interpolation function. The emitter's Fgen and
+// Fgenf methods may be used to generate the call's arguments.
+type FunctionGenerator func(e *gen.Emitter, w io.Writer, n *il.BoundCall)
+
+// A Function describes the TypeScript that is generated for calls to a Terraform interpolation function.
+type Function struct {
+	// Generate generates TypeScript for a call to the function.
+	Generate FunctionGenerator
+	// Imports maps the names bound by the import statements that the generated code requires to the statements
+	// themselves, e.g. "os" to `import * as os from "os";`.
+	Imports map[string]string
+	// Helpers maps the names of the helper functions that the generated code calls to their definitions. Each
+	// definition must declare a function with the corresponding name. Helpers are emitted into the program's utilities
+	// module and imported from there.
+	Helpers map[string]string
+}
+
+// functionGenerator generates TypeScript for calls to a Terraform interpolation function using the generator's state.
+type functionGenerator struct {
+	// generate generates TypeScript for a call to the function.
+	generate func(g *generator, w io.Writer, n *il.BoundCall)
+	// requires, if non-nil, records the imports and runtime helpers that the code generated for the given call
+	// requires. It is called for each call in the program before any code is generated.
+	requires func(g *generator, n *il.BoundCall)
+}
+
+// functions maps the names of interpolation functions to their generators. Calls to functions that are not present in
+// this map are generated by GenCall's built-in cases.
+var functions = map[string]functionGenerator{}
+
+// RegisterFunction registers the generator for calls to the named Terraform interpolation function, replacing any
+// existing generator for that function. The type of the call is computed by the function's binder, which must be
+// registered using il.RegisterFunction. RegisterFunction returns a function that restores the previous registration.
+// RegisterFunction is not safe for concurrent use, and is intended to be called from init functions.
+func RegisterFunction(name string, f Function) (restore func()) {
+	previous, hadPrevious := functions[name]
+	previousHelpers := make(map[string]runtimeHelper)
+	for helper, code := range f.Helpers {
+		if h, ok := runtimeHelpers[helper]; ok {
+			previousHelpers[helper] = h
+		}
+		runtimeHelpers[helper] = runtimeHelper{code: code}
+	}
+
+	functions[name] = functionGenerator{
+		generate: func(g *generator, w io.Writer, n *il.BoundCall) {
+			f.Generate(g.Emitter, w, n)
+		},
+		requires: func(g *generator, _ *il.BoundCall) {
+			for name, statement := range f.Imports {
+				g.useImport(name, statement)
+			}
+			for helper := range f.Helpers {
+				g.useHelper(helper)
+			}
+		},
+	}
+
+	return func() {
+		for helper := range f.Helpers {
+			if h, ok := previousHelpers[helper]; ok {
+				runtimeHelpers[helper] = h
+			} else {
+				delete(runtimeHelpers, helper)
+			}
+		}
+		if hadPrevious {
+			functions[name] = previous
+		} else {
+			delete(functions, name)
+		}
+	}
+}
+
+// usesHelper returns a requires function for a function whose calls always call the named runtime helper.
+func usesHelper(name string) func(g *generator, n *il.BoundCall) {
+	return func(g *generator, _ *il.BoundCall) {
+		g.useHelper(name)
+	}
+}
+
+func init() {
+	functions["chomp"] = functionGenerator{generate: genChomp}
+	functions["cidrhost"] = functionGenerator{generate: genCIDRHost, requires: usesHelper("cidrhost")}
+	functions["cidrnetmask"] = functionGenerator{generate: genCIDRNetmask, requires: usesHelper("cidrnetmask")}
+	functions["cidrsubnet"] = functionGenerator{generate: genCIDRSubnet, requires: usesHelper("cidrsubnet")}
+	functions["coalesce"] = functionGenerator{generate: genCoalesce}
+	functions["coalescelist"] = functionGenerator{generate: genCoalesceList}
+	functions["concat"] = functionGenerator{generate: genConcat}
+	functions["contains"] = functionGenerator{generate: genContains}
+	functions["distinct"] = functionGenerator{generate: genDistinct}
+	functions["element"] = functionGenerator{generate: genElement, requires: requireElement}
+	functions["file"] = functionGenerator{generate: genFile}
+	functions["format"] = functionGenerator{generate: genFormatCall, requires: requireFormat}
+	functions["formatdate"] = functionGenerator{generate: genFormatDate, requires: usesHelper("formatdate")}
+	functions["formatlist"] = functionGenerator{generate: genFormatList, requires: requireFormat}
+	functions["index"] = functionGenerator{generate: genIndexOf, requires: usesHelper("index")}
+	functions["jsondecode"] = functionGenerator{generate: genJSONDecode}
+	functions["jsonencode"] = functionGenerator{generate: genJSONEncode}
+	functions["keys"] = functionGenerator{generate: genKeys}
+	functions["length"] = functionGenerator{generate: genLength}
+	functions["lookup"] = functionGenerator{generate: genLookup, requires: usesHelper("lookup")}
+	functions["lower"] = functionGenerator{generate: genLower}
+	functions["merge"] = functionGenerator{generate: genMerge}
+	functions["regexall"] = functionGenerator{generate: genRegexAll, requires: usesHelper("regexall")}
+	functions["replace"] = functionGenerator{generate: genReplace, requires: requireReplace}
+	functions["reverse"] = functionGenerator{generate: genReverse}
+	functions["slice"] = functionGenerator{generate: genSlice}
+	functions["sort"] = functionGenerator{generate: genSort}
+	functions["split"] = functionGenerator{generate: genSplit}
+	functions["substr"] = functionGenerator{generate: genSubstr, requires: usesHelper("substr")}
+	functions["timecmp"] = functionGenerator{generate: genTimecmp, requires: usesHelper("timecmp")}
+	functions["timestamp"] = functionGenerator{generate: genTimestamp}
+	functions["title"] = functionGenerator{generate: genTitle, requires: usesHelper("title")}
+	functions["trimspace"] = functionGenerator{generate: genTrimSpace}
+	functions["upper"] = functionGenerator{generate: genUpper}
+	functions["uuid"] = functionGenerator{generate: genUUID, requires: usesHelper("uuid")}
+	functions["values"] = functionGenerator{generate: genValues, requires: usesHelper("values")}
+	functions["zipmap"] = functionGenerator{generate: genZipmap, requires: usesHelper("zipmap")}
+}
+
+// genChomp generates a call to `chomp`, which removes any trailing newlines.
+func genChomp(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.replace(/(\\n|\\r\\n)*$/, \"\")", n.Args[0])
+}
+
+// genCIDRHost generates a call to `cidrhost`.
+func genCIDRHost(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "cidrhost(%v, %v)", n.Args[0], n.Args[1])
+}
+
+// genCIDRNetmask generates a call to `cidrnetmask`.
+func genCIDRNetmask(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "cidrnetmask(%v)", n.Args[0])
+}
+
+// genCIDRSubnet generates a call to `cidrsubnet`.
+func genCIDRSubnet(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "cidrsubnet(%v, %v, %v)", n.Args[0], n.Args[1], n.Args[2])
+}
+
+// genCoalesce generates a call to `coalesce`. The result is the first argument that is neither missing nor empty.
+// Unlike `||`, this does not skip values like `0` and `false`.
+func genCoalesce(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "[")
+	g.genCallArgs(w, n)
+	g.Fgen(w, "].find((v: any) => v !== undefined && v !== null && v !== \"\")")
+}
+
+// genCoalesceList generates a call to `coalescelist`. The result is the first argument that is a non-empty list.
+func genCoalesceList(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "[")
+	g.genCallArgs(w, n)
+	g.Fgen(w, "].find((v: any) => v !== undefined && v !== null && (<any[]>v).length > 0)")
+}
+
+// genConcat generates a call to `concat` as an array literal that spreads each of the call's arguments. If the final
+// argument is expanded, it is a list of lists, which are flattened before they are spread.
+func genConcat(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "[")
+	for i, arg := range n.Args {
+		if i > 0 {
+			g.Fgen(w, ", ")
+		}
+		if n.ExpandFinal && i == len(n.Args)-1 {
+			g.Fgenf(w, "...(<any[]>[]).concat(...%v)", arg)
+		} else {
+			g.Fgenf(w, "...%v", arg)
+		}
+	}
+	g.Fgen(w, "]")
+}
+
+// genContains generates a call to `contains`.
+func genContains(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.includes(%v)", n.Args[0], n.Args[1])
+}
+
+// genDistinct generates a call to `distinct`. Sets preserve the order in which their elements were first inserted, so
+// the result retains the first occurrence of each element, as in Terraform.
+func genDistinct(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "Array.from(new Set(%v))", n.Args[0])
+}
+
+// genElement generates a call to `element`. Terraform wraps the index around the length of the list. This is
+// irrelevant for a literal index of zero, so such calls are generated as plain index expressions.
+func genElement(g *generator, w io.Writer, n *il.BoundCall) {
+	if isZeroLiteral(n.Args[1]) {
+		g.Fgenf(w, "%v[0]", n.Args[0])
+	} else {
+		g.Fgenf(w, "element(%v, %v)", n.Args[0], n.Args[1])
+	}
+}
+
+// requireElement records the runtime helper used by calls to `element` whose index is not the literal zero.
+func requireElement(g *generator, n *il.BoundCall) {
+	if !isZeroLiteral(n.Args[1]) {
+		g.useHelper("element")
+	}
+}
+
+// genFile generates a call to `file`.
+func genFile(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "fs.readFileSync(%v, \"utf-8\")", n.Args[0])
+}
+
+// genFormatCall generates a call to `format`.
+func genFormatCall(g *generator, w io.Writer, n *il.BoundCall) {
+	args := make([]interface{}, len(n.Args)-1)
+	for i, a := range n.Args[1:] {
+		args[i] = a
+	}
+	g.genFormat(w, n, args)
+}
+
+// requireFormat records the import of sprintf-js by calls to `format` and `formatlist` that cannot be generated as
+// template literals.
+func requireFormat(g *generator, n *il.BoundCall) {
+	if _, inline := inlineFormatParts(n); !inline {
+		g.useImport("sprintf", `import sprintf = require("sprintf-js");`)
+	}
+}
+
+// genFormatList generates a call to `formatlist`. As in Terraform, the list arguments are iterated in parallel, and the
+// other arguments are repeated for each element. If there is a single list argument, its elements are passed directly
+// to the callback given to `map`; otherwise, each list argument is indexed by the callback's index parameter.
+func genFormatList(g *generator, w io.Writer, n *il.BoundCall) {
+	var lists []int
+	args := make([]interface{}, len(n.Args)-1)
+	for i, a := range n.Args[1:] {
+		args[i] = a
+		if a.Type().IsList() {
+			lists = append(lists, i)
+		}
+	}
+
+	switch len(lists) {
+	case 0:
+		g.Fgen(w, "[")
+		g.genFormat(w, n, args)
+		g.Fgen(w, "]")
+	case 1:
+		v := g.pushTemporary("v")
+		defer g.popTemporary()
+
+		list := args[lists[0]]
+		args[lists[0]] = v
+		g.Fgenf(w, "%v.map(%s => ", list, v)
+		g.genFormat(w, n, args)
+		g.Fgen(w, ")")
+	default:
+		index := g.pushTemporary("i")
+		defer g.popTemporary()
+
+		list := args[lists[0]]
+		for _, i := range lists {
+			var element bytes.Buffer
+			g.Fgenf(&element, "%v[%s]", args[i], index)
+			args[i] = element.String()
+		}
+		g.Fgenf(w, "%v.map((_, %s) => ", list, index)
+		g.genFormat(w, n, args)
+		g.Fgen(w, ")")
+	}
+}
+
+// genFormatDate generates a call to `formatdate`.
+func genFormatDate(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "formatdate(%v, %v)", n.Args[0], n.Args[1])
+}
+
+// genIndexOf generates a call to `index`.
+func genIndexOf(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "index(%v, %v)", n.Args[0], n.Args[1])
+}
+
+// genJSONDecode generates a call to `jsondecode`. The result is cast to `any`, as its type is not known until runtime.
+func genJSONDecode(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "(<any>JSON.parse(%v))", n.Args[0])
+}
+
+// genJSONEncode generates a call to `jsonencode`.
+//
+// If the argument references any outputs, the apply rewriter will already have lifted the entire call into an apply,
+// so the argument is always a prompt value here.
+func genJSONEncode(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "JSON.stringify(%v)", n.Args[0])
+}
+
+// genKeys generates a call to `keys`. As in Terraform, the keys are sorted.
+func genKeys(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "Object.keys(%v).sort()", n.Args[0])
+}
+
+// genLength generates a call to `length`. Strings and lists have a length property; the length of a map is the number
+// of its keys.
+func genLength(g *generator, w io.Writer, n *il.BoundCall) {
+	if n.Args[0].Type().ElementType() == il.TypeMap {
+		g.Fgenf(w, "Object.keys(%v).length", n.Args[0])
+	} else {
+		g.Fgenf(w, "%v.length", n.Args[0])
+	}
+}
+
+// genLookup generates a call to `lookup`.
+//
+// The default value may be an arbitrary expression. If it references any outputs, the apply rewriter will already
+// have lifted the entire call into an apply, so it is safe to generate it inline here.
+//
+// The lookup helper returns the default only if the key is missing, so values like `0`, `false`, and the empty string
+// are not replaced. If there is no default, a missing key is an error. If the result is a number or a boolean, it is
+// cast to the appropriate type.
+func genLookup(g *generator, w io.Writer, n *il.BoundCall) {
+	cast := ""
+	switch n.Type().ElementType() {
+	case il.TypeBool:
+		cast = "<boolean>"
+	case il.TypeNumber:
+		cast = "<number>"
+	}
+	if cast != "" {
+		g.Fgenf(w, "(%s", cast)
+	}
+	g.Fgen(w, "lookup(")
+	g.genCallArgs(w, n)
+	g.Fgen(w, ")")
+	if cast != "" {
+		g.Fgen(w, ")")
+	}
+}
+
+// genLower generates a call to `lower`.
+func genLower(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.toLowerCase()", n.Args[0])
+}
+
+// genMerge generates a call to `merge` as an object literal that spreads each of the call's arguments. As in Terraform,
+// the keys of later arguments override those of earlier arguments. If the final argument is expanded, it is a list of
+// maps, which are merged before they are spread.
+func genMerge(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "{")
+	for i, arg := range n.Args {
+		if i > 0 {
+			g.Fgen(w, ", ")
+		}
+		if n.ExpandFinal && i == len(n.Args)-1 {
+			g.Fgenf(w, "...Object.assign({}, ...%v)", arg)
+		} else {
+			g.Fgenf(w, "...%v", arg)
+		}
+	}
+	g.Fgen(w, "}")
+}
+
+// literalReplaceSearch returns the search argument of a call to `replace` if that argument is a string literal.
+func literalReplaceSearch(n *il.BoundCall) (string, bool) {
+	lit, ok := n.Args[1].(*il.BoundLiteral)
+	if !ok || lit.Type() != il.TypeString {
+		return "", false
+	}
+	return lit.Value.(string), true
+}
+
+// isRegexSearch returns true if the given search argument to `replace` is a regular expression, i.e. if it is wrapped
+// in forward slashes.
+func isRegexSearch(search string) bool {
+	return len(search) > 1 && search[0] == '/' && search[len(search)-1] == '/'
+}
+
+// regexLiteral returns the body of a JavaScript regular expression literal that matches the given pattern.
+// Unescaped forward slashes and line terminators must be escaped, and an empty pattern must not begin a comment.
+func regexLiteral(pattern string) string {
+	if pattern == "" {
+		return "(?:)"
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			b.WriteRune(c)
+			escaped = true
+		case c == '/':
+			b.WriteString("\\/")
+		case c == '\n':
+			b.WriteString("\\n")
+		case c == '\r':
+			b.WriteString("\\r")
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// genReplace generates a call to `replace`. As in Terraform, every occurrence of the search argument is replaced. If
+// the search argument is a literal regular expression, the call is generated as a call to `String.replace` with a
+// global regular expression literal; if it is any other literal string, the call is generated as a split and join.
+// Otherwise, the mode is chosen at runtime by the replace helper.
+func genReplace(g *generator, w io.Writer, n *il.BoundCall) {
+	search, ok := literalReplaceSearch(n)
+	switch {
+	case !ok:
+		g.Fgenf(w, "replace(%v, %v, %v)", n.Args[0], n.Args[1], n.Args[2])
+	case isRegexSearch(search):
+		g.Fgenf(w, "%v.replace(/%s/g, %v)", n.Args[0], regexLiteral(search[1:len(search)-1]), n.Args[2])
+	default:
+		g.Fgenf(w, "%v.split(%v).join(%v)", n.Args[0], n.Args[1], n.Args[2])
+	}
+}
+
+// requireReplace records the runtime helper used by calls to `replace` whose search string is not a literal.
+func requireReplace(g *generator, n *il.BoundCall) {
+	if _, ok := literalReplaceSearch(n); !ok {
+		g.useHelper("replace")
+	}
+}
+
+// genRegexAll generates a call to `regexall`.
+func genRegexAll(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "regexall(%v, %v)", n.Args[0], n.Args[1])
+}
+
+// genReverse generates a call to `reverse`. The list is copied, as `Array.prototype.reverse` reverses in place.
+func genReverse(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "[...%v].reverse()", n.Args[0])
+}
+
+// genSlice generates a call to `slice`.
+func genSlice(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.slice(%v, %v)", n.Args[0], n.Args[1], n.Args[2])
+}
+
+// genSort generates a call to `sort`. The list is copied, as `Array.prototype.sort` sorts in place. Numbers are
+// compared numerically rather than by their string representations.
+func genSort(g *generator, w io.Writer, n *il.BoundCall) {
+	if n.Args[0].Type().ElementType() == il.TypeNumber {
+		g.Fgenf(w, "[...%v].sort((a, b) => a - b)", n.Args[0])
+	} else {
+		g.Fgenf(w, "[...%v].sort()", n.Args[0])
+	}
+}
+
+// genSplit generates a call to `split`.
+func genSplit(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.split(%v)", n.Args[1], n.Args[0])
+}
+
+// genSubstr generates a call to `substr`.
+func genSubstr(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "substr(%v, %v, %v)", n.Args[0], n.Args[1], n.Args[2])
+}
+
+// genTimecmp generates a call to `timecmp`.
+func genTimecmp(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "timecmp(%v, %v)", n.Args[0], n.Args[1])
+}
+
+// genTimestamp generates a call to `timestamp`. As in Terraform, the result is the time at which the program runs, so
+// each run of the program produces a new value.
+func genTimestamp(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "new Date().toISOString()")
+}
+
+// genTitle generates a call to `title`.
+func genTitle(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "title(%v)", n.Args[0])
+}
+
+// genTrimSpace generates a call to `trimspace`.
+func genTrimSpace(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.trim()", n.Args[0])
+}
+
+// genUpper generates a call to `upper`.
+func genUpper(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "%v.toUpperCase()", n.Args[0])
+}
+
+// genUUID generates a call to `uuid`. As in Terraform, each run of the program produces a new value.
+func genUUID(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgen(w, "uuid()")
+}
+
+// genValues generates a call to `values`.
+func genValues(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "values(%v)", n.Args[0])
+}
+
+// genZipmap generates a call to `zipmap`.
+func genZipmap(g *generator, w io.Writer, n *il.BoundCall) {
+	g.Fgenf(w, "zipmap(%v, %v)", n.Args[0], n.Args[1])
+}
@@ -23,6 +23,8 @@ import (
 	"unicode"
 
 	"github.com/blang/semver"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
@@ -37,10 +39,32 @@ import (
 type Options struct {
 	// UsePromptDataSources is true if the target provider supports prompt invocation of data sources.
 	UsePromptDataSources bool
+	// CheckDelimiters is true if the generator should check that the literals, comments, and brackets in the generated
+	// code are terminated and balanced. This is not a full syntax check.
+	CheckDelimiters bool
+	// ResourceTypeMapper, if set, overrides the NodeJS module and type name used to refer to a resource.
+	ResourceTypeMapper ResourceTypeMapper
+	// InlineFiles is true if calls to `file` whose paths can be determined at generation time should be replaced with
+	// the contents of the referenced files, so that the generated program does not read them at runtime.
+	InlineFiles bool
+	// AnnotateTypes is true if the code generated for each bound node should be followed by a comment that describes
+	// the node's bound type. This is intended for debugging.
+	AnnotateTypes bool
 }
 
+// ResourceTypeMapper maps a resource to the NodeJS module and type name of the Pulumi resource class or data source
+// function that should be used to instantiate it. The module is relative to the resource's provider package, and is
+// empty if the type is exported from the package's index. If the mapper returns false, the default mapping derived
+// from the provider's schema is used.
+type ResourceTypeMapper func(r *il.ResourceNode) (module string, typeName string, ok bool)
+
 // New creates a new NodeJS code generator.
 func New(projectName string, targetSDKVersion string, usePromptDataSources bool, w io.Writer) (gen.Generator, error) {
+	return NewWithOptions(projectName, targetSDKVersion, Options{UsePromptDataSources: usePromptDataSources}, w)
+}
+
+// NewWithOptions creates a new NodeJS code generator using the given options.
+func NewWithOptions(projectName string, targetSDKVersion string, opts Options, w io.Writer) (gen.Generator, error) {
 	supportsProxyApplies := true
 	if targetSDKVersion != "" {
 		v, err := semver.Parse(targetSDKVersion)
@@ -52,10 +76,21 @@ func New(projectName string, targetSDKVersion string, usePromptDataSources bool,
 	g := &generator{
 		ProjectName:          projectName,
 		supportsProxyApplies: supportsProxyApplies,
-		usePromptDataSources: usePromptDataSources,
+		usePromptDataSources: opts.UsePromptDataSources,
+		checkDelimiters:      opts.CheckDelimiters,
+		resourceTypeMapper:   opts.ResourceTypeMapper,
+		inlineFiles:          opts.InlineFiles,
 		importNames:          make(map[string]bool),
+		imports:              make(map[string]bool),
+		helpers:              make(map[string]bool),
+		inlinedFiles:         make(map[*il.BoundCall]string),
+		manifest:             make(map[string][]gen.ManifestEntry),
+	}
+	if opts.AnnotateTypes {
+		g.Emitter = gen.NewEmitter(w, typeAnnotator{g: g})
+	} else {
+		g.Emitter = gen.NewEmitter(w, g)
 	}
-	g.Emitter = gen.NewEmitter(w, g)
 	return g, nil
 }
 
@@ -70,6 +105,12 @@ type generator struct {
 	supportsProxyApplies bool
 	// usePromptDataSources is true if the target provider supports prompt invocation of data sources.
 	usePromptDataSources bool
+	// checkDelimiters is true if the generator should check the delimiters in the generated code.
+	checkDelimiters bool
+	// resourceTypeMapper, if non-nil, overrides the default mapping of resources to NodeJS types.
+	resourceTypeMapper ResourceTypeMapper
+	// inlineFiles is true if the contents of files read by `file` should be inlined into the generated code.
+	inlineFiles bool
 	// rootPath is the path to the directory that contains the root module.
 	rootPath string
 	// module is the module currently being generated;.
@@ -82,6 +123,8 @@ type generator struct {
 	applyArgs []*il.BoundVariableAccess
 	// applyArgNames is the list of names for the currently in-scope apply arguments.
 	applyArgNames []string
+	// temporaries is the stack of names for the currently in-scope temporaries.
+	temporaries []string
 	// unknownInputs is the set of input variables that may be unknown at runtime.
 	unknownInputs map[*il.VariableNode]struct{}
 	// nameTable is a mapping from top-level nodes to names.
@@ -90,8 +133,86 @@ type generator struct {
 	promptDataSources map[*il.ResourceNode]bool
 	// importNames is the set of names used by package imports.
 	importNames map[string]bool
+	// imports is the set of import statements required by the generated program, excluding the import of the runtime
+	// helpers.
+	imports map[string]bool
+	// helpers is the set of runtime helpers called by the generated program.
+	helpers map[string]bool
 	// conditionalResources is a table of resources that are instantiated at most once.
 	conditionalResources map[*il.ResourceNode]bool
+	// inlinedFiles maps calls of the form `base64encode(file(path))` to the base64-encoded contents of the file and, if
+	// inlineFiles is true, calls to `file` to the contents of the file.
+	inlinedFiles map[*il.BoundCall]string
+	// lossyCoercions is the list of potentially-lossy coercions found in the node that is currently being generated.
+	lossyCoercions []il.LossyCoercion
+	// diagnostics is the list of diagnostics reported for the generated code.
+	diagnostics hcl.Diagnostics
+	// manifest maps from the name of each module to the manifest entries for the resources generated by that module.
+	// The entries for the root module are keyed by the empty string.
+	manifest map[string][]gen.ManifestEntry
+}
+
+// Diagnostics returns the diagnostics reported for the generated code. These include a warning for each inserted
+// coercion that may change the semantics of the coerced value.
+func (g *generator) Diagnostics() hcl.Diagnostics {
+	return g.diagnostics
+}
+
+// Manifest returns the manifest for the generated code. The manifest includes the resources generated by child
+// modules, but not data sources.
+func (g *generator) Manifest() *gen.Manifest {
+	resources := append([]gen.ManifestEntry{}, g.manifest[""]...)
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Address < resources[j].Address })
+	return &gen.Manifest{Resources: resources}
+}
+
+// manifestModuleNamePrefix is the prefix of the names of resources in child modules. The prefix is replaced with the
+// name of the module instance when the module is instantiated.
+const manifestModuleNamePrefix = "${mod_name}_"
+
+// recordManifestEntry adds a manifest entry for the given resource to the manifest for the current module.
+func (g *generator) recordManifestEntry(r *il.ResourceNode) {
+	moduleName, name := "", r.Name
+	if !g.isRoot() {
+		moduleName, name = g.module.Name, manifestModuleNamePrefix+r.Name
+	}
+
+	tok, _ := r.Tok()
+	g.manifest[moduleName] = append(g.manifest[moduleName], gen.ManifestEntry{
+		Address:  r.Type + "." + r.Name,
+		Type:     tok,
+		Variable: g.nodeName(r),
+		Name:     name,
+		Counted:  r.Count != nil && !g.isConditionalResource(r),
+	})
+}
+
+// reportLossyCoercions records a warning for each potentially-lossy coercion found while generating the given node.
+func (g *generator) reportLossyCoercions(node string, location token.Pos) {
+	for _, c := range g.lossyCoercions {
+		from, to := fmt.Sprint(c.From&^il.TypeOutput), fmt.Sprint(c.To&^il.TypeOutput)
+		if c.ToInteger {
+			to = "integer"
+		}
+
+		site := node
+		if c.Path != "" {
+			site = fmt.Sprintf("property %s of %s", c.Path, node)
+		}
+
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  fmt.Sprintf("lossy coercion from %v to %v in %s", from, to, node),
+			Detail: fmt.Sprintf("the value of %s is converted from %v to %v at runtime; the conversion may fail or "+
+				"change the value if it is not a valid %v", site, from, to, to),
+		}
+		if location.IsValid() {
+			pos := hcl.Pos{Line: location.Line, Column: location.Column}
+			diag.Subject = &hcl.Range{Filename: location.Filename, Start: pos, End: pos}
+		}
+		g.diagnostics = append(g.diagnostics, diag)
+	}
+	g.lossyCoercions = nil
 }
 
 // isLegalIdentifierStart returns true if it is legal for c to be the first character of a JavaScript identifier as per
@@ -150,7 +271,7 @@ func tsName(tfName string, tfSchema *schema.Schema, schemaInfo *tfbridge.SchemaI
 
 	if !isLegalIdentifier(tfName) {
 		if isObjectKey {
-			return fmt.Sprintf("%q", tfName)
+			return stringLiteral(tfName)
 		}
 		return cleanName(tfName)
 	}
@@ -180,6 +301,8 @@ func (g *generator) variableName(n *il.BoundVariableAccess) string {
 		return ""
 	case *config.ResourceVariable:
 		return cleanName(v.Type + "_" + v.Name)
+	case *config.SelfVariable:
+		return "self"
 	case *config.UserVariable:
 		return "var_" + cleanName(v.Name)
 	default:
@@ -209,12 +332,26 @@ func (g *generator) isConditionalResource(r *il.ResourceNode) bool {
 func (g *generator) GenError(w io.Writer, v *il.BoundError) {
 	g.Fgen(w, "(() => {\n")
 	g.Indented(func() {
-		g.Fgenf(w, "%sthrow \"tf2pulumi error: %v\";\n", g.Indent, v.Error.Error())
-		g.Fgenf(w, "%sreturn %v;\n", g.Indent, v.Value)
+		message := &il.BoundLiteral{ExprType: il.TypeString, Value: "tf2pulumi error: " + v.Error.Error()}
+		g.Fgenf(w, "%sthrow %v;\n", g.Indent, message)
+		// A self-reference refers to the resource that is being constructed, which is not in scope. As the function
+		// always throws, its return is simply omitted.
+		if v.Value != nil && !isSelfReference(v.Value) {
+			g.Fgenf(w, "%sreturn %v;\n", g.Indent, v.Value)
+		}
 	})
 	g.Fgen(w, g.Indent, "})()")
 }
 
+// isSelfReference returns true if the given node is a reference to `self`.
+func isSelfReference(e il.BoundNode) bool {
+	if v, ok := e.(*il.BoundVariableAccess); ok {
+		_, isSelf := v.TFVar.(*config.SelfVariable)
+		return isSelf
+	}
+	return false
+}
+
 // computeProperty generates code for the given property into a string ala fmt.Sprintf. It returns both the generated
 // code and a bool value that indicates whether or not any output-typed values were nested in the property value.
 func (g *generator) computeProperty(prop il.BoundNode, indent bool, count string) (string, bool, error) {
@@ -230,13 +367,18 @@ func (g *generator) computeProperty(prop il.BoundNode, indent bool, count string
 	})
 	contract.Assert(err == nil)
 
-	// Next, rewrite assets, lower certain constructrs to literals, insert any necessary coercions, and run the apply
-	// transform.
+	// Next, rewrite assets and resource references, lower certain constructrs to literals, insert any necessary
+	// coercions, and run the apply transform.
 	p, err := il.RewriteAssets(prop)
 	if err != nil {
 		return "", false, err
 	}
 
+	p, err = il.RewriteResourceReferences(p)
+	if err != nil {
+		return "", false, err
+	}
+
 	p, err = g.lowerToLiterals(p)
 	if err != nil {
 		return "", false, err
@@ -246,6 +388,7 @@ func (g *generator) computeProperty(prop il.BoundNode, indent bool, count string
 	if err != nil {
 		return "", false, err
 	}
+	g.lossyCoercions = append(g.lossyCoercions, il.FindLossyCoercions(p)...)
 
 	p, err = il.RewriteApplies(p)
 	if err != nil {
@@ -267,7 +410,14 @@ func (g *generator) computeProperty(prop il.BoundNode, indent bool, count string
 	g.countIndex = count
 	buf := &bytes.Buffer{}
 	g.Fgen(buf, p)
-	return buf.String(), containsOutputs, nil
+	code := buf.String()
+
+	if g.checkDelimiters {
+		if err := checkDelimiters(code); err != nil {
+			return "", false, err
+		}
+	}
+	return code, containsOutputs, nil
 }
 
 // isRoot returns true if we are generating code for the root module.
@@ -318,7 +468,6 @@ func (g *generator) GeneratePreamble(modules []*il.Graph) error {
 	g.Println(`import * as pulumi from "@pulumi/pulumi";`)
 
 	// Accumulate other imports for the various providers. Don't emit them yet, as we need to sort them later on.
-	var imports []string
 	providers := make(map[string]bool)
 	for _, m := range modules {
 		for _, p := range m.Providers {
@@ -329,39 +478,37 @@ func (g *generator) GeneratePreamble(modules []*il.Graph) error {
 				case "archive":
 					// Nothing to do
 				case "http":
-					imports = append(imports,
-						`import rpn = require("request-promise-native");`)
-					g.importNames["rpn"] = true
+					g.useImport("rpn", `import rpn = require("request-promise-native");`)
 				default:
 					importName := cleanName(name)
-					imports = append(imports,
-						fmt.Sprintf(`import * as %s from "@pulumi/%s";`, importName, name))
-					g.importNames[importName] = true
+					g.useImport(importName, fmt.Sprintf(`import * as %s from "@pulumi/%s";`, importName, name))
 				}
 			}
 		}
 	}
 
-	// Look for additional optional imports, also appending them to the list so we can sort them later on.
+	// Read the contents of any files that will be inlined into the generated code. Calls to `file` that are inlined or
+	// replaced with asset paths will not be generated, and do not require any imports.
+	inlinedFileCalls := g.inlineFileContents(modules)
+
+	// Look for additional optional imports. Any helper functions that are required by the generated code are recorded
+	// at the same time.
 	findOptionals := func(n il.BoundNode) (il.BoundNode, error) {
 		switch n := n.(type) {
 		case *il.BoundCall:
 			switch n.Func {
 			case "file":
-				if !g.importNames["fs"] {
-					imports = append(imports, `import * as fs from "fs";`)
-					g.importNames["fs"] = true
+				if !inlinedFileCalls[n] {
+					g.useImport("fs", `import * as fs from "fs";`)
 				}
-			case "format":
-				if !g.importNames["sprintf"] {
-					imports = append(imports, `import sprintf = require("sprintf-js");`)
-					g.importNames["sprintf"] = true
+			default:
+				if f, ok := functions[n.Func]; ok && f.requires != nil {
+					f.requires(g, n)
 				}
 			}
 		case *il.BoundVariableAccess:
-			if v, ok := n.TFVar.(*config.PathVariable); ok && v.Type == config.PathValueCwd && !g.importNames["process"] {
-				imports = append(imports, `import * as process from "process";`)
-				g.importNames["process"] = true
+			if v, ok := n.TFVar.(*config.PathVariable); ok && v.Type == config.PathValueCwd {
+				g.useImport("process", `import * as process from "process";`)
 			}
 		}
 		return n, nil
@@ -371,6 +518,21 @@ func (g *generator) GeneratePreamble(modules []*il.Graph) error {
 		contract.Assert(err == nil)
 	}
 
+	imports := make([]string, 0, len(g.imports)+1)
+	for line := range g.imports {
+		imports = append(imports, line)
+	}
+
+	// Import any helper functions from the utilities module.
+	if len(g.helpers) != 0 {
+		names := make([]string, 0, len(g.helpers))
+		for name := range g.helpers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		imports = append(imports, fmt.Sprintf(`import { %s } from "./%s";`, strings.Join(names, ", "), utilitiesModule))
+	}
+
 	// Now sort the imports, so we emit them deterministically, and emit them.
 	sort.Strings(imports)
 	for _, line := range imports {
@@ -381,15 +543,73 @@ func (g *generator) GeneratePreamble(modules []*il.Graph) error {
 	return nil
 }
 
+// useImport records that the generated program requires the given import statement, which binds the given name.
+func (g *generator) useImport(name, statement string) {
+	g.imports[statement], g.importNames[name] = true, true
+}
+
+// useHelper records that the generated program calls the named runtime helper. The helper is imported from the
+// utilities module, and its definition is emitted into that module by AuxiliaryFiles.
+func (g *generator) useHelper(name string) {
+	_, ok := runtimeHelpers[name]
+	contract.Assertf(ok, "unknown runtime helper %v", name)
+
+	g.helpers[name], g.importNames[name] = true, true
+}
+
+// AuxiliaryFiles returns the utilities module that defines the runtime helpers used by the generated program, if any.
+// Helpers that are called by the program are exported from the module. Their internal definitions are not.
+func (g *generator) AuxiliaryFiles() map[string][]byte {
+	if len(g.helpers) == 0 {
+		return nil
+	}
+
+	definitions, imports := map[string]bool{}, map[string]bool{}
+	for name := range g.helpers {
+		definitions[name] = true
+		for _, dep := range runtimeHelpers[name].deps {
+			definitions[dep] = true
+		}
+	}
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+		for _, line := range runtimeHelpers[name].imports {
+			imports[line] = true
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	if len(imports) != 0 {
+		lines := make([]string, 0, len(imports))
+		for line := range imports {
+			lines = append(lines, line)
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			fmt.Fprintln(&b, line)
+		}
+		fmt.Fprintln(&b)
+	}
+	for i, name := range names {
+		if i != 0 {
+			fmt.Fprintln(&b)
+		}
+		if g.helpers[name] {
+			b.WriteString("export ")
+		}
+		b.WriteString(runtimeHelpers[name].code)
+	}
+
+	return map[string][]byte{utilitiesModule + ".ts": []byte(b.String())}
+}
+
 // BeginModule saves the indicated module in the generator and emits an appropriate function declaration if the module
 // is a child module.
 func (g *generator) BeginModule(m *il.Graph) error {
 	g.module = m
 	if !g.isRoot() {
-		g.Printf("const new_mod_%s = function(mod_name: string, mod_args: pulumi.Inputs) {\n",
-			cleanName(m.Name))
-		g.Indent += "    "
-
 		// Discover the set of input variables that may have unknown values. This is the complete set of inputs minus
 		// the set of variables used in count interpolations, as Terraform requires that the latter are known at graph
 		// generation time (and thus at Pulumi run time).
@@ -426,6 +646,10 @@ func (g *generator) BeginModule(m *il.Graph) error {
 			return n, nil
 		})
 		contract.Assert(err == nil)
+
+		g.Printf("const new_mod_%s = function(mod_name: string, mod_args: %s) {\n",
+			cleanName(m.Name), g.moduleArgsType(m))
+		g.Indent += "    "
 	}
 
 	// Find all prompt datasources if possible.
@@ -437,7 +661,99 @@ func (g *generator) BeginModule(m *il.Graph) error {
 	g.conditionalResources = il.MarkConditionalResources(m)
 
 	// Compute unambiguous names for this module's top-level nodes.
-	g.nameTable = assignNames(m, g.importNames, g.isRoot())
+	g.nameTable = assignNames(m, g.importNames, g.isRoot(), g.resourceTypeMapper)
+
+	// Terraform ignores the backends of child modules, so only the root module's backend is described.
+	if g.isRoot() && m.Backend != nil {
+		return g.genBackend(m.Backend)
+	}
+	return nil
+}
+
+// moduleArgsType returns the TypeScript type of the arguments to the given child module's constructor. Each of the
+// module's variables is a property of the arguments; variables with defaults are optional. Variables that may have
+// unknown values accept inputs.
+func (g *generator) moduleArgsType(m *il.Graph) string {
+	if len(m.Variables) == 0 {
+		return "{}"
+	}
+
+	names := make([]string, 0, len(m.Variables))
+	for name := range m.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range names {
+		v := m.Variables[name]
+
+		optional := ""
+		if v.DefaultValue != nil {
+			optional = "?"
+		}
+
+		typ := moduleInputType(v)
+		if _, isUnknown := g.unknownInputs[v]; isUnknown {
+			typ = fmt.Sprintf("pulumi.Input<%s>", typ)
+		}
+
+		fmt.Fprintf(&b, "%s    %s%s: %s;\n", g.Indent, tsName(v.Name, nil, nil, false), optional, typ)
+	}
+	fmt.Fprintf(&b, "%s}", g.Indent)
+	return b.String()
+}
+
+// moduleInputType returns the TypeScript type of the given module variable. The elements of lists and maps accept
+// inputs.
+func moduleInputType(v *il.VariableNode) string {
+	switch v.Config.DeclaredType {
+	case "list":
+		if v.DefaultValue == nil {
+			return "pulumi.Input<any>[]"
+		}
+	case "map":
+		if v.DefaultValue == nil {
+			return "Record<string, pulumi.Input<any>>"
+		}
+	}
+
+	switch d := v.DefaultValue.(type) {
+	case *il.BoundListProperty:
+		return fmt.Sprintf("pulumi.Input<%s>[]", strings.TrimSuffix(defaultValueType(d), "[]"))
+	case *il.BoundMapProperty:
+		elem := strings.TrimSuffix(strings.TrimPrefix(defaultValueType(d), "Record<string, "), ">")
+		return fmt.Sprintf("Record<string, pulumi.Input<%s>>", elem)
+	}
+
+	switch v.Type() {
+	case il.TypeBool:
+		return "boolean"
+	case il.TypeNumber:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// genBackend generates a comment that describes the given Terraform state backend. Pulumi programs store their state
+// in the backend selected by `pulumi login` rather than in a backend that is configured by the program itself.
+func (g *generator) genBackend(b *il.Backend) error {
+	settings, _, err := g.computeProperty(il.BoundNode(b.Properties), false, "")
+	if err != nil {
+		return annotateSyntaxError(err, "terraform.backend")
+	}
+
+	g.Printf("// The Terraform state for this program was stored using the %q backend with the following\n", b.Type)
+	g.Printf("// settings:\n")
+	g.Printf("//\n")
+	for _, l := range strings.Split(settings, "\n") {
+		g.Printf("//     %s\n", l)
+	}
+	g.Printf("//\n")
+	g.Printf("// Pulumi stores the state for this program in the backend selected by `pulumi login`.\n")
+	g.Printf("\n")
 	return nil
 }
 
@@ -474,7 +790,14 @@ func (g *generator) GenerateVariables(vs []*il.VariableNode) error {
 		g.Printf("%sconst %s = ", g.Indent, g.nodeName(v))
 		if v.DefaultValue == nil {
 			if isRoot {
-				g.Printf("config.require(\"%s\")", configName)
+				switch v.Config.DeclaredType {
+				case "list":
+					g.Printf("config.requireObject<any[]>(\"%s\")", configName)
+				case "map":
+					g.Printf("config.requireObject<Record<string, any>>(\"%s\")", configName)
+				default:
+					g.Printf("config.require(\"%s\")", configName)
+				}
 			} else {
 				f := "mod_args[\"%s\"]"
 				if isUnknown {
@@ -485,18 +808,25 @@ func (g *generator) GenerateVariables(vs []*il.VariableNode) error {
 		} else {
 			def, _, err := g.computeProperty(v.DefaultValue, false, "")
 			if err != nil {
-				return err
+				return annotateSyntaxError(err, "var."+v.Name)
 			}
+			g.reportLossyCoercions("var."+v.Name, v.Location)
 
 			if isRoot {
-				get := "get"
-				switch v.DefaultValue.Type() {
-				case il.TypeBool:
-					get = "getBoolean"
-				case il.TypeNumber:
-					get = "getNumber"
+				switch v.DefaultValue.(type) {
+				case *il.BoundListProperty, *il.BoundMapProperty:
+					// Complex values are read as objects with the same structure as their defaults.
+					g.Printf("config.getObject<%s>(\"%s\") ?? %s", defaultValueType(v.DefaultValue), configName, def)
+				default:
+					get := "get"
+					switch v.DefaultValue.Type() {
+					case il.TypeBool:
+						get = "getBoolean"
+					case il.TypeNumber:
+						get = "getNumber"
+					}
+					g.Printf("config.%v(\"%s\") || %s", get, configName, def)
 				}
-				g.Printf("config.%v(\"%s\") || %s", get, configName, def)
 			} else {
 				f := "mod_args[\"%s\"] || %s"
 				if isUnknown {
@@ -515,11 +845,56 @@ func (g *generator) GenerateVariables(vs []*il.VariableNode) error {
 	return nil
 }
 
+// defaultValueType returns the TypeScript type of the given variable default value. Lists and maps whose elements all
+// have the same type are typed as arrays and records of that type, respectively.
+func defaultValueType(n il.BoundNode) string {
+	// elementsType returns the common type of the given elements, or "any" if the elements' types differ.
+	elementsType := func(elements []il.BoundNode) string {
+		typ := ""
+		for _, e := range elements {
+			switch t := defaultValueType(e); {
+			case typ == "":
+				typ = t
+			case t != typ:
+				return "any"
+			}
+		}
+		if typ == "" {
+			return "any"
+		}
+		return typ
+	}
+
+	switch n := n.(type) {
+	case *il.BoundListProperty:
+		return elementsType(n.Elements) + "[]"
+	case *il.BoundMapProperty:
+		elements := make([]il.BoundNode, 0, len(n.Elements))
+		for _, e := range n.Elements {
+			elements = append(elements, e)
+		}
+		return fmt.Sprintf("Record<string, %s>", elementsType(elements))
+	}
+
+	switch n.Type() {
+	case il.TypeBool:
+		return "boolean"
+	case il.TypeNumber:
+		return "number"
+	case il.TypeString:
+		return "string"
+	default:
+		return "any"
+	}
+}
+
 // GenerateLocal generates a single local value. These values are generated as local variable definitions.
 func (g *generator) GenerateLocal(l *il.LocalNode) error {
+	defer g.reportLossyCoercions("local."+l.Name, l.Location)
+
 	value, _, err := g.computeProperty(l.Value, false, "")
 	if err != nil {
-		return err
+		return annotateSyntaxError(err, "local."+l.Name)
 	}
 
 	g.genLeadingComment(g, l.Comments)
@@ -533,10 +908,12 @@ func (g *generator) GenerateLocal(l *il.LocalNode) error {
 // GenerateModule generates a single module instantiation. A module instantiation is generated as a call to the
 // appropriate module factory function; the result is assigned to a local variable.
 func (g *generator) GenerateModule(m *il.ModuleNode) error {
+	defer g.reportLossyCoercions("module."+m.Name, m.Location)
+
 	// generate a call to the module constructor
 	args, _, err := g.computeProperty(m.Properties, false, "")
 	if err != nil {
-		return err
+		return annotateSyntaxError(err, "module."+m.Name)
 	}
 
 	instanceName, modName := g.nodeName(m), cleanName(m.Name)
@@ -545,6 +922,21 @@ func (g *generator) GenerateModule(m *il.ModuleNode) error {
 	g.genTrailingComment(g, m.Comments)
 	g.Print("\n")
 
+	// Add the module's resources to the current module's manifest. The module's factory function prefixes the names of
+	// the resources it creates with the name of the instance. The names of resources in nested modules are prefixed
+	// with the names of the nested instances, and are left as-is.
+	moduleName := ""
+	if !g.isRoot() {
+		moduleName = g.module.Name
+	}
+	for _, e := range g.manifest[m.Name] {
+		e.Address = "module." + m.Name + "." + e.Address
+		if strings.HasPrefix(e.Name, manifestModuleNamePrefix) {
+			e.Name = instanceName + "_" + strings.TrimPrefix(e.Name, manifestModuleNamePrefix)
+		}
+		g.manifest[moduleName] = append(g.manifest[moduleName], e)
+	}
+
 	return nil
 }
 
@@ -556,6 +948,8 @@ func (g *generator) GenerateProvider(p *il.ProviderNode) error {
 		return nil
 	}
 
+	defer g.reportLossyCoercions("provider."+p.Name+"."+p.Alias, p.Location)
+
 	g.genLeadingComment(g, p.Comments)
 
 	name := g.nodeName(p)
@@ -563,7 +957,7 @@ func (g *generator) GenerateProvider(p *il.ProviderNode) error {
 
 	inputs, _, err := g.computeProperty(il.BoundNode(p.Properties), false, "")
 	if err != nil {
-		return err
+		return annotateSyntaxError(err, "provider."+p.Name+"."+p.Alias)
 	}
 
 	var resName string
@@ -579,14 +973,21 @@ func (g *generator) GenerateProvider(p *il.ProviderNode) error {
 	return nil
 }
 
-// resourceTypeName computes the NodeJS package, module, and type name for the given resource.
-func resourceTypeName(r *il.ResourceNode) (string, string, string, error) {
-	// Compute the resource type from the Terraform type.
-	underscore := strings.IndexRune(r.Type, '_')
-	if underscore == -1 {
-		return "", "", "", errors.New("NYI: single-resource providers")
+// resourceTypeName computes the NodeJS package, module, and type name for the given resource. If a mapper is provided
+// and applies to the resource, its module and type name are used in place of the defaults.
+func resourceTypeName(r *il.ResourceNode, mapper ResourceTypeMapper) (string, string, string, error) {
+	if mapper != nil {
+		if module, typeName, ok := mapper(r); ok {
+			return cleanName(r.Provider.PluginName), module, typeName, nil
+		}
+	}
+
+	// Compute the resource type from the Terraform type. Single-resource providers (e.g. `external`) name their only
+	// resource or data source after the provider itself.
+	provider, resourceType := cleanName(r.Provider.PluginName), r.Type
+	if underscore := strings.IndexRune(r.Type, '_'); underscore != -1 {
+		resourceType = r.Type[underscore+1:]
 	}
-	provider, resourceType := cleanName(r.Provider.PluginName), r.Type[underscore+1:]
 
 	// Convert the TF resource type into its Pulumi name.
 	memberName := tfbridge.TerraformToPulumiName(resourceType, nil, nil, true)
@@ -615,16 +1016,41 @@ func resourceTypeName(r *il.ResourceNode) (string, string, string, error) {
 	return provider, module, memberName, nil
 }
 
+// resourceInstanceType returns the TypeScript type of a single instance of the given non-builtin resource. For managed
+// resources, this is the resource's class; for data sources, this is the data source's result type (wrapped in an
+// output if the data source is not prompt).
+func (g *generator) resourceInstanceType(r *il.ResourceNode) (string, error) {
+	provider, module, memberName, err := resourceTypeName(r, g.resourceTypeMapper)
+	if err != nil {
+		return "", err
+	}
+	if module != "" {
+		module = "." + module
+	}
+
+	if !r.IsDataSource {
+		return fmt.Sprintf("%s%s.%s", provider, module, memberName), nil
+	}
+
+	fmtStr := "pulumi.Output<%s%s.%sResult>"
+	if g.promptDataSources[r] {
+		fmtStr = "%s%s.%sResult"
+	}
+	return fmt.Sprintf(fmtStr, provider, module, strings.Title(memberName)), nil
+}
+
 // makeResourceName returns the expression that should be emitted for a resource's "name" parameter given its base name
-// and the count variable name, if any.
+// and the count variable name, if any. Pulumi requires that each resource has a unique name, so the name of each
+// instance of a counted resource is suffixed with its index, and the name of each resource in a child module is
+// prefixed with the name of the module instance.
 func (g *generator) makeResourceName(baseName, count string) string {
 	if g.isRoot() {
 		if count == "" {
-			return fmt.Sprintf(`"%s"`, baseName)
+			return stringLiteral(baseName)
 		}
-		return fmt.Sprintf("`%s-${%s}`", baseName, count)
+		return fmt.Sprintf("`%s-${%s}`", escapeString(baseName, '`'), count)
 	}
-	baseName = fmt.Sprintf("${mod_name}_%s", baseName)
+	baseName = fmt.Sprintf("${mod_name}_%s", escapeString(baseName, '`'))
 	if count == "" {
 		return fmt.Sprintf("`%s`", baseName)
 	}
@@ -633,7 +1059,7 @@ func (g *generator) makeResourceName(baseName, count string) string {
 
 // generateResource handles the generation of instantiations of non-builtin resources.
 func (g *generator) generateResource(r *il.ResourceNode) error {
-	provider, module, memberName, err := resourceTypeName(r)
+	provider, module, memberName, err := resourceTypeName(r, g.resourceTypeMapper)
 	if err != nil {
 		return err
 	}
@@ -687,6 +1113,12 @@ func (g *generator) generateResource(r *il.ResourceNode) error {
 		resourceOptions = append(resourceOptions, buf.String())
 	}
 
+	// Pulumi creates replacement resources before deleting the resources they replace by default, which matches
+	// Terraform's `create_before_destroy`. We emit the option anyway in order to document the original intent.
+	if r.Config.Lifecycle.CreateBeforeDestroy && !r.IsDataSource {
+		resourceOptions = append(resourceOptions, "deleteBeforeReplace: false")
+	}
+
 	if r.IsDataSource && !g.promptDataSources[r] {
 		resourceOptions = append(resourceOptions, "async: true")
 	}
@@ -801,30 +1233,29 @@ func (g *generator) generateResource(r *il.ResourceNode) error {
 		})
 		g.Printf("%s}", g.Indent)
 	} else {
-		// Otherwise we need to Generate multiple resources in a loop.
+		// Otherwise we need to Generate multiple resources in a loop. The loop variable must not shadow any name that is
+		// referenced by the count or the resource's properties.
 		count, _, err := g.computeProperty(r.Count, false, "")
 		if err != nil {
 			return err
 		}
-		inputs, transformed, err := g.computeProperty(properties, true, "i")
+		index := g.pushTemporary("i")
+		defer g.popTemporary()
+		inputs, transformed, err := g.computeProperty(properties, true, index)
 		if err != nil {
 			return err
 		}
 
-		arrElementType := qualifiedMemberName
-		if r.IsDataSource {
-			fmtStr := "pulumi.Output<%s%s.%sResult>"
-			if g.promptDataSources[r] {
-				fmtStr = "%s%s.%sResult"
-			}
-			arrElementType = fmt.Sprintf(fmtStr, provider, module, strings.Title(memberName))
+		arrElementType, err := g.resourceInstanceType(r)
+		if err != nil {
+			return err
 		}
 
 		g.Printf("%sconst %s: %s[] = [];\n", g.Indent, name, arrElementType)
-		g.Printf("%sfor (let i = 0; i < %s; i++) {\n", g.Indent, count)
+		g.Printf("%sfor (let %s = 0; %s < %s; %s++) {\n", g.Indent, index, index, count, index)
 		g.Indented(func() {
 			if !r.IsDataSource {
-				resName := g.makeResourceName(r.Name, "i")
+				resName := g.makeResourceName(r.Name, index)
 				g.Printf("%s%s.push(new %s(%s, %s%s));\n", g.Indent, name, qualifiedMemberName, resName, inputs,
 					optionsBag)
 			} else {
@@ -844,6 +1275,9 @@ func (g *generator) generateResource(r *il.ResourceNode) error {
 		g.Printf("%s}", g.Indent)
 	}
 
+	if !r.IsDataSource {
+		g.recordManifestEntry(r)
+	}
 	return nil
 }
 
@@ -852,6 +1286,8 @@ func (g *generator) generateResource(r *il.ResourceNode) error {
 // function. Single-instance resources are assigned to a local variable; counted resources are stored in an array-typed
 // local.
 func (g *generator) GenerateResource(r *il.ResourceNode) error {
+	defer g.reportLossyCoercions(r.Config.Id(), r.Location)
+
 	g.genLeadingComment(g, r.Comments)
 
 	// If this resource's provider is one of the built-ins, perform whatever provider-specific code generation is
@@ -866,7 +1302,7 @@ func (g *generator) GenerateResource(r *il.ResourceNode) error {
 		err = g.generateResource(r)
 	}
 	if err != nil {
-		return err
+		return annotateSyntaxError(err, r.Config.Id())
 	}
 
 	g.genTrailingComment(g, r.Comments)
@@ -893,8 +1329,9 @@ func (g *generator) GenerateOutputs(os []*il.OutputNode) error {
 	for _, o := range os {
 		outputs, _, err := g.computeProperty(o.Value, false, "")
 		if err != nil {
-			return err
+			return annotateSyntaxError(err, "output."+o.Name)
 		}
+		g.reportLossyCoercions("output."+o.Name, o.Location)
 
 		// We combine the leading and trailing comments for the output itself and its value.
 
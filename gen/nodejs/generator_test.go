@@ -3,14 +3,25 @@ package nodejs
 import (
 	"bytes"
 	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pulumi/tf2pulumi/gen"
 	"github.com/pulumi/tf2pulumi/il"
 	"github.com/pulumi/tf2pulumi/internal/config"
 	"github.com/pulumi/tf2pulumi/internal/config/module"
+	"github.com/pulumi/tf2pulumi/internal/testutil"
 )
 
 func TestLegalIdentifiers(t *testing.T) {
@@ -114,6 +125,77 @@ func readFile(t *testing.T, path string) string {
 	return string(bytes)
 }
 
+// missingProviderInfoSource is a ProviderInfoSource that never has information for any provider.
+type missingProviderInfoSource struct{}
+
+func (missingProviderInfoSource) GetProviderInfo(tfProviderName string) (*tfbridge.ProviderInfo, error) {
+	return nil, errors.Errorf("no provider info for %s", tfProviderName)
+}
+
+// buildSource builds the graph for the given Terraform source. No provider schema information is available.
+func buildSource(t *testing.T, source string) *il.Graph {
+	return buildSourceWithProviders(t, source, missingProviderInfoSource{})
+}
+
+// buildSourceWithProviders builds the graph for the given Terraform source using the given provider information.
+func buildSourceWithProviders(t *testing.T, source string, providers il.ProviderInfoSource) *il.Graph {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer func() {
+		contract.IgnoreError(os.RemoveAll(dir))
+	}()
+
+	err = ioutil.WriteFile(path.Join(dir, "main.tf"), []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	g, err := il.BuildGraph(module.NewTree("main", loadConfig(t, dir)), &il.BuildOptions{
+		ProviderInfoSource:    providers,
+		AllowMissingProviders: true,
+		AllowMissingVariables: true,
+		AllowMissingComments:  true,
+		Logger:                log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("could not build graph: %v", err)
+	}
+	return g
+}
+
+// generateSource generates TypeScript for the given Terraform source. The generated code is checked for syntax errors.
+func generateSource(t *testing.T, source string) string {
+	return generateSourceWithProviders(t, source, missingProviderInfoSource{})
+}
+
+// generateSourceWithProviders generates TypeScript for the given Terraform source using the given provider
+// information. The generated code is checked for syntax errors.
+func generateSourceWithProviders(t *testing.T, source string, providers il.ProviderInfoSource) string {
+	g := buildSourceWithProviders(t, source, providers)
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+	return b.String()
+}
+
+// generateProgram generates TypeScript for the given Terraform source. It returns the generated program and the
+// contents of the utilities module that defines the runtime helpers used by the program, if any.
+func generateProgram(t *testing.T, source string) (code, utilities string) {
+	g := buildSource(t, source)
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+	return b.String(), string(lang.(gen.AuxiliaryFileReporter).AuxiliaryFiles()["utilities.ts"])
+}
+
 func TestComments(t *testing.T) {
 	conf := loadConfig(t, "testdata/test_comments")
 
@@ -247,3 +329,1013 @@ func TestMetaProperties(t *testing.T) {
 	expectedText := readFile(t, "testdata/test_meta_properties/index.ts")
 	assert.Equal(t, expectedText, b.String())
 }
+
+func TestOutputDependentCount(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+resource "aws_subnet" "subnet" {
+  count = "${length(aws_vpc.main.id)}"
+}
+`
+	tree := testutil.LoadModuleTree(t, map[string]string{"main.tf": source})
+
+	// By default, binding fails at the count.
+	_, err := il.BuildGraph(tree, &il.BuildOptions{AllowMissingProviders: true, AllowMissingComments: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the count of aws_subnet.subnet depends on the outputs of aws_vpc.main")
+	}
+
+	// When accumulating errors, the count is generated as an expression that throws.
+	g, err := il.BuildGraph(tree, &il.BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+		AccumulateErrors:      true,
+		Logger:                log.New(ioutil.Discard, "", 0),
+	})
+	assert.Error(t, err)
+
+	var b bytes.Buffer
+	lang, err := New("main", "1.0.0", false, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "for (let i = 0; i < (() => {\n"+
+		`    throw "tf2pulumi error: the count of aws_subnet.subnet depends on the outputs of aws_vpc.main`)
+}
+
+func TestCreateBeforeDestroy(t *testing.T) {
+	const source = `
+resource "aws_launch_configuration" "config" {
+  name_prefix = "config-"
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+resource "aws_autoscaling_group" "group" {
+  count                = 2
+  launch_configuration = "${aws_launch_configuration.config.name}"
+  name                 = "group-${aws_launch_configuration.config.name}-${count.index}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `const config = new aws.LaunchConfiguration("config", {
+    namePrefix: "config-",
+}, { deleteBeforeReplace: false });`)
+	assert.Contains(t, code, "launchConfiguration: config.name,")
+	assert.Contains(t, code, "name: pulumi.interpolate`group-${config.name}-${i}`,")
+	assert.Equal(t, 1, strings.Count(code, "deleteBeforeReplace"))
+}
+
+func TestStringToNumberCoercion(t *testing.T) {
+	const source = `
+variable "instance_count" {
+  default = "3"
+}
+
+resource "test_source" "source" {}
+
+resource "test_group" "literal" {
+  size = "${var.instance_count}"
+  name = "${var.instance_count}"
+}
+
+resource "test_group" "output" {
+  size = "${test_source.source.size}"
+}
+
+resource "test_group" "constant" {
+  size = "5"
+}
+
+resource "test_group" "counted" {
+  count = "${var.instance_count}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_source": {
+			Schema: map[string]*schema.Schema{
+				"size": {Type: schema.TypeString, Computed: true},
+			},
+		},
+		"test_group": {
+			Schema: map[string]*schema.Schema{
+				"size": {Type: schema.TypeInt, Optional: true},
+				"name": {Type: schema.TypeString, Optional: true},
+			},
+		},
+	})
+
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, "size: Number.parseFloat(instanceCount),")
+	assert.Contains(t, code, "name: instanceCount,")
+	assert.Contains(t, code, "size: source.size.apply(size => Number.parseFloat(size)),")
+	assert.Contains(t, code, "size: 5,")
+	assert.Contains(t, code, "for (let i = 0; i < Number.parseFloat(instanceCount); i++) {")
+}
+
+func TestNumberToStringOutputCoercion(t *testing.T) {
+	const source = `
+resource "test_source" "source" {}
+
+resource "test_group" "group" {
+  name  = "${test_source.source.port}"
+  names = ["${test_source.source.port}", "${test_source.source.enabled}"]
+  label = "port ${test_source.source.port}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_source": {
+			Schema: map[string]*schema.Schema{
+				"port":    {Type: schema.TypeInt, Computed: true},
+				"enabled": {Type: schema.TypeBool, Computed: true},
+			},
+		},
+		"test_group": {
+			Schema: map[string]*schema.Schema{
+				"name":  {Type: schema.TypeString, Optional: true},
+				"label": {Type: schema.TypeString, Optional: true},
+				"names": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	})
+
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, "name: source.port.apply(String),")
+	assert.Contains(t, code, "        source.port.apply(String),\n        source.enabled.apply(String),\n")
+	assert.Contains(t, code, "label: pulumi.interpolate`port ${source.port}`,")
+}
+
+func TestResourceReferences(t *testing.T) {
+	const source = `
+resource "test_security_group" "single" {}
+
+resource "test_security_group" "multi" {
+  count = 2
+}
+
+resource "test_instance" "ids" {
+  security_group_ids = ["${test_security_group.single.id}", "${test_security_group.multi.*.id}"]
+  security_group     = "${test_security_group.single.id}"
+  name               = "${test_security_group.single.id}"
+}
+
+resource "test_instance" "splat" {
+  security_group_ids = ["${test_security_group.multi.*.id}"]
+}
+
+resource "test_instance" "names" {
+  security_group = "${test_security_group.single.name}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_security_group": {
+			Schema: map[string]*schema.Schema{
+				"name": {Type: schema.TypeString, Computed: true},
+			},
+		},
+		"test_instance": {
+			Schema: map[string]*schema.Schema{
+				"security_group_ids": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"security_group": {Type: schema.TypeString, Optional: true},
+				"name":           {Type: schema.TypeString, Optional: true},
+			},
+		},
+	})
+	sgType := providers["test"].Resources["test_security_group"].Tok
+	providers["test"].Resources["test_instance"].Fields = map[string]*tfbridge.SchemaInfo{
+		"security_group_ids": {
+			Elem: &tfbridge.SchemaInfo{AltTypes: []tokens.Type{sgType}},
+		},
+		"security_group": {AltTypes: []tokens.Type{sgType}},
+	}
+
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, "    name: single.id,\n    securityGroup: single,\n")
+	assert.Contains(t, code, "securityGroupIds: [\n        single,\n        ...multi,\n    ],")
+	assert.Contains(t, code, "securityGroupIds: multi,")
+	assert.Contains(t, code, "securityGroup: single.name,")
+}
+
+func TestLossyCoercionWarnings(t *testing.T) {
+	const source = `
+variable "size" {}
+
+resource "test_group" "group" {
+  size  = "${var.size}"
+  label = "${var.size}"
+  fixed = "5"
+}
+
+resource "test_group" "half" {
+  size = 2.5
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_group": {
+			Schema: map[string]*schema.Schema{
+				"size":  {Type: schema.TypeInt, Optional: true},
+				"fixed": {Type: schema.TypeInt, Optional: true},
+				"label": {Type: schema.TypeString, Optional: true},
+			},
+		},
+	})
+	g := buildSourceWithProviders(t, source, providers)
+
+	var b bytes.Buffer
+	lang, err := New("main", "1.0.0", false, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+
+	diags := lang.(gen.DiagnosticReporter).Diagnostics()
+	if assert.Len(t, diags, 2) {
+		assert.Equal(t, hcl.DiagWarning, diags[0].Severity)
+		assert.Equal(t, "lossy coercion from string to number in test_group.group", diags[0].Summary)
+		assert.Equal(t, "the value of property size of test_group.group is converted from string to number at "+
+			"runtime; the conversion may fail or change the value if it is not a valid number", diags[0].Detail)
+		if assert.NotNil(t, diags[0].Subject) {
+			assert.Equal(t, "main.tf", path.Base(diags[0].Subject.Filename))
+			assert.Equal(t, 4, diags[0].Subject.Start.Line)
+		}
+
+		// Numbers with fractional parts are truncated when they are assigned to integer properties.
+		assert.Equal(t, "lossy coercion from number to integer in test_group.half", diags[1].Summary)
+		assert.Equal(t, "the value of property size of test_group.half is converted from number to integer at "+
+			"runtime; the conversion may fail or change the value if it is not a valid integer", diags[1].Detail)
+	}
+}
+
+func TestExternalDataSource(t *testing.T) {
+	const source = `
+data "external" "x" {
+  program = ["python", "x.py"]
+}
+
+resource "aws_s3_bucket" "b" {
+  bucket = "${data.external.x.result["foo"]}"
+  region = "${data.external.x.result.foo_bar}"
+  policy = "${data.external.x.result.foo-bar}-suffix"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "const externalExternal = pulumi.output(external.getExternal({\n    program: [")
+	assert.Contains(t, code, "bucket: externalExternal.apply(externalExternal => externalExternal.result[\"foo\"]),")
+	assert.Contains(t, code, "region: externalExternal.result.foo_bar,")
+	assert.Contains(t, code, "policy: pulumi.interpolate`${externalExternal.result[\"foo-bar\"]}-suffix`,")
+}
+
+func TestBackend(t *testing.T) {
+	const source = `
+variable "bucket" {}
+
+terraform {
+  backend "s3" {
+    bucket = "${var.bucket}"
+    key    = "state"
+  }
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `// The Terraform state for this program was stored using the "s3" backend with the following
+// settings:
+//
+//     {
+//         bucket: bucket,
+//         key: "state",
+//     }
+//
+// Pulumi stores the state for this program in the backend selected by `+"`pulumi login`"+`.
+`)
+	assert.Contains(t, code, `const bucket = config.require("bucket");`)
+}
+
+func TestMathFunctionCount(t *testing.T) {
+	const source = `
+variable "a" {
+  default = 1
+}
+
+variable "b" {
+  default = 2
+}
+
+variable "delta" {}
+
+resource "aws_instance" "x" {
+  count = "${max(var.a, var.b)}"
+}
+
+resource "aws_security_group" "y" {
+  count = "${abs(var.delta)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "for (let i = 0; i < Math.max(a, b); i++) {")
+	assert.Contains(t, code, "for (let i = 0; i < Math.abs(Number.parseFloat(delta)); i++) {")
+
+	// Counts that depend on outputs are reported as errors.
+	const outputSource = `
+resource "aws_vpc" "v" {}
+
+resource "aws_instance" "x" {
+  count = "${max(length(aws_vpc.v.id), 1)}"
+}
+`
+	_, err := il.BuildGraph(testutil.LoadModuleTree(t, map[string]string{"main.tf": outputSource}),
+		&il.BuildOptions{AllowMissingProviders: true, AllowMissingComments: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the count of aws_instance.x depends on the outputs of aws_vpc.v")
+	}
+}
+
+func TestComplexVariableDefaults(t *testing.T) {
+	const source = `
+variable "tags" {
+  default = {
+    Name = "web"
+    Env  = "prod"
+  }
+}
+
+variable "zones" {
+  default = ["a", "b"]
+}
+
+variable "mixed" {
+  default = {
+    name = "web"
+    size = 2
+  }
+}
+
+variable "name" {
+  default = "x"
+}
+
+resource "aws_instance" "x" {
+  tags              = "${var.tags}"
+  availability_zone = "${var.zones[0]}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `const tags = config.getObject<Record<string, string>>("tags") ?? {
+    Env: "prod",
+    Name: "web",
+};`)
+	assert.Contains(t, code, `const zones = config.getObject<string[]>("zones") ?? [`)
+	assert.Contains(t, code, `const mixed = config.getObject<Record<string, any>>("mixed") ?? {`)
+	assert.Contains(t, code, `const name = config.get("name") || "x";`)
+}
+
+func TestModuleInputTypes(t *testing.T) {
+	const childSource = `
+variable "ami" {}
+
+variable "count" {}
+
+variable "ports" {
+  default = [80, 443]
+}
+
+resource "aws_instance" "web" {
+  count = "${var.count}"
+  ami   = "${var.ami}"
+  ports = "${var.ports}"
+
+  tags {
+    Name = "web-${var.ami}"
+  }
+}
+`
+	const parentSource = `
+variable "amis" {
+  default = {
+    us-east-1 = "ami-1"
+  }
+}
+
+variable "region" {
+  default = "us-east-1"
+}
+
+module "child" {
+  source = "./child"
+  ami    = "${lookup(var.amis, var.region)}"
+  count  = 2
+}
+`
+	child, parent := buildModuleSources(t, parentSource, "child", childSource)
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{child, parent}, lang)
+	assert.NoError(t, err)
+
+	// The module's constructor accepts inputs for possibly-unknown variables and plain values for variables that are
+	// used in counts.
+	code := b.String()
+	assert.Contains(t, code, `const new_mod_child = function(mod_name: string, mod_args: {
+    ami: pulumi.Input<string>;
+    count: string;
+    ports?: pulumi.Input<pulumi.Input<number>[]>;
+}) {`)
+	assert.Contains(t, code, "Name: pulumi.interpolate`web-${ami}`,")
+
+	// The result of the lookup is passed as-is, and the numeric count is converted to the variable's type.
+	assert.Contains(t, code, `const child = new_mod_child("child", {
+    ami: lookup(amis, region),
+    count: "2",
+});`)
+}
+
+func TestCountedResourceNames(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+
+  tags {
+    Name = "web-${count.index}"
+  }
+}
+`
+	// Each instance of a counted resource is named using its index.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "for (let i = 0; i < 2; i++) {\n    web.push(new aws.Instance(`web-${i}`, {")
+	assert.Contains(t, code, "Name: `web-${i}`,")
+
+	const shadowSource = `
+variable "i" {
+  default = 3
+}
+
+resource "aws_instance" "web" {
+  count = "${var.i}"
+}
+`
+	// The loop variable does not shadow other names that are in scope.
+	code = generateSource(t, shadowSource)
+	assert.Contains(t, code, "for (let i1 = 0; i1 < i; i1++) {\n    web.push(new aws.Instance(`web-${i1}`, {")
+}
+
+func TestResourceTypeMapper(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  instance_type = "t2.micro"
+}
+
+resource "aws_custom_widget" "widget" {
+  size = 3
+}
+`
+	g := buildSourceWithProviders(t, source, missingProviderInfoSource{})
+
+	mapper := func(r *il.ResourceNode) (string, string, bool) {
+		if r.Type != "aws_custom_widget" {
+			return "", "", false
+		}
+		return "widgets", "Widget", true
+	}
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true, ResourceTypeMapper: mapper}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+
+	// Mapped resources use the custom class path. Other resources use the default path.
+	code := b.String()
+	assert.Contains(t, code, `const widget = new aws.widgets.Widget("widget", {`)
+	assert.Contains(t, code, `const web = new aws.Instance("web", {`)
+}
+
+func TestAssets(t *testing.T) {
+	const source = `
+resource "test_function" "fn" {
+  package = "${file("lambda.zip")}"
+}
+
+resource "test_object" "file" {
+  source = "index.html"
+}
+
+resource "test_object" "contents" {
+  content = "<h1>hello</h1>"
+}
+
+resource "test_object" "read" {
+  content = "${file("index.html")}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_function": {
+			Schema: map[string]*schema.Schema{
+				"package": {Type: schema.TypeString, Optional: true},
+			},
+		},
+		"test_object": {
+			Schema: map[string]*schema.Schema{
+				"source":  {Type: schema.TypeString, Optional: true},
+				"content": {Type: schema.TypeString, Optional: true},
+			},
+		},
+	})
+	providers["test"].Resources["test_function"].Fields = map[string]*tfbridge.SchemaInfo{
+		"package": {Asset: &tfbridge.AssetTranslation{Kind: tfbridge.FileAsset}},
+	}
+	providers["test"].Resources["test_object"].Fields = map[string]*tfbridge.SchemaInfo{
+		"source":  {Asset: &tfbridge.AssetTranslation{Kind: tfbridge.FileAsset}},
+		"content": {Asset: &tfbridge.AssetTranslation{Kind: tfbridge.BytesAsset}},
+	}
+
+	// Files passed to asset-typed properties are referenced by path rather than read. Paths and contents are wrapped
+	// in the appropriate asset type.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, `package: new pulumi.asset.FileAsset("lambda.zip"),`)
+	assert.Contains(t, code, `source: new pulumi.asset.FileAsset("index.html"),`)
+	assert.Contains(t, code, `content: new pulumi.asset.StringAsset("<h1>hello</h1>"),`)
+	assert.Contains(t, code, `content: new pulumi.asset.FileAsset("index.html"),`)
+	assert.NotContains(t, code, `import * as fs from "fs";`)
+}
+
+func TestNestedListInputs(t *testing.T) {
+	const source = `
+variable "extra_rules" {
+  type = "list"
+}
+
+resource "test_rules" "defaults" {}
+
+resource "test_security_group" "web" {
+  ingress = ["${test_rules.defaults.rules}", {
+    from_port   = 443
+    to_port     = 443
+    protocol    = "tcp"
+    cidr_blocks = ["${test_rules.defaults.cidr_block}"]
+  }]
+}
+
+resource "test_security_group" "extra" {
+  ingress = ["${var.extra_rules}", {
+    from_port = 22
+    to_port   = 22
+    protocol  = "tcp"
+  }]
+}
+`
+	rule := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"from_port":   {Type: schema.TypeInt, Required: true},
+			"to_port":     {Type: schema.TypeInt, Required: true},
+			"protocol":    {Type: schema.TypeString, Required: true},
+			"cidr_blocks": {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+		},
+	}
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_rules": {
+			Schema: map[string]*schema.Schema{
+				"cidr_block": {Type: schema.TypeString, Computed: true},
+				"rules":      {Type: schema.TypeList, Computed: true, Elem: rule},
+			},
+		},
+		"test_security_group": {
+			Schema: map[string]*schema.Schema{
+				"ingress": {Type: schema.TypeList, Optional: true, Elem: rule},
+			},
+		},
+	})
+
+	// Output-typed lists of rules are resolved and flattened inside an apply, while the outputs nested within other
+	// rules are left as inputs.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, `ingresses: pulumi.all([
+        defaults.rules,
+        {
+            cidrBlocks: [defaults.cidrBlock],
+            fromPort: 443,
+            protocol: "tcp",
+            toPort: 443,
+        },
+    ]).apply(elements => (<any[]>[]).concat(...elements)),`)
+
+	// Variables that are declared as lists are read as arrays and spread into the list.
+	assert.Contains(t, code, `const extraRules = config.requireObject<any[]>("extraRules");`)
+	assert.Contains(t, code, `ingresses: [
+        ...extraRules,
+        {`)
+}
+
+func TestRequiredCollectionVariables(t *testing.T) {
+	const source = `
+variable "name" {}
+
+variable "names" {
+  type = "list"
+}
+
+variable "tags" {
+  type = "map"
+}
+
+resource "aws_instance" "web" {
+  count = "${length(var.names)}"
+  name  = "${var.names[count.index]}"
+  owner = "${lookup(var.tags, "owner")}"
+  label = "${var.name}"
+  tags  = "${var.tags}"
+}
+`
+	// Required variables that are declared as lists or maps are read as objects rather than as strings, so they can be
+	// counted, indexed, and passed to list- and map-typed properties without conversion.
+	code := generateSource(t, source)
+	assert.Contains(t, code, `const name = config.require("name");`)
+	assert.Contains(t, code, `const names = config.requireObject<any[]>("names");`)
+	assert.Contains(t, code, `const tags = config.requireObject<Record<string, any>>("tags");`)
+	assert.Contains(t, code, "for (let i = 0; i < names.length; i++) {")
+	assert.Contains(t, code, "name: names[i],")
+	assert.Contains(t, code, `owner: lookup(tags, "owner"),`)
+	assert.Contains(t, code, "tags: tags,")
+}
+
+func TestNestedSplats(t *testing.T) {
+	const source = `
+resource "test_rules" "counted" {
+  count = 2
+}
+
+resource "test_rules" "single" {}
+
+output "ports" {
+  value = "${test_rules.counted.*.rules.*.from_port}"
+}
+
+output "protocols" {
+  value = "${join(",", test_rules.single.rules.*.protocol)}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_rules": {
+			Schema: map[string]*schema.Schema{
+				"rules": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"from_port": {Type: schema.TypeInt, Required: true},
+							"protocol":  {Type: schema.TypeString, Required: true},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	// Nested splats are typed as flat lists of the accessed property's type.
+	g := buildSourceWithProviders(t, source, providers)
+	ports := g.Outputs["ports"].Value.(*il.BoundVariableAccess)
+	assert.Equal(t, il.TypeNumber.ListOf().OutputOf(), ports.Type())
+
+	// Splats over nested blocks are mapped over the resolved lists inside an apply. The lists produced by splatting
+	// over the instances of a counted resource are flattened.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, "export const ports = pulumi.all(counted.map((v: test.Rules) => v.rules))"+
+		".apply(rules => (<any[]>[]).concat(...rules.map(v => v.map(v1 => v1.fromPort))));")
+	assert.Contains(t, code, `export const protocols = single.rules.apply(rules => rules.map(v => v.protocol).join(","));`)
+}
+
+func TestMixedOutputAndLiteralArgs(t *testing.T) {
+	const source = `
+variable "enabled" {
+  default = true
+}
+
+resource "test_source" "src" {}
+
+resource "test_target" "dst" {
+  name    = "${test_source.src.port}"
+  enabled = "${var.enabled ? test_source.src.status : "false"}"
+  ports   = ["${test_source.src.port}", 80, "81"]
+
+  tags {
+    Name  = "${test_source.src.status}"
+    Count = 2
+  }
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_source": {
+			Schema: map[string]*schema.Schema{
+				"port":   {Type: schema.TypeInt, Computed: true},
+				"status": {Type: schema.TypeString, Computed: true},
+			},
+		},
+		"test_target": {
+			Schema: map[string]*schema.Schema{
+				"name":    {Type: schema.TypeString, Optional: true},
+				"enabled": {Type: schema.TypeBool, Optional: true},
+				"ports":   {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeInt}},
+				"tags":    {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+			},
+		},
+	})
+
+	// Plain values are left as-is alongside outputs. The branches of a conditional that mixes an output and a plain
+	// value of different types are each converted to the type of the argument.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, `const dst = new test.Target("dst", {
+    enabled: src.status.apply(status => (enabled ? (status === "true") : false)),
+    name: src.port.apply(String),
+    ports: [
+        src.port,
+        80,
+        81,
+    ],
+    tags: {
+        Count: "2",
+        Name: src.status,
+    },
+});`)
+}
+
+func TestFeatureToggleCount(t *testing.T) {
+	const source = `
+variable "features" {
+  default = {
+    enable_web = true
+  }
+}
+
+resource "aws_instance" "web" {
+  count = "${lookup(var.features, "enable_web", false) ? 1 : 0}"
+}
+`
+	// The lookup is typed by its boolean default, so the count is a plain boolean condition.
+	g := buildSource(t, source)
+	count := g.Resources["aws_instance.web"].Count.(*il.BoundConditional)
+	assert.Equal(t, il.TypeBool, count.CondExpr.Type())
+
+	// The keys of the map are preserved so that the lookup finds them, and the resource is created conditionally.
+	code := generateSource(t, source)
+	assert.Contains(t, code, `const features = config.getObject<Record<string, boolean>>("features") ?? {
+    enable_web: true,
+};`)
+	assert.Contains(t, code, `let web: aws.Instance | undefined;
+if ((<boolean>lookup(features, "enable_web", false))) {
+    web = new aws.Instance("web", {});
+}`)
+}
+
+func TestLocalOutputs(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+locals {
+  vpc_name = "${aws_vpc.main.id}-vpc"
+  prefix   = "web"
+  ids      = ["${aws_vpc.main.id}"]
+}
+
+resource "aws_subnet" "main" {
+  vpc_name = "${local.vpc_name}-subnet"
+  prefix   = "${local.prefix}-subnet"
+  count    = "${length(local.ids)}"
+}
+`
+	// A local whose value interpolates an output is itself an output. A list of outputs is a plain list.
+	g := buildSource(t, source)
+	props := g.Resources["aws_subnet.main"].Properties.Elements
+	vpcName := props["vpc_name"].(*il.BoundOutput).Exprs[0].(*il.BoundVariableAccess)
+	assert.Equal(t, il.TypeString.OutputOf(), vpcName.Type())
+	assert.Equal(t, g.Locals["vpc_name"], vpcName.ILNode)
+	prefix := props["prefix"].(*il.BoundOutput).Exprs[0].(*il.BoundVariableAccess)
+	assert.Equal(t, il.TypeString, prefix.Type())
+
+	code := generateSource(t, source)
+	assert.Contains(t, code, "const vpcName = pulumi.interpolate`${mainVpc.id}-vpc`;")
+	assert.Contains(t, code, "vpcName: pulumi.interpolate`${vpcName}-subnet`,")
+	assert.Contains(t, code, "prefix: `${prefix}-subnet`,")
+	assert.Contains(t, code, "i < ids.length;")
+}
+
+func TestCollectionLocalOutputs(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+locals {
+  ids = ["${aws_vpc.main.id}", "default"]
+}
+
+resource "aws_subnet" "main" {
+  count  = "${length(local.ids)}"
+  joined = "${join(",", local.ids)}"
+  ids    = "${local.ids}"
+}
+`
+	// An access to a list local whose elements are outputs is itself an output, except as the argument to length:
+	// the local is generated as a plain list, so its length is known.
+	g := buildSource(t, source)
+	subnet := g.Resources["aws_subnet.main"]
+	join := subnet.Properties.Elements["joined"].(*il.BoundCall)
+	assert.True(t, join.Args[1].Type().IsOutput())
+	length := subnet.Count.(*il.BoundCall)
+	assert.False(t, length.Args[0].Type().IsOutput())
+
+	code := generateSource(t, source)
+	assert.Contains(t, code, "const ids = [\n    mainVpc.id,\n    \"default\",\n];")
+	assert.Contains(t, code, "i < ids.length;")
+	assert.Contains(t, code, `joined: pulumi.output(ids).apply(ids => ids.join(",")),`)
+	assert.Contains(t, code, "ids: ids,")
+}
+
+func TestLocalOrdering(t *testing.T) {
+	const source = `
+locals {
+  subnet_name = "${local.vpc_name}-subnet"
+  vpc_name    = "${var.env}-${aws_vpc.main.id}"
+  cidr        = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "main" {
+  name = "${local.subnet_name}"
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = "${local.cidr}"
+}
+
+variable "env" {
+  default = "dev"
+}
+`
+	// Locals are declared as constants after the variables and resources they reference and before the resources
+	// that reference them, regardless of the order in which they appear in the configuration.
+	code := generateSource(t, source)
+	assert.Contains(t, code, `const env = config.get("env") || "dev";
+
+const cidr = "10.0.0.0/16";
+const mainVpc = new aws.Vpc("main", {
+    cidrBlock: cidr,
+});
+const vpcName = pulumi.interpolate`+"`${env}-${mainVpc.id}`"+`;
+const subnetName = pulumi.interpolate`+"`${vpcName}-subnet`"+`;
+const mainSubnet = new aws.Subnet("main", {
+    name: subnetName,
+});`)
+}
+
+func TestManifest(t *testing.T) {
+	const childSource = `
+resource "aws_vpc" "main" {}
+`
+	const parentSource = `
+variable "enabled" {
+  default = true
+}
+
+resource "aws_instance" "web" {
+  count = 2
+}
+
+resource "aws_eip" "ip" {
+  count = "${var.enabled}"
+}
+
+data "aws_ami" "ubuntu" {}
+
+module "network" {
+  source = "./network"
+}
+`
+	child, parent := buildModuleSources(t, parentSource, "network", childSource)
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{child, parent}, lang)
+	assert.NoError(t, err)
+
+	// Data sources are not included. Resources in child modules are prefixed with the name of the module instance.
+	manifest := lang.(gen.ManifestReporter).Manifest()
+	assert.Equal(t, []gen.ManifestEntry{
+		{Address: "aws_eip.ip", Variable: "ip", Name: "ip"},
+		{Address: "aws_instance.web", Variable: "web", Name: "web", Counted: true},
+		{Address: "module.network.aws_vpc.main", Variable: "main", Name: "network_main"},
+	}, manifest.Resources)
+
+	var j bytes.Buffer
+	err = manifest.WriteJSON(&j)
+	assert.NoError(t, err)
+	assert.Contains(t, j.String(), `{
+            "address": "aws_instance.web",
+            "variable": "web",
+            "name": "web",
+            "counted": true
+        },`)
+}
+
+func TestAnnotateTypes(t *testing.T) {
+	const source = `
+variable "zones" {
+  default = ["a", "b"]
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  zone = "${element(var.zones, 1)}"
+  name = "web-${aws_vpc.main.id}"
+}
+`
+	generate := func(annotateTypes bool) string {
+		g := buildSourceWithProviders(t, source, missingProviderInfoSource{})
+
+		var b bytes.Buffer
+		lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true, AnnotateTypes: annotateTypes}, &b)
+		assert.NoError(t, err)
+		err = gen.Generate([]*il.Graph{g}, lang)
+		assert.NoError(t, err)
+		return b.String()
+	}
+
+	code := generate(true)
+	assert.Contains(t, code, "zone: element(zones /* :list<string> */, 1 /* :number */) /* :string */,")
+	assert.Contains(t, code, "main.id /* :output<string> */")
+
+	// Types are not annotated by default.
+	assert.NotContains(t, generate(false), "/* :")
+}
+
+// buildModuleSources builds graphs for a configuration that consists of the given root module source and a single child
+// module with the given name and source. The child module's source is located in a directory with the same name as the
+// module. The child module's graph is returned first.
+func buildModuleSources(t *testing.T, source, childName, childSource string) (*il.Graph, *il.Graph) {
+	tree := testutil.LoadModuleTree(t, map[string]string{
+		"main.tf":                       source,
+		path.Join(childName, "main.tf"): childSource,
+	})
+
+	opts := &il.BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+		Logger:                log.New(ioutil.Discard, "", 0),
+	}
+	child, err := il.BuildGraph(tree.Children()[childName], opts)
+	if err != nil {
+		t.Fatalf("could not build child graph: %v", err)
+	}
+	opts.ChildModules = map[string]*il.Graph{childName: child}
+	parent, err := il.BuildGraph(tree, opts)
+	if err != nil {
+		t.Fatalf("could not build parent graph: %v", err)
+	}
+	return child, parent
+}
+
+func TestModuleOutputReferences(t *testing.T) {
+	const childSource = `
+resource "aws_vpc" "main" {}
+
+output "vpc_id" {
+  value = "${aws_vpc.main.id}"
+}
+
+output "subnet_ids" {
+  value = ["a", "b"]
+}
+`
+	const source = `
+module "network" {
+  source = "./network"
+}
+
+resource "aws_subnet" "subnet" {
+  vpc_id    = "${module.network.vpc_id}"
+  subnet_id = "${element(module.network.subnet_ids, 1)}"
+  name      = "subnet-${module.network.vpc_id}"
+}
+`
+	child, parent := buildModuleSources(t, source, "network", childSource)
+
+	var b bytes.Buffer
+	lang, err := NewWithOptions("main", "1.0.0", Options{CheckDelimiters: true}, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{child, parent}, lang)
+	assert.NoError(t, err)
+
+	code := b.String()
+	assert.Contains(t, code, "const network = new_mod_network(\"network\", {});")
+	assert.Contains(t, code, "vpcId: network.vpcId,")
+	assert.Contains(t, code, "subnetId: network.subnetIds.apply(subnetIds => element(subnetIds, 1)),")
+	assert.Contains(t, code, "name: pulumi.interpolate`subnet-${network.vpcId}`,")
+}
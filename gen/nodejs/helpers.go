@@ -0,0 +1,345 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+// utilitiesModule is the name of the module into which the runtime helpers used by a generated program are emitted.
+// The program imports the helpers it uses from this module.
+const utilitiesModule = "utilities"
+
+// A runtimeHelper is a function that implements a Terraform interpolation function at runtime.
+type runtimeHelper struct {
+	// code is the definition of the helper.
+	code string
+	// deps is the list of names of the internal definitions used by the helper. Internal definitions are emitted into
+	// the utilities module, but are not imported by the program.
+	deps []string
+	// imports is the list of import statements required by the helper's definition.
+	imports []string
+}
+
+// runtimeHelpers maps the names of runtime helpers and their internal definitions to the helpers themselves. The name
+// of each helper that may be called by a generated program must match the name of the function the helper defines.
+var runtimeHelpers = map[string]runtimeHelper{
+	"cidr":        {code: cidrHelper},
+	"cidrhost":    {code: cidrhostHelper, deps: []string{"cidr"}},
+	"cidrnetmask": {code: cidrnetmaskHelper},
+	"cidrsubnet":  {code: cidrsubnetHelper, deps: []string{"cidr"}},
+	"element":     {code: elementHelper},
+	"formatdate":  {code: formatdateHelper},
+	"index":       {code: indexHelper},
+	"lookup":      {code: lookupHelper},
+	"regexall":    {code: regexallHelper},
+	"replace":     {code: replaceHelper},
+	"substr":      {code: substrHelper},
+	"timecmp":     {code: timecmpHelper},
+	"title":       {code: titleHelper},
+	"uuid":        {code: uuidHelper, imports: []string{`import * as crypto from "crypto";`}},
+	"values":      {code: valuesHelper},
+	"zipmap":      {code: zipmapHelper},
+}
+
+// cidrHelper is the definition of the helper functions shared by the implementations of Terraform's `cidrhost` and
+// `cidrsubnet` functions. Addresses are represented as arrays of bytes so that IPv4 and IPv6 prefixes can be handled
+// uniformly. As in Terraform, IPv6 addresses are formatted in their canonical compressed form.
+const cidrHelper = `function parseCIDR(prefix: string): { bytes: number[], length: number } {
+    const [address, length] = prefix.split("/");
+    let bytes: number[] | undefined;
+    if (address.indexOf(":") === -1) {
+        const octets = address.split(".");
+        if (octets.length === 4 && octets.every(o => /^[0-9]{1,3}$/.test(o) && Number(o) <= 255)) {
+            bytes = octets.map(Number);
+        }
+    } else {
+        const halves = address.split("::");
+        const parseGroups = (s: string) => s === "" ? [] : s.split(":");
+        const head = parseGroups(halves[0]), tail = halves.length === 2 ? parseGroups(halves[1]) : [];
+        const missing = 8 - head.length - tail.length;
+        const groups = halves.length === 2 ? [...head, ...new Array(Math.max(missing, 0)).fill("0"), ...tail] : head;
+        const valid = halves.length === 1 ? missing === 0 : halves.length === 2 && missing > 0;
+        if (valid && groups.every(g => /^[0-9a-fA-F]{1,4}$/.test(g))) {
+            bytes = ([] as number[]).concat(...groups.map(g => [parseInt(g, 16) >> 8, parseInt(g, 16) & 0xff]));
+        }
+    }
+    const bits = Number(length);
+    if (bytes === undefined || length === undefined || !Number.isInteger(bits) || bits < 0 || bits > bytes.length * 8) {
+        throw new Error(` + "`" + `invalid CIDR prefix "${prefix}"` + "`" + `);
+    }
+    setBits(bytes, bits, bytes.length * 8 - bits, 0);
+    return { bytes, length: bits };
+}
+
+function setBits(bytes: number[], start: number, count: number, value: number) {
+    for (let i = 0; i < count; i++) {
+        const bit = Math.floor(value / Math.pow(2, count - 1 - i)) % 2;
+        const mask = 0x80 >> ((start + i) % 8);
+        const index = Math.floor((start + i) / 8);
+        bytes[index] = bit ? bytes[index] | mask : bytes[index] & ~mask;
+    }
+}
+
+function formatIP(bytes: number[]): string {
+    if (bytes.length === 4) {
+        return bytes.join(".");
+    }
+    const groups: number[] = [];
+    for (let i = 0; i < bytes.length; i += 2) {
+        groups.push((bytes[i] << 8) | bytes[i + 1]);
+    }
+    // The longest run of at least two zero groups is elided.
+    let start = -1, length = 0;
+    for (let i = 0; i < groups.length; i++) {
+        let j = i;
+        while (j < groups.length && groups[j] === 0) {
+            j++;
+        }
+        if (j - i >= 2 && j - i > length) {
+            start = i, length = j - i;
+        }
+        i = j;
+    }
+    const hex = groups.map(g => g.toString(16));
+    if (start === -1) {
+        return hex.join(":");
+    }
+    return hex.slice(0, start).join(":") + "::" + hex.slice(start + length).join(":");
+}
+`
+
+// cidrhostHelper is the definition of the helper function used to implement Terraform's `cidrhost` function. As in
+// Terraform, a negative host number counts backwards from the end of the range.
+const cidrhostHelper = `function cidrhost(prefix: string, hostnum: number): string {
+    const { bytes, length } = parseCIDR(prefix);
+    const hostbits = bytes.length * 8 - length;
+    const count = Math.pow(2, hostbits);
+    const host = hostnum < 0 ? count + hostnum : hostnum;
+    if (!Number.isInteger(host) || host < 0 || host >= count) {
+        throw new Error(` + "`" + `prefix of ${length} does not accommodate a host numbered ${hostnum}` + "`" + `);
+    }
+    setBits(bytes, length, hostbits, host);
+    return formatIP(bytes);
+}
+`
+
+// cidrnetmaskHelper is the definition of the helper function used to implement Terraform's `cidrnetmask` function. As
+// in Terraform, only IPv4 prefixes are supported.
+const cidrnetmaskHelper = `function cidrnetmask(prefix: string): string {
+    const [address, length] = prefix.split("/");
+    const bits = Number(length);
+    if (address.indexOf(":") !== -1) {
+        throw new Error(` + "`" + `cidrnetmask only supports IPv4 prefixes; "${prefix}" is an IPv6 prefix` + "`" + `);
+    }
+    if (length === undefined || !Number.isInteger(bits) || bits < 0 || bits > 32) {
+        throw new Error(` + "`" + `invalid CIDR prefix "${prefix}"` + "`" + `);
+    }
+    const mask = bits === 0 ? 0 : (~0 << (32 - bits)) >>> 0;
+    return [24, 16, 8, 0].map(shift => (mask >>> shift) & 0xff).join(".");
+}
+`
+
+// cidrsubnetHelper is the definition of the helper function used to implement Terraform's `cidrsubnet` function.
+const cidrsubnetHelper = `function cidrsubnet(prefix: string, newbits: number, netnum: number): string {
+    const { bytes, length } = parseCIDR(prefix);
+    const newLength = length + newbits;
+    if (newLength > bytes.length * 8) {
+        throw new Error(` + "`" + `insufficient address space to extend prefix of ${length} by ${newbits}` + "`" + `);
+    }
+    if (!Number.isInteger(netnum) || netnum < 0 || netnum >= Math.pow(2, newbits)) {
+        throw new Error(` + "`" + `prefix extension of ${newbits} ` + "`" + ` +
+            ` + "`" + `does not accommodate a subnet numbered ${netnum}` + "`" + `);
+    }
+    setBits(bytes, length, newbits, netnum);
+    return ` + "`" + `${formatIP(bytes)}/${newLength}` + "`" + `;
+}
+`
+
+// elementHelper is the definition of the helper function used to implement Terraform's `element` function. As in
+// Terraform, the index wraps around the length of the list, and the list must not be empty.
+const elementHelper = `function element(list: any[], index: number): any {
+    if (list.length === 0) {
+        throw new Error("element() may not be used with an empty list");
+    }
+    return list[Math.floor(index) % list.length];
+}
+`
+
+// formatdateHelper is the definition of the helper function used to implement Terraform's `formatdate` function. As in
+// Terraform, the timestamp must be an RFC 3339 timestamp, and it is formatted in its own time zone.
+const formatdateHelper = `function formatdate(spec: string, time: string): string {
+    const match = /^(\d{4})-(\d{2})-(\d{2})[Tt](\d{2}):(\d{2}):(\d{2})(\.\d+)?([Zz]|[+-]\d{2}:\d{2})$/.exec(time);
+    if (match === null) {
+        throw new Error(` + "`" + `not a valid RFC3339 timestamp: "${time}"` + "`" + `);
+    }
+    const [year, month, day, hour, minute, second] = match.slice(1, 7).map(Number);
+    const offset = match[8].toUpperCase() === "Z" ? "+00:00" : match[8];
+    const date = new Date(Date.UTC(year, month - 1, day));
+    const months = ["January", "February", "March", "April", "May", "June", "July", "August", "September", "October",
+        "November", "December"];
+    const days = ["Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"];
+    const pad = (n: number, width: number) => ("000" + n).slice(-width);
+    const verbs: {[verb: string]: string} = {
+        YYYY: pad(year, 4), YY: pad(year % 100, 2),
+        MMMM: months[month - 1], MMM: months[month - 1].slice(0, 3), MM: pad(month, 2), M: String(month),
+        DD: pad(day, 2), D: String(day),
+        EEEE: days[date.getUTCDay()], EEE: days[date.getUTCDay()].slice(0, 3),
+        hh: pad(hour, 2), h: String(hour), HH: pad(hour % 12 || 12, 2), H: String(hour % 12 || 12),
+        AA: hour < 12 ? "AM" : "PM", aa: hour < 12 ? "am" : "pm",
+        mm: pad(minute, 2), m: String(minute), ss: pad(second, 2), s: String(second),
+        ZZZZZ: offset, ZZZZ: offset.replace(":", ""), ZZZ: offset === "+00:00" ? "UTC" : offset.replace(":", ""),
+        Z: offset === "+00:00" ? "Z" : offset,
+    };
+    return spec.replace(/'((?:[^']|'')*)'|([A-Za-z])\2*/g, (token: string, quoted?: string) => {
+        if (quoted !== undefined) {
+            return quoted.replace(/''/g, "'");
+        }
+        if (!(token in verbs)) {
+            throw new Error(` + "`" + `invalid date format verb "${token}"` + "`" + `);
+        }
+        return verbs[token];
+    });
+}
+`
+
+// indexHelper is the definition of the helper function used to implement Terraform's `index` function. As in
+// Terraform, it is an error if the list does not contain the value.
+const indexHelper = `function index(list: any[], value: any): number {
+    const i = list.indexOf(value);
+    if (i === -1) {
+        throw new Error(` + "`" + `could not find "${value}" in the list` + "`" + `);
+    }
+    return i;
+}
+`
+
+// lookupHelper is the definition of the helper function used to implement Terraform's `lookup` function. As in
+// Terraform, the default value is returned only if the map does not contain the key, and a missing key is an error
+// if there is no default value.
+const lookupHelper = `function lookup(map: any, key: string | number, ...defaultValue: any[]): any {
+    if (map !== undefined && map !== null && Object.prototype.hasOwnProperty.call(map, key)) {
+        return map[key];
+    }
+    if (defaultValue.length === 0) {
+        throw new Error(` + "`" + `lookup failed to find "${key}"` + "`" + `);
+    }
+    return defaultValue[0];
+}
+`
+
+// regexallHelper is the definition of the helper function used to implement Terraform's `regexall` function. If the
+// pattern has capture groups, each match is a list of the captured substrings; otherwise, each match is the matched
+// substring.
+const regexallHelper = `function regexall(pattern: string, str: string): any[] {
+    const re = new RegExp(pattern, "g");
+    const matches: any[] = [];
+    for (let m = re.exec(str); m !== null; m = re.exec(str)) {
+        if (m[0] === "") {
+            re.lastIndex++;
+        }
+        matches.push(m.length === 1 ? m[0] : m.slice(1));
+    }
+    return matches;
+}
+`
+
+// replaceHelper is the definition of the helper function used to implement Terraform's `replace` function when the
+// search argument is not a literal. As in Terraform, a search argument that is wrapped in forward slashes is a regular
+// expression, and every occurrence of the search argument is replaced.
+const replaceHelper = `function replace(str: string, search: string, replacement: string): string {
+    if (search.length > 1 && search[0] === "/" && search[search.length - 1] === "/") {
+        return str.replace(new RegExp(search.slice(1, -1), "g"), replacement);
+    }
+    return str.split(search).join(replacement);
+}
+`
+
+// substrHelper is the definition of the helper function used to implement Terraform's `substr` function. As in
+// Terraform, a negative offset counts backwards from the end of the string, and a length of -1 extends the substring
+// to the end of the string.
+const substrHelper = `function substr(str: string, offset: number, length: number): string {
+    if (offset < 0) {
+        offset += str.length;
+    }
+    let end: number;
+    if (length === -1) {
+        end = str.length;
+    } else if (length >= 0) {
+        end = offset + length;
+    } else {
+        throw new Error("length should be a non-negative integer");
+    }
+    if (offset > str.length || offset < 0) {
+        throw new Error("offset cannot be larger than the length of the string");
+    }
+    if (end > str.length) {
+        throw new Error("'offset + length' cannot be larger than the length of the string");
+    }
+    return str.slice(offset, end);
+}
+`
+
+// timecmpHelper is the definition of the helper function used to implement Terraform's `timecmp` function. As in
+// Terraform, both timestamps must be RFC 3339 timestamps; the result is -1, 0, or 1.
+const timecmpHelper = `function timecmp(a: string, b: string): number {
+    const parse = (timestamp: string): number => {
+        const rfc3339 = /^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$/i;
+        const time = rfc3339.test(timestamp) ? Date.parse(timestamp) : NaN;
+        if (Number.isNaN(time)) {
+            throw new Error(` + "`" + `not a valid RFC3339 timestamp: "${timestamp}"` + "`" + `);
+        }
+        return time;
+    };
+    return Math.sign(parse(a) - parse(b));
+}
+`
+
+// titleHelper is the definition of the helper function used to implement Terraform's `title` function. As in
+// Terraform, the first letter of each word is capitalized. Words are separated by any character that is not a letter,
+// a digit, or an underscore.
+const titleHelper = `function title(str: string): string {
+    const isSeparator = (c: string) => c.toLowerCase() === c.toUpperCase() && !/[0-9_]/.test(c);
+    return str.split("").map((c, i) => i === 0 || isSeparator(str[i - 1]) ? c.toUpperCase() : c).join("");
+}
+`
+
+// uuidHelper is the definition of the helper function used to implement Terraform's `uuid` function. The result is a
+// random (version 4) UUID as described by RFC 4122.
+const uuidHelper = `function uuid(): string {
+    const bytes = crypto.randomBytes(16);
+    bytes[6] = (bytes[6] & 0x0f) | 0x40;
+    bytes[8] = (bytes[8] & 0x3f) | 0x80;
+    const hex = bytes.toString("hex");
+    return [hex.slice(0, 8), hex.slice(8, 12), hex.slice(12, 16), hex.slice(16, 20), hex.slice(20)].join("-");
+}
+`
+
+// valuesHelper is the definition of the helper function used to implement Terraform's `values` function. As in
+// Terraform, the values are ordered by their keys.
+const valuesHelper = `function values(map: any): any[] {
+    return Object.keys(map).sort().map(key => map[key]);
+}
+`
+
+// zipmapHelper is the definition of the helper function used to implement Terraform's `zipmap` function. As in
+// Terraform, the lists of keys and values must have the same length.
+const zipmapHelper = `function zipmap(keys: string[], values: any[]): {[key: string]: any} {
+    if (keys.length !== values.length) {
+        throw new Error(` + "`" + `count of keys (${keys.length}) ` + "`" + ` +
+            ` + "`" + `does not match count of values (${values.length})` + "`" + `);
+    }
+    return keys.reduce((m: {[key: string]: any}, k, i) => {
+        m[k] = values[i];
+        return m;
+    }, {});
+}
+`
@@ -17,6 +17,7 @@ package nodejs
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/hil/ast"
@@ -30,6 +31,12 @@ import (
 // This file contains the code necessary to generate code for bound expression trees. It is the responsibility of each
 // node-specific generation function to ensure that the generated code is appropriately parenthesized where necessary
 // in order to avoid unexpected issues with operator precedence.
+//
+// References to output-typed values are not resolved here. Before a property is generated, il.RewriteApplies lifts
+// each expression that references outputs into a call to the `__apply` intrinsic whose arguments are the referenced
+// outputs, and replaces those references with calls to the `__applyArg` intrinsic. genApply then generates these calls
+// as `output.apply(x => ...)` or `pulumi.all([...]).apply(([a, b, ...]) => ...)`. Expressions that do not reference
+// any outputs are generated as-is.
 
 // GenArithmetic generates code for the given arithmetic expression.
 func (g *generator) GenArithmetic(w io.Writer, n *il.BoundArithmetic) {
@@ -62,6 +69,13 @@ func (g *generator) GenArithmetic(w io.Writer, n *il.BoundArithmetic) {
 	case ast.ArithmeticOpGreaterThanOrEqual:
 		op = ">="
 	}
+	if len(n.Exprs) == 1 {
+		if n.Op == ast.ArithmeticOpEqual {
+			op = "!"
+		}
+		g.Fgenf(w, "(%s%v)", op, n.Exprs[0])
+		return
+	}
 	op = fmt.Sprintf(" %s ", op)
 
 	g.Fgen(w, "(")
@@ -78,6 +92,10 @@ func (g *generator) GenArithmetic(w io.Writer, n *il.BoundArithmetic) {
 func (g *generator) genApplyOutput(w io.Writer, n *il.BoundVariableAccess) {
 	if rv, ok := n.TFVar.(*config.ResourceVariable); ok && rv.Multi && rv.Index == -1 {
 		g.Fgenf(w, "pulumi.all(%v)", n)
+	} else if _, ok := n.ILNode.(*il.LocalNode); ok && n.Type().IsList() {
+		// A list-typed local may be either an output or a list of outputs (e.g. if its value is a splat), so we wrap
+		// it in a call to `pulumi.output` in order to resolve it in either case.
+		g.Fgenf(w, "pulumi.output(%v)", n)
 	} else {
 		g.Fgen(w, n)
 	}
@@ -93,10 +111,16 @@ func (g *generator) genApply(w io.Writer, n *il.BoundCall) {
 	g.applyArgs, g.applyArgNames = applyArgs, g.assignApplyArgNames(applyArgs, then)
 	defer func() { g.applyArgs = nil }()
 
+	// An arrow function whose body begins with a brace is parsed as a block, so object literals must be parenthesized.
+	thenFormat := "%v"
+	if isObjectLiteral(then) {
+		thenFormat = "(%v)"
+	}
+
 	if len(g.applyArgs) == 1 {
 		// If we only have a single output, just generate a normal `.apply`.
 		g.genApplyOutput(w, g.applyArgs[0])
-		g.Fgenf(w, ".apply(%s => %v)", g.applyArgNames[0], then)
+		g.Fgenf(w, ".apply(%s => "+thenFormat+")", g.applyArgNames[0], then)
 	} else {
 		// Otherwise, generate a call to `pulumi.all([]).apply()`.
 		g.Fgen(w, "pulumi.all([")
@@ -113,10 +137,16 @@ func (g *generator) genApply(w io.Writer, n *il.BoundCall) {
 			}
 			g.Fgenf(w, "%s", g.applyArgNames[i])
 		}
-		g.Fgen(w, "]) => ", then, ")")
+		g.Fgenf(w, "]) => "+thenFormat+")", then)
 	}
 }
 
+// isObjectLiteral returns true if the given expression is generated as an object literal.
+func isObjectLiteral(n il.BoundExpr) bool {
+	call, ok := n.(*il.BoundCall)
+	return ok && (call.Func == "map" || call.Func == "merge")
+}
+
 // getNestedPropertyAccessElementInfo returns the schema information for the first element of the nested property
 // access expression and the list of elements accessed in the expression. This information can then be used to
 // examine the type and name of each property accessed by the expression.
@@ -130,22 +160,80 @@ func (g *generator) getNestedPropertyAccessElementInfo(v *il.BoundVariableAccess
 	return sch, elements
 }
 
+// isIndex returns true if the given property path element is a list index.
+func isIndex(element string) bool {
+	_, err := strconv.ParseUint(element, 10, 0)
+	return err == nil
+}
+
 // genNestedPropertyAccess generates a property access expression for a nested property of a resource or data source.
 func (g *generator) genNestedPropertyAccess(w io.Writer, v *il.BoundVariableAccess) {
 	_, ok := v.TFVar.(*config.ResourceVariable)
 	contract.Assert(ok)
 
 	sch, elements := g.getNestedPropertyAccessElementInfo(v)
+	g.genPropertyPath(w, sch, elements)
+}
+
+// countSplats returns the number of splats ("*") in the given property path.
+func countSplats(elements []string) int {
+	count := 0
 	for _, e := range elements {
+		if e == "*" {
+			count++
+		}
+	}
+	return count
+}
+
+// genFlattenedPropertyPath generates a property access expression for the given path rooted at the given base
+// expression. If the path contains more than one splat, each splat but the last produces a list of lists, so the
+// result of the access is flattened.
+func (g *generator) genFlattenedPropertyPath(w io.Writer, base string, sch il.Schemas, elements []string) {
+	flatten := countSplats(elements) > 1
+	if flatten {
+		g.Fgen(w, "(<any[]>[]).concat(...")
+	}
+	g.Fgen(w, base)
+	g.genPropertyPath(w, sch, elements)
+	if flatten {
+		g.Fgen(w, ")")
+	}
+}
+
+// genPropertyPath generates the property accesses for the given path, which is relative to a property with the given
+// schemas. A splat ("*") in the path maps the remainder of the path over the elements of the list it follows.
+func (g *generator) genPropertyPath(w io.Writer, sch il.Schemas, elements []string) {
+	for i, e := range elements {
+		if e == "*" {
+			t := g.pushTemporary("v")
+			g.Fgenf(w, ".map(%s => ", t)
+			g.genFlattenedPropertyPath(w, t, sch.ElemSchemas(), elements[i+1:])
+			g.Fgen(w, ")")
+			g.popTemporary()
+			return
+		}
+
 		isListElement := sch.Type().IsList()
 		projectListElement := isListElement && tfbridge.IsMaxItemsOne(sch.TF, sch.Pulumi)
+		isMapElement := sch.Type() == il.TypeMap
 
 		sch = sch.PropertySchemas(e)
-		if isListElement {
+		if isMapElement {
+			// Map keys are not renamed.
+			if isLegalIdentifier(e) {
+				g.Fgenf(w, ".%s", e)
+			} else {
+				g.Fgenf(w, "[%s]", stringLiteral(e))
+			}
+		} else if isListElement {
 			// If we're projecting the list element, just skip this path element entirely.
 			if !projectListElement {
 				g.Fgenf(w, "[%s]", e)
 			}
+		} else if isIndex(e) {
+			// Without schema information, a numeric path element is assumed to index into a list.
+			g.Fgenf(w, "[%s]", e)
 		} else {
 			g.Fgenf(w, ".%s", tfbridge.TerraformToPulumiName(e, sch.TF, nil, false))
 			if sch.TF != nil && sch.TF.Optional {
@@ -162,27 +250,48 @@ func (g *generator) genApplyArg(w io.Writer, index int) {
 	// Extract the variable reference.
 	v := g.applyArgs[index]
 
-	// Generate a reference to the parameter.
-	g.Fgen(w, g.applyArgNames[index])
-
-	// Generate any nested path.
-	if rv, ok := v.TFVar.(*config.ResourceVariable); ok {
-		// Handle splats
-		isSplat := rv.Multi && rv.Index == -1
-		if isSplat {
-			g.Fgen(w, ".map(v => v")
-		}
+	rv, ok := v.TFVar.(*config.ResourceVariable)
+	if !ok {
+		// Generate a reference to the parameter.
+		g.Fgen(w, g.applyArgNames[index])
+		return
+	}
 
-		g.genNestedPropertyAccess(w, v)
+	// Generate a reference to the parameter and any nested path. If the access is a splat, the nested path is mapped
+	// over the resolved list. If there is no nested path, the resolved list is already the list of values. If the
+	// nested path itself contains splats, each element of the resolved list produces a list, and the result is
+	// flattened.
+	sch, elements := g.getNestedPropertyAccessElementInfo(v)
+	if !rv.Multi || rv.Index != -1 || len(elements) == 0 {
+		g.genFlattenedPropertyPath(w, g.applyArgNames[index], sch, elements)
+		return
+	}
 
-		if isSplat {
-			g.Fgen(w, ")")
-		}
+	flatten := countSplats(elements) > 0
+	if flatten {
+		g.Fgen(w, "(<any[]>[]).concat(...")
+	}
+	t := g.pushTemporary("v")
+	g.Fgenf(w, "%s.map(%s => ", g.applyArgNames[index], t)
+	g.genFlattenedPropertyPath(w, t, sch, elements)
+	g.Fgen(w, ")")
+	g.popTemporary()
+	if flatten {
+		g.Fgen(w, ")")
 	}
 }
 
 // genCoercion generates code for a single call to the __coerce intrinsic that converts an expression between types.
 func (g *generator) genCoercion(w io.Writer, n il.BoundExpr, toType il.Type) {
+	// Output-typed numbers and booleans are converted to strings lazily. These coercions are produced by
+	// lowerProxyApplies.
+	if typ := n.Type(); typ.IsOutput() && !typ.IsList() && toType == il.TypeString {
+		if elem := typ.ElementType(); elem == il.TypeNumber || elem == il.TypeBool {
+			g.Fgenf(w, "%v.apply(String)", n)
+			return
+		}
+	}
+
 	switch n.Type() {
 	case il.TypeBool:
 		if toType == il.TypeString {
@@ -218,8 +327,28 @@ func (g *generator) genCoercion(w io.Writer, n il.BoundExpr, toType il.Type) {
 	g.Fgen(w, n)
 }
 
-// GenCall generates code for a call expression.
+// genCallArgs generates the comma-separated arguments to a call expression. If the call expands its final argument,
+// that argument is spread into the argument list.
+func (g *generator) genCallArgs(w io.Writer, n *il.BoundCall) {
+	for i, a := range n.Args {
+		if i > 0 {
+			g.Fgen(w, ", ")
+		}
+		if n.ExpandFinal && i == len(n.Args)-1 {
+			g.Fgen(w, "...")
+		}
+		g.Fgen(w, a)
+	}
+}
+
+// GenCall generates code for a call expression. Calls to functions that are present in the function registry are
+// generated by their registered generators.
 func (g *generator) GenCall(w io.Writer, n *il.BoundCall) {
+	if f, ok := functions[n.Func]; ok {
+		f.generate(g, w, n)
+		return
+	}
+
 	switch n.Func {
 	case il.IntrinsicApply:
 		g.genApply(w, n)
@@ -229,9 +358,13 @@ func (g *generator) GenCall(w io.Writer, n *il.BoundCall) {
 		g.Fgenf(w, "new pulumi.asset.FileArchive(%v)", il.ParseArchiveCall(n))
 	case il.IntrinsicAsset:
 		g.Fgenf(w, "new pulumi.asset.FileAsset(%v)", il.ParseAssetCall(n))
+	case il.IntrinsicStringAsset:
+		g.Fgenf(w, "new pulumi.asset.StringAsset(%v)", il.ParseStringAssetCall(n))
 	case il.IntrinsicCoerce:
 		value, toType := il.ParseCoerceCall(n)
 		g.genCoercion(w, value, toType)
+	case il.IntrinsicGetProject:
+		g.Fgenf(w, "pulumi.getProject()")
 	case il.IntrinsicGetStack:
 		g.Fgenf(w, "pulumi.getStack()")
 	case intrinsicDataSource:
@@ -248,68 +381,47 @@ func (g *generator) GenCall(w io.Writer, n *il.BoundCall) {
 		fmt.Fprint(w, "pulumi.interpolate`")
 		for _, s := range n.Args {
 			if lit, ok := s.(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
-				fmt.Fprint(w, lit.Value.(string))
+				fmt.Fprint(w, escapeString(lit.Value.(string), '`'))
 			} else {
 				g.Fgenf(w, "${%v}", s)
 			}
 		}
 		fmt.Fprint(w, "`")
+	case "abs":
+		g.Fgenf(w, "Math.abs(%v)", n.Args[0])
 	case "base64decode":
-		g.Fgenf(w, "Buffer.from(%v, \"base64\").toString()", n.Args[0])
+		// Buffer is a global in Node, so no import is required. Its typings are provided by @types/node, which is a
+		// dependency of every Pulumi TypeScript project.
+		g.Fgenf(w, "Buffer.from(%v, \"base64\").toString(\"utf-8\")", n.Args[0])
 	case "base64encode":
 		g.Fgenf(w, "Buffer.from(%v).toString(\"base64\")", n.Args[0])
-	case "chomp":
-		g.Fgenf(w, "%v.replace(/(\\n|\\r\\n)*$/, \"\")", n.Args[0])
-	case "coalesce":
-		g.Fgen(w, "[")
-		for i, v := range n.Args {
-			if i > 0 {
-				g.Fgen(w, ", ")
-			}
-			g.Fgen(w, v)
-		}
-		g.Fgen(w, "].find((v: any) => v !== undefined && v !== \"\")")
-	case "coalescelist":
-		g.Fgen(w, "[")
-		for i, v := range n.Args {
-			if i > 0 {
-				g.Fgen(w, ", ")
-			}
-			g.Fgen(w, v)
-		}
-		g.Fgen(w, "].find((v: any) => v !== undefined && (<any[]>v).length > 0)")
+	case "ceil":
+		g.Fgenf(w, "Math.ceil(%v)", n.Args[0])
 	case "compact":
 		g.Fgenf(w, "%v.filter((v: any) => <string>v !== \"\")", n.Args[0])
-	case "concat":
-		g.Fgenf(w, "%v.concat(", n.Args[0])
-		for i, arg := range n.Args[1:] {
-			if i > 0 {
-				g.Fgen(w, ", ")
-			}
-			g.Fgenf(w, "%v", arg)
+	case "floor":
+		g.Fgenf(w, "Math.floor(%v)", n.Args[0])
+	case "indent":
+		g.Fgenf(w,
+			"((str, indent) => str.split(\"\\n\").map((l, i) => i == 0 ? l : indent + l).join(\"\"))(%v, \" \".repeat(%v))",
+			n.Args[1], n.Args[0])
+	case "join":
+		// HIL's join accepts any number of lists, which are concatenated before they are joined.
+		if len(n.Args) == 2 && !n.ExpandFinal {
+			g.Fgenf(w, "%v.join(%v)", n.Args[1], n.Args[0])
+			return
 		}
-		g.Fgen(w, ")")
-	case "element":
-		g.Fgenf(w, "%v[%v]", n.Args[0], n.Args[1])
-	case "file":
-		g.Fgenf(w, "fs.readFileSync(%v, \"utf-8\")", n.Args[0])
-	case "format":
-		g.Fgen(w, "sprintf.sprintf(")
-		for i, a := range n.Args {
+		g.Fgen(w, "(<any[]>[]).concat(")
+		for i, a := range n.Args[1:] {
 			if i > 0 {
 				g.Fgen(w, ", ")
 			}
+			if n.ExpandFinal && i == len(n.Args)-2 {
+				g.Fgen(w, "...")
+			}
 			g.Fgen(w, a)
 		}
-		g.Fgen(w, ")")
-	case "indent":
-		g.Fgenf(w,
-			"((str, indent) => str.split(\"\\n\").map((l, i) => i == 0 ? l : indent + l).join(\"\"))(%v, \" \".repeat(%v))",
-			n.Args[1], n.Args[0])
-	case "join":
-		g.Fgenf(w, "%v.join(%v)", n.Args[1], n.Args[0])
-	case "length":
-		g.Fgenf(w, "%v.length", n.Args[0])
+		g.Fgenf(w, ").join(%v)", n.Args[0])
 	case "list":
 		g.Fgen(w, "[")
 		for i, e := range n.Args {
@@ -319,17 +431,6 @@ func (g *generator) GenCall(w io.Writer, n *il.BoundCall) {
 			g.Fgen(w, e)
 		}
 		g.Fgen(w, "]")
-	case "lookup":
-		hasDefault := len(n.Args) == 3
-		if hasDefault {
-			g.Fgen(w, "(")
-		}
-		g.Fgenf(w, "(<any>%v)[%v]", n.Args[0], n.Args[1])
-		if hasDefault {
-			g.Fgenf(w, " || %v)", n.Args[2])
-		}
-	case "lower":
-		g.Fgenf(w, "%v.toLowerCase()", n.Args[0])
 	case "map":
 		contract.Assert(len(n.Args)%2 == 0)
 		g.Fgen(w, "{")
@@ -345,35 +446,16 @@ func (g *generator) GenCall(w io.Writer, n *il.BoundCall) {
 			g.Fgenf(w, ": %v", n.Args[i+1])
 		}
 		g.Fgen(w, "}")
-	case "merge":
-		g.Fgenf(w, "Object.assign(%v", n.Args[0])
-		for i, arg := range n.Args[1:] {
-			if i > 0 {
-				g.Fgen(w, ", ")
-			}
-			g.Fgenf(w, "%v", arg)
-		}
-		g.Fgen(w, ")")
-	case "min":
-		g.Fgenf(w, "%v.reduce((min, v) => !min ? v : Math.min(min, v))", n.Args[0])
-	case "replace":
-		pat := (interface{})(n.Args[1])
-		if lit, ok := pat.(*il.BoundLiteral); ok && lit.Type() == il.TypeString {
-			patStr := lit.Value.(string)
-			if len(patStr) > 1 && patStr[0] == '/' && patStr[len(patStr)-1] == '/' {
-				pat = patStr
-			}
+	case "max", "min":
+		if len(n.Args) == 1 && !n.ExpandFinal && n.Args[0].Type().IsList() {
+			g.Fgenf(w, "Math.%s(...%v)", n.Func, n.Args[0])
+		} else {
+			g.Fgenf(w, "Math.%s(", n.Func)
+			g.genCallArgs(w, n)
+			g.Fgen(w, ")")
 		}
-		g.Fgenf(w, "%v.replace(%v, %v)", n.Args[0], pat, n.Args[2])
 	case "signum":
 		g.Fgenf(w, "Math.sign(%v)", n.Args[0])
-	case "split":
-		g.Fgenf(w, "%v.split(%v)", n.Args[1], n.Args[0])
-	case "substr":
-		g.Fgenf(w, "((str, s, l) => str.slice(s, l === -1 ? s.length : s + l))(%v, %v, %v)", n.Args[0], n.Args[1], n.Args[2])
-	case "zipmap":
-		g.Fgenf(w, "((keys, values) => Object.assign.apply({}, keys.map((k: any, i: number) => ({[k]: values[i]}))))(%v, %v)",
-			n.Args[0], n.Args[1])
 	default:
 		g.Fgenf(w, "(() => { throw \"NYI: call to %v\"; })()", n.Func)
 	}
@@ -389,45 +471,172 @@ func (g *generator) GenIndex(w io.Writer, n *il.BoundIndex) {
 	g.Fgenf(w, "%v[%v]", n.TargetExpr, n.KeyExpr)
 }
 
-func (g *generator) genStringLiteral(w io.Writer, v string) {
+// escapeString escapes the given string for inclusion in a JavaScript string literal delimited by the given quote
+// character, which must be a double quote, a single quote, or a backquote.
+//
+// For string literals, quotes, backslashes, and line terminators will be escaped in conformance with ECMA-262 11.8.4
+// ("String Literals"). For template literals, "${", backquotes, backslashes, and carriage returns will be escaped in
+// conformance with ECMA-262 11.8.6 ("Template Literal Lexical Components"); all other line terminators are preserved.
+// In both cases, control characters other than tabs and line feeds are escaped as Unicode escape sequences, and all
+// other characters--including non-ASCII characters--are preserved.
+func escapeString(v string, quote rune) string {
+	contract.Assert(quote == '"' || quote == '\'' || quote == '`')
+
 	builder := strings.Builder{}
-	newlines := strings.Count(v, "\n")
-	if newlines == 0 || newlines == 1 && (v[0] == '\n' || v[len(v)-1] == '\n') {
-		// This string either does not contain newlines or contains a single leading or trailing newline, so we'll
-		// generate a normal string literal. Quotes, backslashes, and newlines will be escaped in conformance with
-		// ECMA-262 11.8.4 ("String Literals").
-		builder.WriteRune('"')
-		for _, c := range v {
-			if c == '\n' {
-				builder.WriteString(`\n`)
-			} else {
-				if c == '"' || c == '\\' {
-					builder.WriteRune('\\')
-				}
-				builder.WriteRune(c)
+	runes := []rune(v)
+	for i, c := range runes {
+		switch {
+		case c == quote || c == '\\':
+			builder.WriteRune('\\')
+		case c == '$' && quote == '`':
+			if i < len(runes)-1 && runes[i+1] == '{' {
+				builder.WriteRune('\\')
 			}
+		case c == '\r':
+			builder.WriteString(`\r`)
+			continue
+		case c == '\n' && quote != '`':
+			builder.WriteString(`\n`)
+			continue
+		case (c == '\u2028' || c == '\u2029') && quote != '`':
+			fmt.Fprintf(&builder, `\u%04x`, c)
+			continue
+		case c < ' ' && c != '\t' && c != '\n' || c == '\u007f':
+			fmt.Fprintf(&builder, `\u%04x`, c)
+			continue
 		}
-		builder.WriteRune('"')
-	} else {
-		// This string does contain newlines, so we'll generate a template string literal. "${", backquotes, and
-		// backslashes will be escaped in conformance with ECMA-262 11.8.6 ("Template Literal Lexical Components").
-		runes := []rune(v)
-		builder.WriteRune('`')
-		for i, c := range runes {
-			switch c {
-			case '$':
-				if i < len(runes)-1 && runes[i+1] == '{' {
-					builder.WriteRune('\\')
-				}
-			case '`', '\\':
-				builder.WriteRune('\\')
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// stringLiteral returns a double-quoted JavaScript string literal with the given value. This is suitable for use as
+// an expression, an object key, or an index.
+func stringLiteral(v string) string {
+	return `"` + escapeString(v, '"') + `"`
+}
+
+// sprintfFormat rewrites a Terraform format string into the equivalent sprintf-js format string. Escaped percent signs
+// are preserved and the flags of each verb are reordered as required by sprintf-js. If the format string cannot be
+// parsed, it is returned as-is.
+func sprintfFormat(format string) string {
+	parts, err := il.ParseFormat(format)
+	if err != nil {
+		return format
+	}
+
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Verb != nil {
+			b.WriteString(p.Verb.String())
+		} else {
+			b.WriteString(strings.Replace(p.Text, "%", "%%", -1))
+		}
+	}
+	return b.String()
+}
+
+// isZeroLiteral returns true if the given expression is the numeric literal zero.
+func isZeroLiteral(n il.BoundExpr) bool {
+	lit, ok := n.(*il.BoundLiteral)
+	return ok && lit.ExprType == il.TypeNumber && lit.Value.(float64) == 0
+}
+
+// inlineFormatParts returns the parsed format string of the given call to `format` or `formatlist` if the call can be
+// generated as a template literal rather than a call to sprintf-js. This is the case if the format string is a literal,
+// the call does not expand its final argument, and each verb is a plain %s, %d, or %v verb with a primitive-typed
+// argument. The list arguments to `formatlist` are formatted element by element, so their element types are used.
+func inlineFormatParts(n *il.BoundCall) ([]il.FormatPart, bool) {
+	lit, ok := n.Args[0].(*il.BoundLiteral)
+	if !ok || lit.ExprType != il.TypeString || n.ExpandFinal {
+		return nil, false
+	}
+	parts, err := il.ParseFormat(lit.Value.(string))
+	if err != nil {
+		return nil, false
+	}
+
+	args := n.Args[1:]
+	for _, p := range parts {
+		if p.Verb == nil {
+			continue
+		}
+		if len(args) == 0 {
+			return nil, false
+		}
+		arg, v := args[0], p.Verb
+		args = args[1:]
+
+		if v.Plus || v.Minus || v.Zero || v.Width != -1 || v.Precision != -1 {
+			return nil, false
+		}
+		switch v.Verb {
+		case 's', 'd', 'v':
+			typ := arg.Type()
+			if n.Func == "formatlist" {
+				typ = typ.ElementType()
 			}
-			builder.WriteRune(c)
+			if typ.IsList() || typ.ElementType() == il.TypeMap {
+				return nil, false
+			}
+		default:
+			return nil, false
 		}
-		builder.WriteRune('`')
+	}
+	return parts, len(args) == 0
+}
+
+// genFormat generates code that formats the given arguments using the format string of the given call to `format` or
+// `formatlist`. Each argument is either a bound expression or a snippet of generated code.
+func (g *generator) genFormat(w io.Writer, n *il.BoundCall, args []interface{}) {
+	if parts, ok := inlineFormatParts(n); ok {
+		g.genInlineFormat(w, parts, args)
+		return
 	}
 
-	g.Fgenf(w, "%s", builder.String())
+	g.Fgen(w, "sprintf.sprintf(")
+	if lit, ok := n.Args[0].(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
+		// Literal format strings are rewritten into the form expected by sprintf-js, which requires that flags
+		// appear in a particular order.
+		g.Fgen(w, stringLiteral(sprintfFormat(lit.Value.(string))))
+	} else {
+		g.Fgen(w, n.Args[0])
+	}
+	for i, a := range args {
+		g.Fgen(w, ", ")
+		if n.ExpandFinal && i == len(args)-1 {
+			g.Fgen(w, "...")
+		}
+		g.Fgen(w, a)
+	}
+	g.Fgen(w, ")")
+}
+
+// genInlineFormat generates a template literal for a call to `format` or `formatlist` whose format string was parsed
+// into the given parts. Each verb is replaced by the corresponding argument.
+func (g *generator) genInlineFormat(w io.Writer, parts []il.FormatPart, args []interface{}) {
+	g.Fgen(w, "`")
+	for _, p := range parts {
+		if p.Verb == nil {
+			g.Fgen(w, escapeString(p.Text, '`'))
+			continue
+		}
+		g.Fgenf(w, "${%v}", args[0])
+		args = args[1:]
+	}
+	g.Fgen(w, "`")
+}
+
+// genStringLiteral generates a string literal with the given value. If the value contains multiple newlines or a
+// newline that is neither leading nor trailing, a template literal is generated. Otherwise, a double-quoted string
+// literal is generated.
+func (g *generator) genStringLiteral(w io.Writer, v string) {
+	newlines := strings.Count(v, "\n")
+	if newlines == 0 || newlines == 1 && (v[0] == '\n' || v[len(v)-1] == '\n') {
+		g.Fgenf(w, "%s", stringLiteral(v))
+	} else {
+		g.Fgenf(w, "`%s`", escapeString(v, '`'))
+	}
 }
 
 // GenLiteral generates code for a single literal expression
@@ -454,7 +663,7 @@ func (g *generator) GenOutput(w io.Writer, n *il.BoundOutput) {
 	g.Fgen(w, "`")
 	for _, s := range n.Exprs {
 		if lit, ok := s.(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
-			g.Fgen(w, lit.Value.(string))
+			g.Fgen(w, escapeString(lit.Value.(string), '`'))
 		} else {
 			g.Fgenf(w, "${%v}", s)
 		}
@@ -467,12 +676,30 @@ func (g *generator) GenPropertyValue(w io.Writer, n *il.BoundPropertyValue) {
 	g.Gen(w, n.Value)
 }
 
+// genSplatCallback generates the start of the callback passed to `.map` when generating a splat access to the
+// instances of a counted resource. If the type of the resource's instances is known, the callback's parameter is
+// annotated with that type. The callback's parameter remains in scope until the caller calls popTemporary.
+func (g *generator) genSplatCallback(w io.Writer, n *il.BoundVariableAccess) {
+	v := g.pushTemporary("v")
+	if r, ok := n.ILNode.(*il.ResourceNode); ok && r.Provider.Name != "archive" && r.Provider.Name != "http" {
+		if typ, err := g.resourceInstanceType(r); err == nil {
+			g.Fgenf(w, ".map((%s: %s) => %s", v, typ, v)
+			return
+		}
+	}
+	g.Fgenf(w, ".map(%s => %s", v, v)
+}
+
 // GenVariableAccess generates code for a single variable access expression.
 func (g *generator) GenVariableAccess(w io.Writer, n *il.BoundVariableAccess) {
 	switch v := n.TFVar.(type) {
 	case *config.CountVariable, *config.LocalVariable, *config.UserVariable:
 		g.Fgen(w, g.variableName(n))
 
+	case *config.SelfVariable:
+		// Self-references are always bound as errors, and GenError does not generate their values.
+		contract.Failf("unexpected self-reference %v", v.FullKey())
+
 	case *config.ModuleVariable:
 		g.Fgen(w, g.variableName(n))
 		for _, e := range strings.Split(v.Field, ".") {
@@ -518,7 +745,7 @@ func (g *generator) GenVariableAccess(w io.Writer, n *il.BoundVariableAccess) {
 			// Handle splats
 			isSplat := v.Multi && v.Index == -1
 			if isSplat {
-				g.Fgen(w, ".map(v => v")
+				g.genSplatCallback(w, n)
 			}
 			g.Fgenf(w, ".%s", tfbridge.TerraformToPulumiName(element, elementSch.TF, nil, false))
 			if !g.inApplyCall {
@@ -526,18 +753,20 @@ func (g *generator) GenVariableAccess(w io.Writer, n *il.BoundVariableAccess) {
 			}
 			if isSplat {
 				g.Fgen(w, ")")
+				g.popTemporary()
 			}
 		} else if !g.inApplyCall {
 			// Handle splats
 			isSplat := v.Multi && v.Index == -1
 			if isSplat {
-				g.Fgen(w, ".map(v => v")
+				g.genSplatCallback(w, n)
 			}
 			if !g.inApplyCall {
 				g.genNestedPropertyAccess(w, n)
 			}
 			if isSplat {
 				g.Fgen(w, ")")
+				g.popTemporary()
 			}
 		}
 	default:
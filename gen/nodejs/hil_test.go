@@ -2,9 +2,24 @@ package nodejs
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/tf2pulumi/gen"
+	"github.com/pulumi/tf2pulumi/il"
+	"github.com/pulumi/tf2pulumi/internal/config/module"
+	"github.com/pulumi/tf2pulumi/internal/testutil"
 )
 
 func TestStringLiteral(t *testing.T) {
@@ -25,6 +40,11 @@ func TestStringLiteral(t *testing.T) {
 		{"foo\nbar`", "`foo\nbar\\``"},
 		{"foo\nbar\\", "`foo\nbar\\\\`"},
 		{"foo\nbar${", "`foo\nbar\\${`"},
+		{`foo'bar"baz`, `"foo'bar\"baz"`},
+		{"foo`bar${baz}", "\"foo`bar${baz}\""},
+		{"foo\rbar", `"foo\rbar"`},
+		{"foo\u2028bar", `"foo\u2028bar"`},
+		{"foo\nbar'\"\r", "`foo\nbar'\"\\r`"},
 	}
 
 	g := &generator{}
@@ -34,3 +54,1511 @@ func TestStringLiteral(t *testing.T) {
 		assert.Equal(t, c.expected, b.String())
 	}
 }
+
+func TestEscapeString(t *testing.T) {
+	type escapeCase struct {
+		input    string
+		quote    rune
+		expected string
+	}
+
+	cases := []escapeCase{
+		{`it's "quoted"`, '"', `it's \"quoted\"`},
+		{`it's "quoted"`, '\'', `it\'s "quoted"`},
+		{`it's "quoted"`, '`', `it's "quoted"`},
+		{"`${x}` and $y", '"', "`${x}` and $y"},
+		{"`${x}` and $y", '`', "\\`\\${x}\\` and $y"},
+		{"a\\b\nc", '"', `a\\b\nc`},
+		{"a\\b\nc", '`', "a\\\\b\nc"},
+		{"a\x00b\bc\td\x7f", '"', `a\u0000b\u0008c	d\u007f`},
+		{"a\x00b\bc\td\x7f", '`', `a\u0000b\u0008c	d\u007f`},
+		{"héllo, 世界", '"', "héllo, 世界"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, escapeString(c.input, c.quote))
+	}
+}
+
+func TestStringContexts(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  user_data = "echo '${aws_vpc.main.id}' \"` + "`" + `$${HOME}` + "`" + `\""
+
+  tags = {
+    "it's \"quoted\"" = "${aws_vpc.main.tags["it's \"quoted\""]}"
+  }
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "userData: pulumi.interpolate`echo '${main.id}' \"\\`\\${HOME}\\`\"`,")
+	assert.Contains(t, code, `"it's \"quoted\"": main.tags.apply(tags => tags["it's \"quoted\""]),`)
+}
+
+func TestStringEscaping(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  quoted    = "` + "`" + `$${x}` + "`" + ` héllo\n"
+  literal   = "` + "`" + `$${x}` + "`" + `\nhéllo\n世界"
+  user_data = "` + "`" + `$${x}` + "`" + `\nhéllo ${aws_vpc.main.id}\n世界"
+}
+`
+	// Backquotes and "${" are escaped in template literals but not in string literals. Newlines are escaped in string
+	// literals but not in template literals. Non-ASCII characters are preserved.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "quoted: \"`${x}` héllo\\n\",")
+	assert.Contains(t, code, "literal: `\\`\\${x}\\`\nhéllo\n世界`,")
+	assert.Contains(t, code, "userData: pulumi.interpolate`\\`\\${x}\\`\nhéllo ${main.id}\n世界`,")
+}
+
+func TestLookupDefault(t *testing.T) {
+	const source = `
+variable "m" {
+  default = {
+    a = "b"
+  }
+}
+
+resource "aws_x" "y" {}
+
+resource "aws_z" "w" {
+  simple = "${lookup(var.m, "k", aws_x.y.id)}"
+  both   = "${lookup(aws_x.y.tags, "k", aws_x.y.id)}"
+  nested = "${lookup(var.m, "k", "${aws_x.y.id}-suffix")}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "simple: x.id.apply(id => lookup(m, \"k\", id)),")
+	assert.Contains(t, code, "both: pulumi.all([x.tags, x.id]).apply(([tags, id]) => lookup(tags, \"k\", id)),")
+	assert.Contains(t, code, "nested: x.id.apply(id => lookup(m, \"k\", `${id}-suffix`)),")
+}
+
+func TestCIDRNetmask(t *testing.T) {
+	const source = `
+variable "prefix" {
+  default = "10.0.0.0/16"
+}
+
+resource "aws_x" "y" {
+  ipv4 = "${cidrnetmask(var.prefix)}"
+  ipv6 = "${cidrnetmask("fd00::/8")}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, utilities, "export function cidrnetmask(prefix: string): string {")
+	assert.Contains(t, code, "ipv4: cidrnetmask(prefix),")
+	assert.Contains(t, code,
+		`throw "tf2pulumi error: main.tf:8:13: cidrnetmask only supports IPv4 prefixes; \"fd00::/8\" is an IPv6 prefix";`)
+}
+
+func TestArithmeticInInterpolation(t *testing.T) {
+	const source = `
+variable "base" {
+  default = 2
+}
+
+variable "offset" {}
+
+resource "aws_x" "y" {}
+
+resource "aws_z" "w" {
+  sum     = "${var.base + var.offset}"
+  mixed   = "n-${var.base + var.offset}-${var.base * 2}"
+  adjacent = "${var.base + 1}${var.base}"
+  output  = "x${aws_x.y.id + 1}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "sum: (base + Number.parseFloat(offset)),")
+	assert.Contains(t, code, "mixed: `n-${(base + Number.parseFloat(offset))}-${(base * 2)}`,")
+	assert.Contains(t, code, "adjacent: `${(base + 1)}${base}`,")
+	assert.Contains(t, code, "output: x.id.apply(id => `x${(Number.parseFloat(id) + 1)}`),")
+}
+
+func TestTypedSplatCallback(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+}
+
+data "aws_subnet" "subnet" {
+  count = 2
+}
+
+resource "aws_x" "y" {
+  ids         = ["${aws_instance.web.*.id}"]
+  joined      = "${join(",", aws_instance.web.*.id)}"
+  cidr_blocks = ["${data.aws_subnet.subnet.*.cidr_block}"]
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "ids: web.map((v: aws.Instance) => v.id),")
+	assert.Contains(t, code, "joined: pulumi.all(web.map((v: aws.Instance) => v.id)).apply(")
+	assert.Contains(t, code, "cidrBlocks: subnet.map((v: pulumi.Output<aws.SubnetResult>) => v.cidrBlock),")
+}
+
+func TestInlineBase64EncodedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer func() {
+		contract.IgnoreError(os.RemoveAll(dir))
+	}()
+
+	fixture := path.Join(dir, "fixture.bin")
+	err = ioutil.WriteFile(fixture, []byte("hello\x00world"), 0600)
+	if err != nil {
+		t.Fatalf("could not create fixture.bin: %v", err)
+	}
+
+	literalSource := fmt.Sprintf(`
+resource "aws_x" "y" {
+  literal = "${base64encode(file(%q))}"
+}
+`, fixture)
+	code := generateSource(t, literalSource)
+	assert.Contains(t, code, `literal: "aGVsbG8Ad29ybGQ=",`)
+	assert.NotContains(t, code, `import * as fs from "fs";`)
+
+	dynamicSource := `
+variable "filename" {}
+
+resource "aws_x" "y" {
+  dynamic = "${base64encode(file(var.filename))}"
+}
+`
+	code = generateSource(t, dynamicSource)
+	assert.Contains(t, code, `dynamic: Buffer.from(fs.readFileSync(filename, "utf-8")).toString("base64"),`)
+	assert.Contains(t, code, `import * as fs from "fs";`)
+
+	// Relative paths are resolved against the root module's directory.
+	const relativeSource = `
+resource "aws_x" "y" {
+  relative = "${base64encode(file("fixture.bin"))}"
+}
+`
+	err = ioutil.WriteFile(path.Join(dir, "main.tf"), []byte(relativeSource), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+	g, err := il.BuildGraph(module.NewTree("main", loadConfig(t, dir)), &il.BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	})
+	if err != nil {
+		t.Fatalf("could not build graph: %v", err)
+	}
+
+	var b bytes.Buffer
+	lang, err := New("main", "1.0.0", false, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{g}, lang)
+	assert.NoError(t, err)
+	code = b.String()
+	assert.Contains(t, code, `relative: "aGVsbG8Ad29ybGQ=",`)
+	assert.NotContains(t, code, `import * as fs from "fs";`)
+}
+
+func TestApplyParameterNames(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {}
+
+resource "aws_vpc" "vpc" {}
+
+resource "aws_x" "y" {
+  distinct  = "${lower(aws_instance.web.private_ip) == aws_vpc.vpc.cidr_block}"
+  colliding = "${lower(aws_instance.web.id) == aws_vpc.vpc.id}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "distinct: pulumi.all([web.privateIp, vpc.cidrBlock]).apply(([privateIp, cidrBlock]) => "+
+		"(privateIp.toLowerCase() === cidrBlock)),")
+	assert.Contains(t, code, "colliding: pulumi.all([web.id, vpc.id]).apply(([webId, vpcId]) => "+
+		"(webId.toLowerCase() === vpcId)),")
+}
+
+func TestRegexall(t *testing.T) {
+	const source = `
+variable "names" {
+  default = "a-1,b-2,c-3"
+}
+
+resource "aws_x" "y" {
+  count_value   = "${length(regexall("[a-z]-[0-9]", var.names))}"
+  first_value   = "${element(regexall("[a-z]-[0-9]", var.names), 0)}"
+  joined_values = "${join(",", regexall("[a-z]-[0-9]", var.names))}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, utilities, "export function regexall(pattern: string, str: string): any[] {")
+	assert.Contains(t, code, `countValue: regexall("[a-z]-[0-9]", names).length,`)
+	assert.Contains(t, code, `firstValue: regexall("[a-z]-[0-9]", names)[0],`)
+	assert.Contains(t, code, `joinedValues: regexall("[a-z]-[0-9]", names).join(","),`)
+}
+
+func TestTimecmp(t *testing.T) {
+	const source = `
+variable "deadline" {
+  default = "2017-11-22T01:00:00Z"
+}
+
+resource "aws_x" "y" {
+  earlier = "${timecmp("2017-11-22T00:00:00Z", var.deadline)}"
+  equal   = "${timecmp("2017-11-22T02:00:00+01:00", var.deadline)}"
+  later   = "${timecmp("2017-11-22T02:00:00Z", var.deadline)}"
+}
+
+resource "aws_x" "invalid" {
+  value = "${timecmp("2017-11-22", var.deadline)}"
+}
+`
+	g := buildSource(t, source)
+	earlier := g.Resources["aws_x.y"].Properties.Elements["earlier"].(*il.BoundCall)
+	assert.Equal(t, il.TypeNumber, earlier.Type())
+
+	// Invalid literal timestamps are reported.
+	invalid := g.Resources["aws_x.invalid"].Properties.Elements["value"].(*il.BoundError)
+	assert.EqualError(t, invalid.Error, `main.tf:13:14: not a valid RFC3339 timestamp: "2017-11-22"`)
+
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, utilities, "export function timecmp(a: string, b: string): number {")
+	assert.Contains(t, utilities, "return Math.sign(parse(a) - parse(b));")
+	assert.Contains(t, code, `earlier: timecmp("2017-11-22T00:00:00Z", deadline),`)
+	assert.Contains(t, code, `equal: timecmp("2017-11-22T02:00:00+01:00", deadline),`)
+	assert.Contains(t, code, `later: timecmp("2017-11-22T02:00:00Z", deadline),`)
+}
+
+func TestExpandFinal(t *testing.T) {
+	const source = `
+variable "sizes" {
+  type = "list"
+}
+
+resource "aws_x" "x" {}
+
+data "aws_x" "d" {}
+
+resource "aws_x" "y" {
+  largest        = "${max(data.aws_x.d.sizes...)}"
+  largest_output = "${max(aws_x.x.sizes...)}"
+  smallest       = "${min(1, data.aws_x.d.sizes...)}"
+  formatted      = "${format("%s-%s", data.aws_x.d.sizes...)}"
+  largest_list   = "${max(var.sizes)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "largest: x.apply(x => Math.max(...x.sizes)),")
+	assert.Contains(t, code, "largestList: Math.max(...sizes),")
+	assert.Contains(t, code, "largestOutput: awsX.sizes.apply(sizes => Math.max(...sizes)),")
+	assert.Contains(t, code, "smallest: x.apply(x => Math.min(1, ...x.sizes)),")
+	assert.Contains(t, code, `formatted: x.apply(x => sprintf.sprintf("%s-%s", ...x.sizes)),`)
+}
+
+func TestExplicitInstanceIndex(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+}
+
+resource "aws_x" "y" {
+  first   = "${aws_instance.web.0.id}"
+  second  = "${aws_instance.web.1.private_ip}"
+  joined  = "${aws_instance.web.0.id}-${aws_instance.web.1.id}"
+  nested  = "${aws_instance.web.1.root_block_device.0.volume_size}"
+  largest = "${max(aws_instance.web.1.sizes...)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "first: web[0].id,")
+	assert.Contains(t, code, "second: web[1].privateIp,")
+	assert.Contains(t, code, "joined: pulumi.interpolate`${web[0].id}-${web[1].id}`,")
+	assert.Contains(t, code, "nested: web[1].rootBlockDevice[0].volumeSize,")
+	assert.Contains(t, code, "largest: web[1].sizes.apply(sizes => Math.max(...sizes)),")
+}
+
+func TestSplatAndScalarApply(t *testing.T) {
+	const source = `
+resource "aws_instance" "x" {
+  count = 2
+}
+
+resource "aws_vpc" "z" {}
+
+resource "aws_s3_bucket" "b" {
+  joined = "${join(",", aws_instance.x.*.id)}-${aws_vpc.z.id}"
+  nested = "${join(",", aws_instance.x.*.root_block_device.0.volume_size)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "joined: pulumi.all([pulumi.all(instance.map((v: aws.Instance) => v.id)), vpc.id])"+
+		".apply(([instanceId, vpcId]) => `${instanceId.join(\",\")}-${vpcId}`),")
+	assert.Contains(t, code, "nested: pulumi.all(instance.map((v: aws.Instance) => v.rootBlockDevice))"+
+		".apply(rootBlockDevice => rootBlockDevice.map(v => v[0].volumeSize).join(\",\")),")
+}
+
+func TestTemporaryNames(t *testing.T) {
+	const source = `
+variable "v" {}
+
+resource "aws_instance" "x" {
+  count = 2
+}
+
+resource "aws_s3_bucket" "b" {
+  bucket = "${var.v}"
+  joined = "${join(",", aws_instance.x.*.id)}"
+  nested = "${join(",", aws_instance.x.*.root_block_device.0.volume_size)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "joined: pulumi.all(instance.map((v1: aws.Instance) => v1.id))")
+	assert.Contains(t, code, "rootBlockDevice.map(v1 => v1[0].volumeSize)")
+
+	// Nested temporaries must not shadow one another, and names must be reused once they go out of scope.
+	g := &generator{importNames: map[string]bool{"aws": true}}
+	assert.Equal(t, "v", g.pushTemporary("v"))
+	assert.Equal(t, "v1", g.pushTemporary("v"))
+	assert.Equal(t, "v2", g.pushTemporary("v"))
+	g.popTemporary()
+	assert.Equal(t, "v2", g.pushTemporary("v"))
+	g.popTemporary()
+	g.popTemporary()
+	assert.Equal(t, "v1", g.pushTemporary("v"))
+	assert.Equal(t, "aws1", g.pushTemporary("aws"))
+}
+
+func TestOutputIndex(t *testing.T) {
+	const source = `
+resource "aws_instance" "x" {}
+
+resource "aws_s3_bucket" "b" {
+  bucket = "${lookup(aws_instance.x.root_block_device[0], "volume_size")}"
+  acl    = "${lookup(element(aws_instance.x.root_block_device, 0), "volume_size")}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code,
+		"bucket: instance.rootBlockDevice.apply(rootBlockDevice => lookup(rootBlockDevice[0], \"volume_size\")),")
+	assert.Contains(t, code,
+		"acl: instance.rootBlockDevice.apply(rootBlockDevice => lookup(rootBlockDevice[0], \"volume_size\")),")
+}
+
+func TestLookupNullishDefault(t *testing.T) {
+	const source = `
+variable "sizes" {
+  default = {
+    small = 0
+    large = 10
+  }
+}
+
+variable "flags" {
+  default = {
+    enabled = false
+  }
+}
+
+variable "names" {
+  default = {
+    a = "x"
+  }
+}
+
+resource "aws_instance" "x" {
+  cpu_core_count = "${lookup(var.sizes, "small", 1)}"
+  monitoring     = "${lookup(var.flags, "enabled", true)}"
+  ami            = "${lookup(var.names, "a", "y")}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, `cpuCoreCount: (<number>lookup(sizes, "small", 1)),`)
+	assert.Contains(t, code, `monitoring: (<boolean>lookup(flags, "enabled", true)),`)
+	assert.Contains(t, code, `ami: lookup(names, "a", "y"),`)
+
+	// The helper returns the default only if the key is missing, so empty strings are not replaced either. Lookups
+	// without a default fail if the key is missing.
+	assert.Contains(t, utilities, "export function lookup(map: any, key: string | number, ...defaultValue: any[]): any {")
+	assert.Contains(t, utilities, "Object.prototype.hasOwnProperty.call(map, key)")
+	assert.Contains(t, utilities, "throw new Error(`lookup failed to find \"${key}\"`);")
+}
+
+func TestLookupBooleanDefault(t *testing.T) {
+	const source = `
+variable "flags" {
+  type = "map"
+}
+
+variable "settings" {
+  default = {
+    enabled = false
+    name    = "x"
+  }
+}
+
+resource "test_instance" "x" {
+  monitoring = "${lookup(var.flags, "enabled", false)}"
+  public     = "${lookup(var.settings, "enabled", true)}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_instance": {
+			Schema: map[string]*schema.Schema{
+				"monitoring": {Type: schema.TypeBool, Optional: true},
+				"public":     {Type: schema.TypeBool, Optional: true},
+			},
+		},
+	})
+
+	// The boolean default types the result, so it is not coerced, and a present false value is not replaced by the
+	// default.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, `monitoring: (<boolean>lookup(flags, "enabled", false)),`)
+	assert.Contains(t, code, `public: (<boolean>lookup(settings, "enabled", true)),`)
+}
+
+func TestCountIndexAndSplat(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+}
+
+locals {
+  ids = "${aws_instance.web.*.id}"
+}
+
+resource "aws_eip" "ip" {
+  count    = 2
+  instance = "${element(aws_instance.web.*.id, count.index)}"
+  name     = "${element(local.ids, count.index)}-${count.index}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "const ids = web.map((v: aws.Instance) => v.id);")
+	assert.Contains(t, code, "instance: pulumi.all(web.map((v: aws.Instance) => v.id)).apply(id => element(id, i)),")
+	assert.Contains(t, code, "name: pulumi.output(ids).apply(ids => `${element(ids, i)}-${i}`),")
+}
+
+func TestDeduplicatedApplyArgs(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "x" {
+  user_data = "${aws_vpc.main.id == "" ? "none" : aws_vpc.main.id}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `userData: main.id.apply(id => ((id === "") ? "none" : id)),`)
+}
+
+func TestSelfReference(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  user_data = "${base64encode("echo ${self.private_ip} > /tmp/ip")}"
+}
+`
+	// The resource being constructed is not in scope, so the error must not refer to it.
+	code := generateSource(t, source)
+	assert.Contains(t, code, `throw "tf2pulumi error: main.tf:3:39: self-references are not supported: a resource's `+
+		`inputs cannot depend on its own outputs (self.private_ip)";`)
+	assert.NotContains(t, code, "return self")
+	assert.NotContains(t, code, "self.privateIp")
+}
+
+func TestFormatWidthAndPadding(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+
+  tags {
+    Name  = "${format("web-%02d", count.index + 1)}"
+    Owner = "${format("[%-10s] %-+5d 100%%", "ops", count.index)}"
+  }
+}
+
+resource "aws_instance" "invalid" {
+  tags {
+    Name = "${format("%-05d", 1)}"
+  }
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `Name: sprintf.sprintf("web-%02d", (i + 1)),`)
+	assert.Contains(t, code, `Owner: sprintf.sprintf("[%-10s] %+-5d 100%%", "ops", i),`)
+
+	// Unsupported combinations of flags are reported.
+	assert.Contains(t, code, `invalid format string for format: the '-' and '0' flags cannot be combined in format `+
+		`verb \"%-05d\"`)
+}
+
+func TestInlineFormat(t *testing.T) {
+	const source = `
+variable "name" {}
+
+variable "names" {
+  default = ["a", "b"]
+}
+
+resource "aws_instance" "web" {
+  count = 2
+
+  tags {
+    Name    = "${format("%s-%d", var.name, count.index)}"
+    Percent = "${format("100%% of %v", var.name)}"
+    Width   = "${format("%5.2f", 3)}"
+    List    = "${format("%v", var.names)}"
+  }
+}
+
+output "id" {
+  value = "${format("%s/%s", aws_instance.web.0.id, var.name)}"
+}
+`
+	code := generateSource(t, source)
+
+	// Literal format strings with plain verbs are generated as template literals.
+	assert.Contains(t, code, "Name: `${name}-${i}`,")
+	assert.Contains(t, code, "Percent: `100% of ${name}`,")
+	assert.Contains(t, code, "export const id = web[0].id.apply(id => `${id}/${name}`);")
+
+	// Verbs with widths or precisions and verbs applied to lists or maps fall back to sprintf-js.
+	assert.Contains(t, code, `Width: sprintf.sprintf("%5.2f", 3),`)
+	assert.Contains(t, code, `List: sprintf.sprintf("%v", names),`)
+	assert.Contains(t, code, `import sprintf = require("sprintf-js");`)
+}
+
+func TestJoinSplit(t *testing.T) {
+	const source = `
+variable "csv" {
+  default = "a,b"
+}
+
+variable "more" {
+  default = ["c"]
+}
+
+resource "aws_instance" "web" {
+  count = 2
+}
+
+resource "aws_x" "y" {
+  first = "${element(split(",", var.csv), 0)}"
+  all   = "${join(",", split(",", var.csv), var.more)}"
+  ids   = "${join("-", aws_instance.web.*.id)}"
+  parts = "${join("-", split(",", aws_instance.web.0.id))}"
+}
+`
+	// The elements of split lists are strings.
+	g := buildSource(t, source)
+	first := g.Resources["aws_x.y"].Properties.Elements["first"].(*il.BoundCall)
+	assert.Equal(t, il.TypeString.ListOf(), first.Args[0].Type())
+	assert.Equal(t, il.TypeString, first.Type())
+
+	code := generateSource(t, source)
+	assert.Contains(t, code, `first: csv.split(",")[0],`)
+	assert.Contains(t, code, `all: (<any[]>[]).concat(csv.split(","), more).join(","),`)
+	assert.Contains(t, code, `ids: pulumi.all(web.map((v: aws.Instance) => v.id)).apply(id => id.join("-")),`)
+	assert.Contains(t, code, `parts: web[0].id.apply(id => id.split(",").join("-")),`)
+}
+
+func TestCountIndexedListVariable(t *testing.T) {
+	const source = `
+variable "cidrs" {
+  type = "list"
+}
+
+variable "names" {
+  default = ["a", "b"]
+}
+
+resource "aws_subnet" "s" {
+  count = 2
+
+  cidr_block = "${var.cidrs[count.index]}"
+  name       = "${var.names[count.index]}"
+}
+`
+	// The element type of an indexed list variable is the common type of the elements of its default, if any.
+	g := buildSource(t, source)
+	props := g.Resources["aws_subnet.s"].Properties.Elements
+	assert.Equal(t, il.TypeUnknown, props["cidr_block"].(*il.BoundIndex).Type())
+	assert.Equal(t, il.TypeString, props["name"].(*il.BoundIndex).Type())
+
+	code := generateSource(t, source)
+	assert.Contains(t, code, "cidrBlock: cidrs[i],")
+	assert.Contains(t, code, "name: names[i],")
+}
+
+func TestWorkspaceName(t *testing.T) {
+	const source = `
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs-${terraform.workspace}"
+
+  tags {
+    Environment = "${terraform.env}"
+  }
+}
+`
+	// Terraform workspaces correspond to Pulumi stacks.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "bucket: `logs-${pulumi.getStack()}`,")
+	assert.Contains(t, code, "Environment: pulumi.getStack(),")
+}
+
+func TestWorkspaceConditional(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  instance_type = "${terraform.workspace == "prod" ? "m5.large" : "t2.micro"}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `instanceType: ((pulumi.getStack() === "prod") ? "m5.large" : "t2.micro"),`)
+}
+
+func TestElementWraparound(t *testing.T) {
+	const source = `
+variable "zones" {
+  default = ["a", "b"]
+}
+
+resource "aws_subnet" "s" {
+  count = 3
+
+  availability_zone = "${element(var.zones, count.index)}"
+  first_zone        = "${element(var.zones, 0)}"
+}
+`
+	// The element type of the list is preserved.
+	g := buildSource(t, source)
+	zone := g.Resources["aws_subnet.s"].Properties.Elements["availability_zone"].(*il.BoundCall)
+	assert.Equal(t, il.TypeString, zone.Type())
+
+	// Indices wrap around the length of the list. Literal zero indices are generated as plain index expressions.
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, utilities, "export function element(list: any[], index: number): any {")
+	assert.Contains(t, utilities, "return list[Math.floor(index) % list.length];")
+	assert.Contains(t, code, "availabilityZone: element(zones, i),")
+	assert.Contains(t, code, "firstZone: zones[0],")
+}
+
+func TestNestedInterpolate(t *testing.T) {
+	const source = `
+variable "suffix" {}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "x" {
+  name      = "${lower("${aws_vpc.main.id}-${var.suffix}")}"
+  user_data = "${lower("${aws_vpc.main.id}-${aws_vpc.main.arn}")}-data"
+  tags      = ["${aws_vpc.main.id}-tag", "${lower("${aws_vpc.main.id}-tag")}"]
+}
+`
+	// Interpolated strings that are arguments to a function become template literals inside the function's apply.
+	// Only interpolated strings that are not nested inside other expressions are generated using pulumi.interpolate.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "name: main.id.apply(id => `${id}-${suffix}`.toLowerCase()),")
+	assert.Contains(t, code, "userData: pulumi.all([main.id, main.arn]).apply(([id, arn]) => "+
+		"`${`${id}-${arn}`.toLowerCase()}-data`),")
+	assert.Contains(t, code, "pulumi.interpolate`${main.id}-tag`,")
+	assert.Contains(t, code, "main.id.apply(id => `${id}-tag`.toLowerCase()),")
+	assert.NotContains(t, code, "apply(id => pulumi.interpolate")
+}
+
+func TestCountedDataSource(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["a", "b"]
+}
+
+data "aws_ami" "x" {
+  count = "${length(var.names)}"
+  name  = "${var.names[count.index]}"
+}
+
+resource "aws_instance" "web" {
+  count = "${length(var.names)}"
+  ami   = "${data.aws_ami.x.*.id[count.index]}"
+  first = "${data.aws_ami.x.0.id}"
+}
+`
+	// Each data source invocation is an output, so indexing the splat requires an apply.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "const ami: pulumi.Output<aws.AmiResult>[] = [];")
+	assert.Contains(t, code, "ami: pulumi.all(ami).apply(ami => ami.map(v => v.id)[i]),")
+	assert.Contains(t, code, "first: ami[0].id,")
+
+	// Prompt data source invocations are plain values.
+	var b bytes.Buffer
+	lang, err := New("main", "1.0.0", true /*prompt*/, &b)
+	assert.NoError(t, err)
+	err = gen.Generate([]*il.Graph{buildSource(t, source)}, lang)
+	assert.NoError(t, err)
+	code = b.String()
+	assert.Contains(t, code, "const ami: aws.AmiResult[] = [];")
+	assert.Contains(t, code, "ami: ami.map((v: aws.AmiResult) => v.id)[i],")
+	assert.Contains(t, code, "first: ami[0].id,")
+}
+
+func TestRegisterFunction(t *testing.T) {
+	bindString := func(args []il.BoundExpr) (il.Type, error) {
+		return il.TypeString, nil
+	}
+	t.Cleanup(il.RegisterFunction("shout", bindString))
+	t.Cleanup(RegisterFunction("shout", Function{
+		Generate: func(e *gen.Emitter, w io.Writer, n *il.BoundCall) {
+			e.Fgenf(w, "%v.toUpperCase()", n.Args[0])
+		},
+	}))
+
+	const source = `
+variable "name" {}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "x" {
+  name      = "${shout(var.name)}"
+  user_data = "${shout(aws_vpc.main.id)}"
+}
+`
+	// Registered functions participate in output lifting like any other function.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "name: name.toUpperCase(),")
+	assert.Contains(t, code, "userData: main.id.apply(id => id.toUpperCase()),")
+
+	// Registered functions may require imports and runtime helpers.
+	t.Cleanup(il.RegisterFunction("hostname", bindString))
+	t.Cleanup(RegisterFunction("hostname", Function{
+		Generate: func(e *gen.Emitter, w io.Writer, n *il.BoundCall) {
+			e.Fgenf(w, "withSuffix(os.hostname(), %v)", n.Args[0])
+		},
+		Imports: map[string]string{"os": `import * as os from "os";`},
+		Helpers: map[string]string{
+			"withSuffix": "function withSuffix(s: string, suffix: string): string {\n    return s + suffix;\n}\n",
+		},
+	}))
+
+	code, utilities := generateProgram(t, `
+resource "aws_instance" "x" {
+  name = "${hostname("-web")}"
+}
+`)
+	assert.Contains(t, code, `import * as os from "os";`)
+	assert.Contains(t, code, `import { withSuffix } from "./utilities";`)
+	assert.Contains(t, code, `name: withSuffix(os.hostname(), "-web"),`)
+	assert.Contains(t, utilities, "export function withSuffix(s: string, suffix: string): string {")
+}
+
+func TestConcat(t *testing.T) {
+	const source = `
+variable "a" {
+  default = ["x"]
+}
+
+variable "b" {
+  default = ["y"]
+}
+
+data "aws_x" "d" {}
+
+resource "aws_vpc" "main" {
+  count = 2
+}
+
+resource "aws_instance" "web" {
+  plain    = "${concat(var.a, var.b, list("z"))}"
+  output   = "${concat(var.a, aws_vpc.main.*.id)}"
+  expanded = "${concat(var.a, data.aws_x.d.lists...)}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, "plain: [...a, ...b, ...[\"z\"]],")
+	assert.Contains(t, code, "output: pulumi.all(main.map((v: aws.Vpc) => v.id)).apply(id => [...a, ...id]),")
+	assert.Contains(t, code, "expanded: x.apply(x => [...a, ...(<any[]>[]).concat(...x.lists)]),")
+}
+
+func TestLength(t *testing.T) {
+	const source = `
+variable "subnets" {
+  default = ["a", "b"]
+}
+
+variable "tags" {
+  default = {
+    Name = "web"
+  }
+}
+
+resource "aws_instance" "web" {
+  count = "${length(var.subnets)}"
+
+  tag_count   = "${length(var.tags)}"
+  name_length = "${length(var.subnets[count.index])}"
+}
+`
+	// Strings and lists are measured using their length property. Maps are measured by the number of their keys.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "for (let i = 0; i < subnets.length; i++) {")
+	assert.Contains(t, code, "tagCount: Object.keys(tags).length,")
+	assert.Contains(t, code, "nameLength: subnets[i].length,")
+}
+
+func TestJSONFunctions(t *testing.T) {
+	const source = `
+variable "tags" {
+  default = {
+    Name = "web"
+  }
+}
+
+variable "document" {
+  default = "{}"
+}
+
+resource "aws_vpc" "main" {}
+
+locals {
+  policy = {
+    vpc = "${aws_vpc.main.id}"
+  }
+}
+
+resource "aws_instance" "web" {
+  prompt  = "${jsonencode(var.tags)}"
+  output  = "${jsonencode(map("vpc", aws_vpc.main.id))}"
+  local   = "${jsonencode(local.policy)}"
+  decoded = "${lookup(jsondecode(var.document), "key")}"
+}
+`
+	// Values that reference outputs must be resolved before they are encoded. This includes locals whose elements
+	// reference outputs.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "prompt: JSON.stringify(tags),")
+	assert.Contains(t, code, "output: main.id.apply(id => JSON.stringify({\"vpc\": id})),")
+	assert.Contains(t, code, "local: pulumi.output(policy).apply(policy => JSON.stringify(policy)),")
+	assert.Contains(t, code, "decoded: lookup((<any>JSON.parse(document)), \"key\"),")
+}
+
+func TestMerge(t *testing.T) {
+	const source = `
+variable "tags" {
+  default = {
+    Name = "default"
+  }
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  defaults  = "${merge(map("Name", "web", "Env", "dev"), var.tags)}"
+  overrides = "${merge(var.tags, map("Name", "web"))}"
+  output    = "${merge(var.tags, map("Vpc", aws_vpc.main.id))}"
+}
+`
+	// Later arguments override the keys of earlier arguments, so the order of the arguments must be preserved. Object
+	// literals that are returned from an apply must be parenthesized.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "defaults: {...{\"Name\": \"web\", \"Env\": \"dev\"}, ...tags},")
+	assert.Contains(t, code, "overrides: {...tags, ...{\"Name\": \"web\"}},")
+	assert.Contains(t, code, "output: main.id.apply(id => ({...tags, ...{\"Vpc\": id}})),")
+}
+
+func TestCoalesce(t *testing.T) {
+	const source = `
+variable "name" {
+  default = ""
+}
+
+variable "zones" {
+  default = []
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  empty  = "${coalesce("", var.name, "default")}"
+  output = "${coalesce(var.name, aws_vpc.main.id)}"
+  list   = "${coalescelist(var.zones, list("a"))}"
+}
+`
+	// Empty strings and empty lists are skipped, but falsy values like zero are not.
+	code := generateSource(t, source)
+	assert.Contains(t, code,
+		"empty: [\"\", name, \"default\"].find((v: any) => v !== undefined && v !== null && v !== \"\"),")
+	assert.Contains(t, code,
+		"output: main.id.apply(id => [name, id].find((v: any) => v !== undefined && v !== null && v !== \"\")),")
+	assert.Contains(t, code,
+		"list: [zones, [\"a\"]].find((v: any) => v !== undefined && v !== null && (<any[]>v).length > 0),")
+}
+
+func TestStringTransforms(t *testing.T) {
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: "upper", expected: "name.toUpperCase()"},
+		{call: "lower", expected: "name.toLowerCase()"},
+		{call: "title", expected: "title(name)"},
+		{call: "trimspace", expected: "name.trim()"},
+		{call: "chomp", expected: "name.replace(/(\\n|\\r\\n)*$/, \"\")"},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			source := `
+variable "name" {}
+
+resource "aws_instance" "web" {
+  value = "${` + c.call + `(var.name)}"
+}
+`
+			code, utilities := generateProgram(t, source)
+			assert.Contains(t, code, "value: "+c.expected+",")
+			assert.Equal(t, c.call == "title", strings.Contains(utilities, "export function title(str: string): string {"))
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	const source = `
+variable "name" {}
+
+variable "pattern" {}
+
+resource "aws_instance" "web" {
+  literal = "${replace(var.name, "-", "_")}"
+  regex   = "${replace(var.name, "/[a-z]+/([0-9]+)/", "$1")}"
+  dynamic = "${replace(var.name, var.pattern, "")}"
+}
+`
+	// Every occurrence of the search argument is replaced. Unescaped slashes in literal regular expressions are escaped.
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, "literal: name.split(\"-\").join(\"_\"),")
+	assert.Contains(t, code, "regex: name.replace(/[a-z]+\\/([0-9]+)/g, \"$1\"),")
+	assert.Contains(t, code, "dynamic: replace(name, pattern, \"\"),")
+	assert.Contains(t, utilities, "export function replace(str: string, search: string, replacement: string): string {")
+}
+
+func TestFormatList(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["a", "b"]
+}
+
+variable "addresses" {
+  default = ["10.0.0.1", "10.0.0.2"]
+}
+
+variable "domain" {
+  default = "example.com"
+}
+
+resource "aws_vpc" "main" {
+  count = 2
+}
+
+resource "aws_instance" "web" {
+  single   = "${formatlist("%s.example.com", var.names)}"
+  parallel = "${formatlist("%s.%s = %s", var.names, var.domain, var.addresses)}"
+  padded   = "${formatlist("%-10s", var.names)}"
+  outputs  = "${formatlist("%s-%s", var.names, aws_vpc.main.*.id)}"
+}
+`
+	// Lists are iterated in parallel, and other arguments are repeated for each element. Resolved lists of outputs are
+	// still lists.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "single: names.map(v => `${v}.example.com`),")
+	assert.Contains(t, code, "parallel: names.map((_, i) => `${names[i]}.${domain} = ${addresses[i]}`),")
+	assert.Contains(t, code, "padded: names.map(v => sprintf.sprintf(\"%-10s\", v)),")
+	assert.Contains(t, code, "outputs: pulumi.all(main.map((v: aws.Vpc) => v.id)).apply(id => "+
+		"names.map((_, i) => `${names[i]}-${id[i]}`)),")
+	assert.Contains(t, code, "import sprintf = require(\"sprintf-js\");")
+}
+
+func TestNumericFunctions(t *testing.T) {
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: "min(var.size, 1)", expected: "Math.min(size, 1)"},
+		{call: "max(var.size, 1, 2)", expected: "Math.max(size, 1, 2)"},
+		{call: "ceil(var.size / 2)", expected: "Math.ceil((size / 2))"},
+		{call: "floor(\"2.5\")", expected: "Math.floor(2.5)"},
+		{call: "abs(var.size)", expected: "Math.abs(size)"},
+		{call: "signum(var.size)", expected: "Math.sign(size)"},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			source := `
+variable "size" {
+  default = 3
+}
+
+resource "aws_instance" "web" {
+  value = "${` + c.call + `}"
+}
+`
+			code := generateSource(t, source)
+			assert.Contains(t, code, "value: "+c.expected+",")
+		})
+	}
+}
+
+func TestBase64Functions(t *testing.T) {
+	const source = `
+variable "filename" {}
+
+variable "encoded" {}
+
+resource "aws_instance" "web" {
+  user_data = "${base64encode(file(var.filename))}"
+  decoded   = "${base64decode(var.encoded)}"
+  roundtrip = "${base64decode(base64encode(file(var.filename)))}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `userData: Buffer.from(fs.readFileSync(filename, "utf-8")).toString("base64"),`)
+	assert.Contains(t, code, `decoded: Buffer.from(encoded, "base64").toString("utf-8"),`)
+	assert.Contains(t, code, `roundtrip: Buffer.from(Buffer.from(fs.readFileSync(filename, "utf-8")).toString("base64"), `+
+		`"base64").toString("utf-8"),`)
+	assert.NotContains(t, code, "import * as buffer")
+}
+
+func TestInlineFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer func() {
+		contract.IgnoreError(os.RemoveAll(dir))
+	}()
+
+	const source = `
+variable "filename" {}
+
+resource "aws_instance" "web" {
+  module_relative = "${file("${path.module}/user-data.sh")}"
+  dynamic         = "${file(var.filename)}"
+}
+`
+	err = ioutil.WriteFile(path.Join(dir, "main.tf"), []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "user-data.sh"), []byte("#!/bin/sh\necho hello\n"), 0600)
+	if err != nil {
+		t.Fatalf("could not create user-data.sh: %v", err)
+	}
+
+	generate := func(inlineFiles bool) string {
+		g, err := il.BuildGraph(module.NewTree("main", loadConfig(t, dir)), &il.BuildOptions{
+			AllowMissingProviders: true,
+			AllowMissingComments:  true,
+		})
+		if err != nil {
+			t.Fatalf("could not build graph: %v", err)
+		}
+
+		var b bytes.Buffer
+		lang, err := NewWithOptions("main", "1.0.0", Options{InlineFiles: inlineFiles}, &b)
+		assert.NoError(t, err)
+		err = gen.Generate([]*il.Graph{g}, lang)
+		assert.NoError(t, err)
+		return b.String()
+	}
+
+	// If inlining is enabled, files whose paths are known are inlined. Files with dynamic paths are still read at
+	// runtime, so the fs module is still imported.
+	code := generate(true)
+	assert.Contains(t, code, "moduleRelative: `#!/bin/sh\necho hello\n`,")
+	assert.Contains(t, code, `dynamic: fs.readFileSync(filename, "utf-8"),`)
+	assert.Contains(t, code, `import * as fs from "fs";`)
+
+	// Otherwise, all files are read at runtime.
+	code = generate(false)
+	assert.Contains(t, code, "moduleRelative: fs.readFileSync(`./user-data.sh`, \"utf-8\"),")
+	assert.Contains(t, code, `import * as fs from "fs";`)
+}
+
+func TestPathVariables(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  cwd    = "${path.cwd}"
+  root   = "${path.root}"
+  module = "${path.module}"
+  script = "${path.cwd}/scripts/init.sh"
+  data   = "${path.module}/data"
+}
+`
+	// path.cwd is evaluated at runtime. path.module and path.root are lowered to paths relative to the root module.
+	code := generateSource(t, source)
+	assert.Contains(t, code, `import * as process from "process";`)
+	assert.Contains(t, code, "cwd: process.cwd(),")
+	assert.Contains(t, code, "script: `${process.cwd()}/scripts/init.sh`,")
+	assert.Contains(t, code, `root: ".",`)
+	assert.Contains(t, code, `module: ".",`)
+	assert.Contains(t, code, "data: `./data`,")
+}
+
+func TestKeysAndValues(t *testing.T) {
+	const source = `
+variable "tags" {
+  default = {
+    Name = "web"
+    Env  = "dev"
+  }
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  variable_keys   = "${keys(var.tags)}"
+  variable_values = "${values(var.tags)}"
+  literal_keys    = "${keys(map("b", "1", "a", "2"))}"
+  literal_values  = "${values(map("b", "1", "a", "2"))}"
+  output_values   = "${values(aws_vpc.main.tags)}"
+}
+`
+	// As in Terraform, keys are sorted, and values are ordered by their keys.
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, "variableKeys: Object.keys(tags).sort(),")
+	assert.Contains(t, code, "variableValues: values(tags),")
+	assert.Contains(t, code, "literalKeys: Object.keys({\"b\": \"1\", \"a\": \"2\"}).sort(),")
+	assert.Contains(t, code, "literalValues: values({\"b\": \"1\", \"a\": \"2\"}),")
+	assert.Contains(t, code, "outputValues: main.tags.apply(tags => values(tags)),")
+	assert.Contains(t, utilities, "export function values(map: any): any[] {")
+}
+
+func TestListFunctions(t *testing.T) {
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: `contains(var.zones, "a")`, expected: `zones.includes("a")`},
+		{call: `index(var.zones, "b")`, expected: `index(zones, "b")`},
+		{call: `distinct(var.zones)`, expected: `Array.from(new Set(zones))`},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			source := `
+variable "zones" {
+  default = ["a", "b", "a"]
+}
+
+resource "aws_instance" "web" {
+  value = "${` + c.call + `}"
+}
+`
+			code := generateSource(t, source)
+			assert.Contains(t, code, "value: "+c.expected+",")
+		})
+	}
+
+	// index is implemented by a helper that throws if the list does not contain the value.
+	_, utilities := generateProgram(t, `
+variable "zones" {
+  default = ["a", "b", "a"]
+}
+
+resource "aws_instance" "web" {
+  value = "${index(var.zones, "b")}"
+}
+`)
+	assert.Contains(t, utilities, "export function index(list: any[], value: any): number {")
+}
+
+func TestCIDRFunctions(t *testing.T) {
+	const source = `
+variable "hostnum" {
+  default = "5"
+}
+
+resource "aws_subnet" "subnet" {
+  count = 3
+
+  cidr_block = "${cidrsubnet("10.0.0.0/16", 8, count.index)}"
+  gateway    = "${cidrhost("10.0.0.0/16", var.hostnum)}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, `cidrBlock: cidrsubnet("10.0.0.0/16", 8, i),`)
+	assert.Contains(t, code, `gateway: cidrhost("10.0.0.0/16", Number.parseFloat(hostnum)),`)
+	assert.Contains(t, utilities, "function parseCIDR(prefix: string): { bytes: number[], length: number } {")
+	assert.Contains(t, utilities, "export function cidrsubnet(prefix: string, newbits: number, netnum: number): string {")
+	assert.Contains(t, utilities, "export function cidrhost(prefix: string, hostnum: number): string {")
+
+	// The shared helpers are only emitted once.
+	assert.Equal(t, 1, strings.Count(utilities, "function parseCIDR("))
+
+	// The helpers compute the same results as Terraform for both IPv4 and IPv6 prefixes.
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: `cidrsubnet("10.0.0.0/16", 8, 2)`, expected: "10.0.2.0/24"},
+		{call: `cidrsubnet("172.16.0.0/12", 4, 15)`, expected: "172.31.0.0/16"},
+		{call: `cidrsubnet("10.1.2.0/24", 4, 15)`, expected: "10.1.2.240/28"},
+		{call: `cidrsubnet("fd00:fd12:3456:7890::/56", 16, 162)`, expected: "fd00:fd12:3456:7800:a200::/72"},
+		{call: `cidrsubnet("2607:f298:6051:516c::/64", 8, 1)`, expected: "2607:f298:6051:516c:100::/72"},
+		{call: `cidrhost("10.12.127.0/20", 16)`, expected: "10.12.112.16"},
+		{call: `cidrhost("10.12.127.0/20", 268)`, expected: "10.12.113.12"},
+		{call: `cidrhost("10.0.0.0/8", -1)`, expected: "10.255.255.255"},
+		{call: `cidrhost("fd00:fd12:3456:7890:00a2::/72", 34)`, expected: "fd00:fd12:3456:7890::22"},
+		{call: `cidrhost("fd00::/64", 1)`, expected: "fd00::1"},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			assert.Equal(t, c.expected, evaluateHelpers(t, utilities, c.call))
+		})
+	}
+}
+
+func TestSubstr(t *testing.T) {
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: `substr(var.name, 0, -1)`, expected: `substr(name, 0, -1)`},
+		{call: `substr(var.name, 2, 3)`, expected: `substr(name, 2, 3)`},
+		{call: `substr(var.name, -3, 2)`, expected: `substr(name, -3, 2)`},
+		{call: `substr(var.name, "1", "2")`, expected: `substr(name, 1, 2)`},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			source := `
+variable "name" {
+  default = "hello world"
+}
+
+resource "aws_instance" "web" {
+  value = "${` + c.call + `}"
+}
+`
+			code, utilities := generateProgram(t, source)
+			assert.Contains(t, code, "value: "+c.expected+",")
+			assert.Contains(t, utilities, "export function substr(str: string, offset: number, length: number): string {")
+		})
+	}
+}
+
+func TestListShapingFunctions(t *testing.T) {
+	cases := []struct {
+		call     string
+		expected string
+	}{
+		{call: `slice(var.zones, 1, 2)`, expected: `zones.slice(1, 2)`},
+		{call: `slice(var.zones, "0", var.count)`, expected: `zones.slice(0, Number.parseFloat(count))`},
+		{call: `sort(var.zones)`, expected: `[...zones].sort()`},
+		{call: `sort(var.sizes)`, expected: `[...sizes].sort((a, b) => a - b)`},
+		{call: `reverse(var.zones)`, expected: `[...zones].reverse()`},
+		{call: `element(sort(var.sizes), 0)`, expected: `[...sizes].sort((a, b) => a - b)[0]`},
+	}
+	for _, c := range cases {
+		t.Run(c.call, func(t *testing.T) {
+			source := `
+variable "zones" {
+  default = ["b", "a", "c"]
+}
+
+variable "sizes" {
+  default = [10, 9, 100]
+}
+
+variable "count" {
+  default = "2"
+}
+
+resource "aws_instance" "web" {
+  value = "${` + c.call + `}"
+}
+`
+			code := generateSource(t, source)
+			assert.Contains(t, code, "value: "+c.expected+",")
+		})
+	}
+}
+
+func TestZipmap(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["web", "db"]
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  literal_keys = "${zipmap(list("a", "b"), var.names)}"
+  output_keys  = "${zipmap(list(aws_vpc.main.id), var.names)}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, `literalKeys: zipmap(["a", "b"], names),`)
+	assert.Contains(t, code, `outputKeys: main.id.apply(id => zipmap([id], names)),`)
+	assert.Contains(t, utilities, "export function zipmap(keys: string[], values: any[]): {[key: string]: any} {")
+}
+
+func TestTimeAndUUIDFunctions(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  created = "${timestamp()}"
+  name    = "web-${uuid()}"
+  date    = "${formatdate("DD MMM YYYY hh:mm ZZZ", timestamp())}"
+  invalid = "${formatdate("YYYY", "yesterday")}"
+}
+`
+	code, utilities := generateProgram(t, source)
+	assert.Contains(t, code, "created: new Date().toISOString(),")
+	assert.Contains(t, code, "name: `web-${uuid()}`,")
+	assert.Contains(t, code, `date: formatdate("DD MMM YYYY hh:mm ZZZ", new Date().toISOString()),`)
+	assert.Contains(t, code, `throw "tf2pulumi error: main.tf:6:16: not a valid RFC3339 timestamp: \"yesterday\"";`)
+	assert.Contains(t, utilities, `import * as crypto from "crypto";`)
+	assert.Contains(t, utilities, "export function uuid(): string {")
+	assert.Contains(t, utilities, "export function formatdate(spec: string, time: string): string {")
+}
+
+func TestCountIndexInterpolation(t *testing.T) {
+	const source = `
+resource "test_group" "group" {
+  count = 2
+  name  = "${count.index}"
+  size  = "${count.index}"
+  other = "${count.index}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_group": {
+			Schema: map[string]*schema.Schema{
+				"name": {Type: schema.TypeString, Optional: true},
+				"size": {Type: schema.TypeInt, Optional: true},
+			},
+		},
+	})
+
+	// An interpolated count index is a string unless the field expects a number.
+	code := generateSourceWithProviders(t, source, providers)
+	assert.Contains(t, code, "name: `${i}`,")
+	assert.Contains(t, code, "size: i,")
+	assert.Contains(t, code, "other: `${i}`,")
+}
+
+func TestUnaryOperators(t *testing.T) {
+	const source = `
+variable "size" {
+  default = 2
+}
+
+variable "enabled" {
+  default = true
+}
+
+variable "flag" {
+  default = "true"
+}
+
+resource "aws_x" "y" {
+  offset   = "${-var.size * 2}"
+  negative = "${-5}"
+  state    = "${!var.enabled ? "off" : "on"}"
+  inverted = "${!var.flag}"
+  nested   = "${!(var.size > 1)}"
+}
+`
+	// Negations are generated as prefix operators. Negated literals are folded into negative literals.
+	code := generateSource(t, source)
+	assert.Contains(t, code, "offset: ((-size) * 2),")
+	assert.Contains(t, code, "negative: -5,")
+	assert.Contains(t, code, `state: ((!enabled) ? "off" : "on"),`)
+	assert.Contains(t, code, `inverted: (!(flag === "true")),`)
+	assert.Contains(t, code, "nested: (!(size > 1)),")
+
+	// Unary expressions are typed according to their operators.
+	g := buildSource(t, source)
+	props := g.Resources["aws_x.y"].Properties.Elements
+	offset := props["offset"].(*il.BoundArithmetic)
+	if assert.IsType(t, &il.BoundArithmetic{}, offset.Exprs[0]) {
+		assert.Len(t, offset.Exprs[0].(*il.BoundArithmetic).Exprs, 1)
+		assert.Equal(t, il.TypeNumber, offset.Exprs[0].Type())
+	}
+	if assert.IsType(t, &il.BoundArithmetic{}, props["nested"]) {
+		assert.Len(t, props["nested"].(*il.BoundArithmetic).Exprs, 1)
+		assert.Equal(t, il.TypeBool, props["nested"].Type())
+	}
+}
+
+func TestRuntimeHelpersModule(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["a", "b"]
+}
+
+resource "aws_instance" "web" {
+  tags = "${zipmap(list("a", "b"), var.names)}"
+  name = "${title("web")}"
+  ami  = "${title(var.names[0])}"
+}
+`
+	code, utilities := generateProgram(t, source)
+
+	// Only the helpers that are used are imported, and each is imported once.
+	assert.Contains(t, code, `import { title, zipmap } from "./utilities";`)
+	assert.Contains(t, code, `tags: zipmap(["a", "b"], names),`)
+	assert.Contains(t, code, `name: title("web"),`)
+	assert.NotContains(t, code, "function ")
+
+	// The utilities module exports exactly the helpers that are used.
+	assert.Equal(t, 2, strings.Count(utilities, "function "))
+	assert.Equal(t, 1, strings.Count(utilities, "export function title(str: string): string {"))
+	assert.Equal(t, 1,
+		strings.Count(utilities, "export function zipmap(keys: string[], values: any[]): {[key: string]: any} {"))
+
+	// Programs that use no helpers have no utilities module.
+	_, utilities = generateProgram(t, `
+resource "aws_instance" "web" {
+  name = "${upper("web")}"
+}
+`)
+	assert.Empty(t, utilities)
+}
+
+// typeAnnotations matches the TypeScript type annotations used by the runtime helpers that are evaluated by
+// evaluateHelpers, along with the replacements that strip them.
+var typeAnnotations = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`^export `), ""},
+	{regexp.MustCompile(`\): [^()]+ \{\n`), ") {\n"},
+	{regexp.MustCompile(`(\w+): (?:string|number|any)(?:\[\])?([,)])`), "$1$2"},
+	{regexp.MustCompile(`(\w+): number\[\](?: \| undefined)?([;=]| =)`), "$1$2"},
+	{regexp.MustCompile(`\(\[\] as number\[\]\)`), "[]"},
+}
+
+// evaluateHelpers evaluates the given expression in the context of the given runtime helpers using Node.js, and
+// returns the result. TypeScript type annotations are stripped from the helpers before they are evaluated. The test is
+// skipped if Node.js is not available.
+func evaluateHelpers(t *testing.T, helpers, expr string) string {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not available")
+	}
+
+	lines := strings.Split(helpers, "\n")
+	for i, line := range lines {
+		line += "\n"
+		for _, a := range typeAnnotations {
+			line = a.pattern.ReplaceAllString(line, a.replacement)
+		}
+		lines[i] = strings.TrimSuffix(line, "\n")
+	}
+	script := strings.Join(lines, "\n") + fmt.Sprintf("\nprocess.stdout.write(String(%s));\n", expr)
+
+	cmd := exec.Command(node)
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("could not evaluate %v: %v\n%s", expr, err, out)
+	}
+	return string(out)
+}
@@ -72,13 +72,15 @@ func (g *generator) generateHTTP(r *il.ResourceNode) error {
 		if err != nil {
 			return err
 		}
-		inputs, err := g.computeHTTPInputs(r, true, "i")
+		index := g.pushTemporary("i")
+		defer g.popTemporary()
+		inputs, err := g.computeHTTPInputs(r, true, index)
 		if err != nil {
 			return err
 		}
 
 		g.Printf("const %s: pulumi.Output<string>[] = [];\n", name)
-		g.Printf("for (let i = 0; i < %s; i++) {\n", count)
+		g.Printf("for (let %s = 0; %s < %s; %s++) {\n", index, index, count, index)
 		g.Printf("    %s.push(pulumi.output(rpn(%s).promise()));\n", name, inputs)
 		g.Printf("}")
 	}
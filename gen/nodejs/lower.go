@@ -15,7 +15,10 @@
 package nodejs
 
 import (
+	"encoding/base64"
+	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 
@@ -23,10 +26,155 @@ import (
 	"github.com/pulumi/tf2pulumi/internal/config"
 )
 
-// lowerToLiterals lowers certain elements--namely Module and Root path references--to bound literals. This allows the
-// code generator to fold these expressions into template literals as necessary.
+// filePath returns the path to the file referenced by the given argument to `file` if that path can be determined at
+// generation time, i.e. if the argument is a string literal or an interpolation of string literals and references to
+// `path.module` or `path.root`. Relative paths that do not begin with a path variable are interpreted relative to the
+// root module's directory.
+func (g *generator) filePath(m *il.Graph, arg il.BoundExpr) (string, bool) {
+	var parts []il.BoundExpr
+	switch arg := arg.(type) {
+	case *il.BoundLiteral:
+		parts = []il.BoundExpr{arg}
+	case *il.BoundOutput:
+		parts = arg.Exprs
+	default:
+		return "", false
+	}
+
+	var b strings.Builder
+	hasPathVariable := false
+	for _, part := range parts {
+		switch part := part.(type) {
+		case *il.BoundLiteral:
+			if part.ExprType != il.TypeString {
+				return "", false
+			}
+			b.WriteString(part.Value.(string))
+		case *il.BoundVariableAccess:
+			pv, ok := part.TFVar.(*config.PathVariable)
+			if !ok {
+				return "", false
+			}
+			switch pv.Type {
+			case config.PathValueModule:
+				if m.Path == "" {
+					b.WriteString(".")
+				} else {
+					b.WriteString(m.Path)
+				}
+			case config.PathValueRoot:
+				b.WriteString(g.rootPath)
+			default:
+				return "", false
+			}
+			hasPathVariable = true
+		default:
+			return "", false
+		}
+	}
+
+	path := b.String()
+	if !hasPathVariable && !filepath.IsAbs(path) {
+		path = filepath.Join(g.rootPath, path)
+	}
+	return path, true
+}
+
+// readFileContents attempts to read the contents of the file referenced by a call to `file` in the given module. If
+// the path to the file can be determined at generation time and the file can be read, this function returns the
+// contents of the file and true.
+func (g *generator) readFileContents(m *il.Graph, n *il.BoundCall) (string, bool) {
+	if n.Func != "file" || len(n.Args) != 1 {
+		return "", false
+	}
+	path, ok := g.filePath(m, n.Args[0])
+	if !ok {
+		return "", false
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(contents), true
+}
+
+// encodeFileContents attempts to read and base64-encode the contents of the file referenced by a call of the form
+// `base64encode(file(path))` in the given module, where path can be determined at generation time. If the call has the
+// expected form and the file can be read, this function returns the inner call to `file`, the encoded contents of the
+// file, and true.
+func (g *generator) encodeFileContents(m *il.Graph, n *il.BoundCall) (*il.BoundCall, string, bool) {
+	if n.Func != "base64encode" || len(n.Args) != 1 {
+		return nil, "", false
+	}
+	fileCall, ok := n.Args[0].(*il.BoundCall)
+	if !ok {
+		return nil, "", false
+	}
+	contents, ok := g.readFileContents(m, fileCall)
+	if !ok {
+		return nil, "", false
+	}
+	return fileCall, base64.StdEncoding.EncodeToString([]byte(contents)), true
+}
+
+// inlineFileContents reads the contents of the files that are inlined into the generated code: files with known paths
+// whose contents are base64-encoded by a call of the form `base64encode(file(path))` and, if file inlining is enabled,
+// files with known paths that are read by calls to `file`. The contents of these files are recorded in inlinedFiles,
+// and are substituted for the corresponding calls by lowerToLiterals. This function returns the set of calls to `file`
+// that will not be generated: those that are inlined and those that are passed to asset-typed properties, which are
+// replaced with the file's path.
+func (g *generator) inlineFileContents(modules []*il.Graph) map[*il.BoundCall]bool {
+	inlinedFileCalls := map[*il.BoundCall]bool{}
+	for _, m := range modules {
+		module := m
+		inline := func(n il.BoundNode) (il.BoundNode, error) {
+			// Nodes are visited before their children, so a call to `file` that is passed to an asset-typed property
+			// or whose contents are encoded by its enclosing call to `base64encode` is never inlined on its own.
+			switch n := n.(type) {
+			case *il.BoundMapProperty:
+				for k, e := range n.Elements {
+					c, ok := e.(*il.BoundCall)
+					if !ok || c.Func != "file" {
+						continue
+					}
+					if sch := n.Schemas.PropertySchemas(k).Pulumi; sch != nil && sch.Asset != nil && sch.Asset.IsAsset() {
+						inlinedFileCalls[c] = true
+					}
+				}
+			case *il.BoundCall:
+				switch n.Func {
+				case "base64encode":
+					if fileCall, encoded, ok := g.encodeFileContents(module, n); ok {
+						g.inlinedFiles[n], inlinedFileCalls[fileCall] = encoded, true
+					}
+				case "file":
+					if g.inlineFiles && !inlinedFileCalls[n] {
+						if contents, ok := g.readFileContents(module, n); ok {
+							g.inlinedFiles[n], inlinedFileCalls[n] = contents, true
+						}
+					}
+				}
+			}
+			return n, nil
+		}
+		err := il.VisitAllProperties(m, inline, il.IdentityVisitor)
+		contract.Assert(err == nil)
+	}
+	return inlinedFileCalls
+}
+
+// lowerToLiterals lowers certain elements--namely Module and Root path references and the inlined contents of files
+// with literal paths--to bound literals. This allows the code generator to fold these expressions into template
+// literals as necessary.
 func (g *generator) lowerToLiterals(prop il.BoundNode) (il.BoundNode, error) {
 	rewriter := func(n il.BoundNode) (il.BoundNode, error) {
+		if c, ok := n.(*il.BoundCall); ok {
+			if encoded, ok := g.inlinedFiles[c]; ok {
+				return &il.BoundLiteral{ExprType: il.TypeString, Value: encoded}, nil
+			}
+			return n, nil
+		}
+
 		v, ok := n.(*il.BoundVariableAccess)
 		if !ok {
 			return n, nil
@@ -61,8 +209,17 @@ func (g *generator) lowerToLiterals(prop il.BoundNode) (il.BoundNode, error) {
 
 // canLiftVariableAccess returns true if this variable access expression can be lifted. Any variable access expression
 // that does not contain references to potentially-undefined values (e.g. optional fields of a resource) can be lifted.
+// Accesses to variables other than resources (e.g. possibly-unknown module inputs) do not access nested properties,
+// and can always be lifted. Accesses that splat over nested blocks must map over resolved lists, and cannot be lifted.
 func (g *generator) canLiftVariableAccess(v *il.BoundVariableAccess) bool {
+	if _, ok := v.TFVar.(*config.ResourceVariable); !ok {
+		return true
+	}
+
 	sch, elements := g.getNestedPropertyAccessElementInfo(v)
+	if countSplats(elements) > 0 {
+		return false
+	}
 
 	for _, e := range elements {
 		if sch.TF != nil && sch.TF.Optional {
@@ -99,6 +256,31 @@ func (g *generator) parseProxyApply(args []*il.BoundVariableAccess, then il.Boun
 	return v, true
 }
 
+// parseStringCoercion attempts to match the given parsed apply against the pattern (call __coerce (call __applyArg 0)
+// string), where argument zero is a number or a boolean. If the call matches, it returns a coercion of the
+// BoundVariableAccess that corresponds to argument zero, which can then be generated as a call to `.apply(String)`.
+func (g *generator) parseStringCoercion(args []*il.BoundVariableAccess, then il.BoundExpr) (*il.BoundCall, bool) {
+	thenCall, ok := then.(*il.BoundCall)
+	if !ok || thenCall.Func != il.IntrinsicCoerce {
+		return nil, false
+	}
+
+	value, toType := il.ParseCoerceCall(thenCall)
+	if toType != il.TypeString {
+		return nil, false
+	}
+
+	v, ok := g.parseProxyApply(args, value)
+	if !ok {
+		return nil, false
+	}
+	if t := v.Type().ElementType(); v.Type().IsList() || t != il.TypeNumber && t != il.TypeBool {
+		return nil, false
+	}
+
+	return il.NewCoerceCall(v, il.TypeString), true
+}
+
 // hasApplyArgDescendant returns true if the given BoundExpr has any descendant that is a call to __applyArg. This is a
 // helper for parseInterpolate.
 func hasApplyArgDescendant(expr il.BoundExpr) bool {
@@ -150,10 +332,12 @@ func (g *generator) parseInterpolate(args []*il.BoundVariableAccess, then il.Bou
 // lowerProxyApplies lowers certain calls to the apply intrinsic into proxied property accesses and/or calls to the
 // pulumi.interpolate function. Concretely, this boils down to rewriting the following shapes
 // - (call __apply (resource variable access) (call __applyArg 0))
+// - (call __apply (resource variable access) (call __coerce (call __applyArg 0) string))
 // - (call __apply (resource variable access 0) ... (resource variable access n)
 //       (output /* some mix of expressions and calls to __applyArg))
 // into (respectively)
 // - (resource variable access)
+// - (call __coerce (resource variable access) string)
 // - (call __interpolate /* mix of literals and variable accesses that correspond to the __applyArg calls)
 //
 // The generated code requires that the target version of `@pulumi/pulumi` supports output proxies.
@@ -173,6 +357,11 @@ func (g *generator) lowerProxyApplies(prop il.BoundNode) (il.BoundNode, error) {
 			return v, nil
 		}
 
+		// Attempt to match (call __apply (rvar) (call __coerce (call __applyArg 0) string))
+		if v, ok := g.parseStringCoercion(args, then); ok {
+			return v, nil
+		}
+
 		// Attempt to match (call __apply (rvar 0) ... (rvar n) (output /* mix of literals and calls to __applyArg)
 		if v, ok := g.parseInterpolate(args, then); ok {
 			return v, nil
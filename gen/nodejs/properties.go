@@ -15,7 +15,6 @@
 package nodejs
 
 import (
-	"fmt"
 	"io"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -26,6 +25,11 @@ import (
 
 // genListProperty generates code for as single list property.
 func (g *generator) GenListProperty(w io.Writer, n *il.BoundListProperty) {
+	if len(n.Elements) > 1 && hasOutputListElement(n) {
+		g.genOutputListProperty(w, n)
+		return
+	}
+
 	switch len(n.Elements) {
 	case 0:
 		g.Fgen(w, "[]")
@@ -66,6 +70,33 @@ func (g *generator) GenListProperty(w io.Writer, n *il.BoundListProperty) {
 	}
 }
 
+// hasOutputListElement returns true if any element of the given list is an output-typed list.
+func hasOutputListElement(n *il.BoundListProperty) bool {
+	for _, v := range n.Elements {
+		if t := v.Type(); t.IsList() && t.IsOutput() {
+			return true
+		}
+	}
+	return false
+}
+
+// genOutputListProperty generates code for a list property with elements that are output-typed lists. Terraform
+// flattens list elements that are themselves lists into the parent list, but outputs cannot be spread into an array
+// literal. Instead, all of the elements are resolved and then concatenated inside an apply. Any element that resolves
+// to a list is flattened by the concatenation.
+func (g *generator) genOutputListProperty(w io.Writer, n *il.BoundListProperty) {
+	g.Fgen(w, "pulumi.all([")
+	g.Indented(func() {
+		for _, v := range n.Elements {
+			g.Fgenf(w, "\n")
+			g.genLeadingComment(w, v.Comments())
+			g.Fgenf(w, "%s%v,", g.Indent, v)
+			g.genTrailingComment(w, v.Comments())
+		}
+	})
+	g.Fgen(w, "\n", g.Indent, "]).apply(elements => (<any[]>[]).concat(...elements))")
+}
+
 // genMapProperty generates code for a single map property.
 func (g *generator) GenMapProperty(w io.Writer, n *il.BoundMapProperty) {
 	if len(n.Elements) == 0 {
@@ -85,7 +116,7 @@ func (g *generator) GenMapProperty(w io.Writer, n *il.BoundMapProperty) {
 				if !useExactKeys {
 					key = tsName(k, propSch.TF, propSch.Pulumi, true)
 				} else if !isLegalIdentifier(key) {
-					key = fmt.Sprintf("%q", key)
+					key = stringLiteral(key)
 				}
 				g.Fgenf(w, "%s%s: %v,", g.Indent, key, v)
 
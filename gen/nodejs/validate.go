@@ -0,0 +1,272 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes a syntax error in the TypeScript code generated for a single node.
+type SyntaxError struct {
+	// Node identifies the Terraform node whose generated code contains the error (e.g. "aws_instance.foo" or "local.bar").
+	Node string
+	// Property is the name of the innermost property that contains the error, if any.
+	Property string
+	// Line is the 1-based line of the error within the generated code for the node.
+	Line int
+	// Column is the 1-based column of the error within the generated code for the node.
+	Column int
+	// Message describes the error.
+	Message string
+	// Source is the line of generated code that contains the error.
+	Source string
+}
+
+func (e *SyntaxError) Error() string {
+	var b strings.Builder
+	b.WriteString("generated code")
+	if e.Node != "" {
+		fmt.Fprintf(&b, " for %s", e.Node)
+	}
+	if e.Property != "" {
+		fmt.Fprintf(&b, " (property %s)", e.Property)
+	}
+	fmt.Fprintf(&b, " has a syntax error at %d:%d: %s\n    %s", e.Line, e.Column, e.Message, e.Source)
+	return b.String()
+}
+
+// annotateSyntaxError records the name of the node responsible for err if err is a SyntaxError.
+func annotateSyntaxError(err error, node string) error {
+	if serr, ok := err.(*SyntaxError); ok && serr.Node == "" {
+		serr.Node = node
+	}
+	return err
+}
+
+// propertyKeyRegexp matches a line of generated code that begins an object property.
+var propertyKeyRegexp = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|[\p{L}\p{N}_$]+): `)
+
+// propertyAt returns the name of the innermost property that contains the given line of generated code. It finds this
+// property by walking backwards to the closest line that begins a property.
+func propertyAt(lines []string, line int) string {
+	for ; line >= 0; line-- {
+		if m := propertyKeyRegexp.FindStringSubmatch(lines[line]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// newSyntaxError creates a SyntaxError for the given offset into the generated code.
+func newSyntaxError(code string, offset int, format string, args ...interface{}) *SyntaxError {
+	lines := strings.Split(code, "\n")
+	line, lineStart := 0, 0
+	for line < len(lines)-1 && lineStart+len(lines[line]) < offset {
+		lineStart += len(lines[line]) + 1
+		line++
+	}
+	column := utf8.RuneCountInString(code[lineStart:offset]) + 1
+
+	return &SyntaxError{
+		Property: propertyAt(lines, line),
+		Line:     line + 1,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+		Source:   strings.TrimSpace(lines[line]),
+	}
+}
+
+// checkDelimiters performs a lexical check of the given generated TypeScript code. It ensures that all string
+// literals, template literals, regular expression literals, and comments are terminated and that all brackets are
+// balanced. This is not a parse, and does not detect other syntax errors, but it is sufficient to catch the sorts of
+// escaping and nesting errors that the generator is likely to make.
+func checkDelimiters(code string) error {
+	type frame struct {
+		delim  byte // One of '(', '[', '{', or '$' for a template substitution.
+		offset int  // The offset of the delimiter, or of the start of the template literal for a substitution.
+	}
+
+	closers := map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+	// prev is the last significant character before the current offset. It is used to distinguish regular expression
+	// literals from division operators.
+	var prev byte
+
+	var stack []frame
+	for i := 0; i < len(code); {
+		switch c := code[i]; c {
+		case '\'', '"':
+			end, err := scanStringLiteral(code, i)
+			if err != nil {
+				return err
+			}
+			i, prev = end, c
+		case '`':
+			end, subst, err := scanTemplateLiteral(code, i, i+1)
+			if err != nil {
+				return err
+			}
+			prev = '`'
+			if subst {
+				stack = append(stack, frame{delim: '$', offset: i})
+				prev = '{'
+			}
+			i = end
+		case '/':
+			switch {
+			case strings.HasPrefix(code[i:], "//"):
+				end := strings.IndexByte(code[i:], '\n')
+				if end == -1 {
+					return nil
+				}
+				i += end
+			case strings.HasPrefix(code[i:], "/*"):
+				end := strings.Index(code[i+2:], "*/")
+				if end == -1 {
+					return newSyntaxError(code, i, "unterminated comment")
+				}
+				i += end + 4
+			case regexpMayFollow(prev):
+				end, err := scanRegexpLiteral(code, i)
+				if err != nil {
+					return err
+				}
+				i, prev = end, c
+			default:
+				i, prev = i+1, c
+			}
+		case '(', '[', '{':
+			stack = append(stack, frame{delim: c, offset: i})
+			i, prev = i+1, c
+		case ')', ']', '}':
+			if len(stack) == 0 {
+				return newSyntaxError(code, i, "unexpected '%c'", c)
+			}
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if c == '}' && top.delim == '$' {
+				// This closes a template substitution: continue scanning the template literal.
+				end, subst, err := scanTemplateLiteral(code, top.offset, i+1)
+				if err != nil {
+					return err
+				}
+				prev = '`'
+				if subst {
+					stack = append(stack, frame{delim: '$', offset: top.offset})
+					prev = '{'
+				}
+				i = end
+				continue
+			}
+			if top.delim != closers[c] {
+				// Attribute the error to the property that contains the unclosed delimiter.
+				serr := newSyntaxError(code, i, "unexpected '%c'; expected a match for '%c'", c, top.delim)
+				serr.Property = newSyntaxError(code, top.offset, "").Property
+				return serr
+			}
+			i, prev = i+1, c
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			i, prev = i+1, c
+		}
+	}
+
+	if len(stack) != 0 {
+		top := stack[len(stack)-1]
+		if top.delim == '$' {
+			return newSyntaxError(code, top.offset, "unterminated template literal")
+		}
+		return newSyntaxError(code, top.offset, "unclosed '%c'", top.delim)
+	}
+	return nil
+}
+
+// scanStringLiteral scans the single- or double-quoted string literal that begins at the given offset and returns the
+// offset of the first character after the literal.
+func scanStringLiteral(code string, start int) (int, error) {
+	quote := code[start]
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '\n':
+			return 0, newSyntaxError(code, start, "unterminated string literal")
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, newSyntaxError(code, start, "unterminated string literal")
+}
+
+// regexpMayFollow returns true if a '/' that follows the given significant character begins a regular expression
+// literal rather than a division operator. This is the case at the start of the code and after punctuation that cannot
+// end an operand. This does not account for keywords such as `return`, which the generator never places before a
+// regular expression literal.
+func regexpMayFollow(prev byte) bool {
+	return prev == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", prev) != -1
+}
+
+// scanRegexpLiteral scans the regular expression literal that begins at the given offset and returns the offset of the
+// first character after the literal and its flags.
+func scanRegexpLiteral(code string, start int) (int, error) {
+	inClass := false
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '\n':
+			return 0, newSyntaxError(code, start, "unterminated regular expression literal")
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				// Skip the literal's flags.
+				i++
+				for i < len(code) && code[i] >= 'a' && code[i] <= 'z' {
+					i++
+				}
+				return i, nil
+			}
+		}
+	}
+	return 0, newSyntaxError(code, start, "unterminated regular expression literal")
+}
+
+// scanTemplateLiteral scans the template literal that begins at the given start offset, starting at offset i. It
+// returns the offset of the first character after the literal or after the start of the next substitution. In the
+// latter case, the returned bool is true; the caller is responsible for scanning the substitution and then resuming the
+// template literal.
+func scanTemplateLiteral(code string, start, i int) (int, bool, error) {
+	for ; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, false, nil
+		case '$':
+			if i+1 < len(code) && code[i+1] == '{' {
+				return i + 2, true, nil
+			}
+		}
+	}
+	return 0, false, newSyntaxError(code, start, "unterminated template literal")
+}
@@ -0,0 +1,87 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodejs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDelimiters(t *testing.T) {
+	valid := []string{
+		"`foo ${bar} baz`",
+		"`foo ${`nested ${bar[\"}\"]}`} baz`",
+		"`\\${not a substitution`",
+		"{\n    foo: \"a \\\" b\",\n    bar: 'c',\n}",
+		"x.apply(x => (x / 2) /* comment ) */)",
+		"// trailing comment (",
+		"x.replace(/[(\"/]\\//g, \"-\")",
+		"(a) / (b) / c",
+	}
+	for _, code := range valid {
+		assert.NoError(t, checkDelimiters(code), code)
+	}
+
+	cases := []struct {
+		code              string
+		line, column      int
+		property, message string
+	}{
+		{"{\n    foo: `bar ${baz`,\n}", 2, 20, "foo", "unterminated template literal"},
+		{"{\n    foo: \"bar,\n}", 2, 10, "foo", "unterminated string literal"},
+		{"{\n    foo: (a + b,\n    bar: 1,\n}", 4, 1, "foo", "unexpected '}'; expected a match for '('"},
+		{"{\n    foo: a + b),\n}", 2, 15, "", "unexpected ')'; expected a match for '{'"},
+		{"{\n    foo: [a,\n", 2, 10, "foo", "unclosed '['"},
+		{"a /* b", 1, 3, "", "unterminated comment"},
+		{"{\n    foo: x.replace(/a(, \"b\"),\n}", 2, 20, "foo", "unterminated regular expression literal"},
+	}
+	for _, c := range cases {
+		err := checkDelimiters(c.code)
+		if !assert.Error(t, err, c.code) {
+			continue
+		}
+		serr := err.(*SyntaxError)
+		assert.Equal(t, c.line, serr.Line, c.code)
+		assert.Equal(t, c.column, serr.Column, c.code)
+		assert.Equal(t, c.property, serr.Property, c.code)
+		assert.Equal(t, c.message, serr.Message, c.code)
+	}
+}
+
+func TestCheckGeneratedDelimiters(t *testing.T) {
+	const source = `
+variable "name" {}
+
+resource "aws_x" "y" {}
+
+resource "aws_z" "w" {
+  quotes    = "it's \"quoted\" and ` + "`backticked`" + `"
+  escaped   = "$${not_interpolated} and \\${var.name}"
+  nested    = "${format("%s-${var.name}", aws_x.y.id)}"
+  condition = "${var.name == "" ? "${aws_x.y.id}-${var.name}" : "{}"}"
+  math      = "${length(var.name) * 2 + 1}"
+  braces    = "}{ ${var.name} ][ )("
+  regexp    = "${replace(var.name, "/[(\"]/", "-")}"
+}
+
+output "o" {
+  value = "${aws_z.w.quotes}:${lookup(aws_z.w.tags, "k", "${aws_x.y.id}")}"
+}
+`
+	code := generateSource(t, source)
+	assert.Contains(t, code, `regexp: name.replace(/[("]/g, "-"),`)
+	assert.NoError(t, checkDelimiters(code))
+}
@@ -52,6 +52,11 @@ type generator struct {
 	countIndex string
 	// unknownInputs is the set of input variables that may be unknown at runtime.
 	unknownInputs map[*il.VariableNode]struct{}
+
+	// inApplyCall is true iff we are currently generating an apply call.
+	inApplyCall bool
+	// applyArgs is the list of currently in-scope apply arguments.
+	applyArgs []*il.BoundVariableAccess
 }
 
 func (g *generator) GeneratePreamble(modules []*il.Graph) error {
@@ -0,0 +1,93 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/tf2pulumi/gen"
+	"github.com/pulumi/tf2pulumi/il"
+	"github.com/pulumi/tf2pulumi/internal/config"
+	"github.com/pulumi/tf2pulumi/internal/config/module"
+	"github.com/pulumi/tf2pulumi/internal/testutil"
+)
+
+func loadConfig(t *testing.T, path string) *config.Config {
+	conf, err := config.LoadDir(path)
+	if err != nil {
+		t.Fatalf("could not load config at %s: %v", path, err)
+	}
+	return conf
+}
+
+func readFile(t *testing.T, path string) string {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read file %s: %v", path, err)
+	}
+	return string(bytes)
+}
+
+// testProviders serves the schemas of the AWS resources used by the test programs.
+var testProviders = testutil.ProviderInfoSource{
+	"aws": &tfbridge.ProviderInfo{
+		P: &schema.Provider{
+			ResourcesMap: map[string]*schema.Resource{
+				"aws_vpc": {
+					Schema: map[string]*schema.Schema{
+						"arn":        {Type: schema.TypeString, Computed: true},
+						"cidr_block": {Type: schema.TypeString, Required: true},
+					},
+				},
+				"aws_subnet": {
+					Schema: map[string]*schema.Schema{
+						"availability_zone": {Type: schema.TypeString, Optional: true},
+						"cidr_block":        {Type: schema.TypeString, Required: true},
+						"tags":              {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+						"vpc_id":            {Type: schema.TypeString, Required: true},
+					},
+				},
+			},
+		},
+		Resources: map[string]*tfbridge.ResourceInfo{
+			"aws_vpc":    {Tok: "aws:ec2/vpc:Vpc"},
+			"aws_subnet": {Tok: "aws:ec2/subnet:Subnet"},
+		},
+	},
+}
+
+func TestExpressions(t *testing.T) {
+	conf := loadConfig(t, "testdata/test_expressions")
+	g, err := il.BuildGraph(module.NewTree("main", conf), &il.BuildOptions{
+		ProviderInfoSource:   testProviders,
+		AllowMissingComments: true,
+	})
+	if err != nil {
+		t.Fatalf("could not build graph: %v", err)
+	}
+
+	var b bytes.Buffer
+	err = gen.Generate([]*il.Graph{g}, New("main", &b))
+	assert.NoError(t, err)
+
+	expectedText := readFile(t, "testdata/test_expressions/__main__.py")
+	assert.Equal(t, expectedText, b.String())
+}
@@ -15,9 +15,14 @@
 package python
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
+	"github.com/hashicorp/hil/ast"
+	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 
 	"github.com/pulumi/tf2pulumi/gen"
@@ -38,8 +43,55 @@ def tf2pulumi_nyi(reason):
 `
 )
 
+// GenArithmetic generates code for the given arithmetic expression. HIL's logical and comparison operators map onto
+// Python's `and`, `or`, `not`, and comparison operators.
 func (g *generator) GenArithmetic(w io.Writer, v *il.BoundArithmetic) {
-	g.genNYI(w, "arithmetic")
+	op := ""
+	switch v.Op {
+	case ast.ArithmeticOpAdd:
+		op = "+"
+	case ast.ArithmeticOpSub:
+		op = "-"
+	case ast.ArithmeticOpMul:
+		op = "*"
+	case ast.ArithmeticOpDiv:
+		op = "/"
+	case ast.ArithmeticOpMod:
+		op = "%"
+	case ast.ArithmeticOpLogicalAnd:
+		op = "and"
+	case ast.ArithmeticOpLogicalOr:
+		op = "or"
+	case ast.ArithmeticOpEqual:
+		op = "=="
+	case ast.ArithmeticOpNotEqual:
+		op = "!="
+	case ast.ArithmeticOpLessThan:
+		op = "<"
+	case ast.ArithmeticOpLessThanOrEqual:
+		op = "<="
+	case ast.ArithmeticOpGreaterThan:
+		op = ">"
+	case ast.ArithmeticOpGreaterThanOrEqual:
+		op = ">="
+	}
+	if len(v.Exprs) == 1 {
+		if v.Op == ast.ArithmeticOpEqual {
+			op = "not "
+		}
+		g.Fgenf(w, "(%s%v)", op, v.Exprs[0])
+		return
+	}
+	op = fmt.Sprintf(" %s ", op)
+
+	g.Fgen(w, "(")
+	for i, e := range v.Exprs {
+		if i != 0 {
+			g.Fgen(w, op)
+		}
+		g.Fgen(w, e)
+	}
+	g.Fgen(w, ")")
 }
 
 func (g *generator) GenCall(w io.Writer, v *il.BoundCall) {
@@ -50,6 +102,15 @@ func (g *generator) GenCall(w io.Writer, v *il.BoundCall) {
 		g.genResourceCall(w, v)
 	case il.IntrinsicApply:
 		g.genApply(w, v)
+	case il.IntrinsicApplyArg:
+		g.genApplyArg(w, il.ParseApplyArgCall(v))
+	case il.IntrinsicCoerce:
+		value, toType := il.ParseCoerceCall(v)
+		g.genCoercion(w, value, toType)
+	case il.IntrinsicGetProject:
+		g.Fgen(w, "pulumi.get_project()")
+	case il.IntrinsicGetStack:
+		g.Fgen(w, "pulumi.get_stack()")
 	default:
 		g.genNYI(w, "call")
 	}
@@ -86,16 +147,93 @@ func (g *generator) genResourceCall(w io.Writer, v *il.BoundCall) {
 	g.Fgen(w, ")")
 }
 
+// genApply generates code for a single `.apply` invocation as represented by a call to the `__apply` intrinsic. Python
+// lambdas cannot destructure their parameters, so if there are multiple outputs, the lambda accepts the list of
+// resolved values and indexes into it.
 func (g *generator) genApply(w io.Writer, v *il.BoundCall) {
-	g.genNYI(w, "nontrivial apply")
+	g.inApplyCall = true
+	defer func() { g.inApplyCall = false }()
+
+	applyArgs, then := il.ParseApplyCall(v)
+	g.applyArgs = applyArgs
+	defer func() { g.applyArgs = nil }()
+
+	if len(applyArgs) == 1 {
+		g.Fgenf(w, "%v.apply(lambda %s: %v)", applyArgs[0], g.applyArgName(0), then)
+		return
+	}
+
+	g.Fgen(w, "pulumi.Output.all(")
+	for i, arg := range applyArgs {
+		if i > 0 {
+			g.Fgen(w, ", ")
+		}
+		g.Fgen(w, arg)
+	}
+	g.Fgenf(w, ").apply(lambda args: %v)", then)
+}
+
+// applyArgName returns the name of the reference to the resolved value of the given apply argument.
+func (g *generator) applyArgName(index int) string {
+	if len(g.applyArgs) > 1 {
+		return fmt.Sprintf("args[%d]", index)
+	}
+
+	v := g.applyArgs[index]
+	if _, ok := v.TFVar.(*config.ResourceVariable); ok {
+		if len(v.Elements) == 0 || g.isDataSourceAccess(v) {
+			return g.nodeName(v.ILNode)
+		}
+		return pyName(tfbridge.TerraformToPulumiName(v.Elements[0], nil, nil, false))
+	}
+	return "arg"
+}
+
+// genApplyArg generates a single reference to a resolved output value inside the context of a call to `.apply`.
+func (g *generator) genApplyArg(w io.Writer, index int) {
+	contract.Assert(g.applyArgs != nil)
+
+	v := g.applyArgs[index]
+	g.Fgen(w, g.applyArgName(index))
+	if _, ok := v.TFVar.(*config.ResourceVariable); ok && len(v.Elements) > 0 {
+		if g.isDataSourceAccess(v) {
+			g.genAttribute(w, v)
+		}
+		g.genPropertyPath(w, v.Schemas.PropertySchemas(v.Elements[0]), v.Elements[1:])
+	}
+}
+
+// genCoercion generates code for a single call to the __coerce intrinsic that converts an expression between types.
+func (g *generator) genCoercion(w io.Writer, n il.BoundExpr, toType il.Type) {
+	switch n.Type() {
+	case il.TypeBool, il.TypeNumber:
+		if toType == il.TypeString {
+			g.Fgenf(w, "str(%v)", n)
+			return
+		}
+	case il.TypeString:
+		switch toType {
+		case il.TypeBool:
+			g.Fgenf(w, "(%v == \"true\")", n)
+			return
+		case il.TypeNumber:
+			g.Fgenf(w, "float(%v)", n)
+			return
+		}
+	}
+
+	// If we get here, we weren't able to generate a coercion. Just generate the node.
+	g.Fgen(w, n)
 }
 
+// GenConditional generates code for a single conditional expression.
 func (g *generator) GenConditional(w io.Writer, v *il.BoundConditional) {
-	g.genNYI(w, "conditionals")
+	g.Fgenf(w, "(%v if %v else %v)", v.TrueExpr, v.CondExpr, v.FalseExpr)
 }
 
+// GenIndex generates code for a single index expression.
 func (g *generator) GenIndex(w io.Writer, v *il.BoundIndex) {
-	g.genNYI(w, "index")
+	g.Fgenf(w, "%v[%v]", v.TargetExpr, v.KeyExpr)
 }
 
 func (g *generator) GenLiteral(w io.Writer, v *il.BoundLiteral) {
@@ -121,8 +259,52 @@ func (g *generator) GenLiteral(w io.Writer, v *il.BoundLiteral) {
 	}
 }
 
+// GenOutput generates code for a single interpolated string. Interpolated strings are generated as f-strings unless
+// an interpolated expression contains a quote or a backslash, neither of which may appear in the expressions of an
+// f-string. In that case, the string is generated as a concatenation instead.
 func (g *generator) GenOutput(w io.Writer, v *il.BoundOutput) {
-	g.genNYI(w, "outputs")
+	parts, useFString := make([]string, len(v.Exprs)), true
+	for i, e := range v.Exprs {
+		if lit, ok := e.(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
+			continue
+		}
+
+		var buf bytes.Buffer
+		g.Fgen(&buf, e)
+		parts[i] = buf.String()
+		useFString = useFString && !strings.ContainsAny(parts[i], "\"'\\\n")
+	}
+
+	if !useFString {
+		for i, e := range v.Exprs {
+			if i > 0 {
+				g.Fgen(w, " + ")
+			}
+			if lit, ok := e.(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
+				g.Fgen(w, strconv.Quote(lit.Value.(string)))
+			} else {
+				g.Fgenf(w, "str(%s)", parts[i])
+			}
+		}
+		return
+	}
+
+	g.Fgen(w, "f\"")
+	for i, e := range v.Exprs {
+		if lit, ok := e.(*il.BoundLiteral); ok && lit.ExprType == il.TypeString {
+			g.Fgen(w, escapeFStringLiteral(lit.Value.(string)))
+		} else {
+			g.Fgenf(w, "{%s}", parts[i])
+		}
+	}
+	g.Fgen(w, "\"")
+}
+
+// escapeFStringLiteral escapes the given string for inclusion in the literal portion of a double-quoted f-string.
+func escapeFStringLiteral(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = quoted[1 : len(quoted)-1]
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(quoted)
 }
 
 func (g *generator) GenVariableAccess(w io.Writer, v *il.BoundVariableAccess) {
@@ -133,13 +315,72 @@ func (g *generator) GenVariableAccess(w io.Writer, v *il.BoundVariableAccess) {
 			return
 		}
 
-		name := g.nodeName(v.ILNode)
-		g.Fgenf(w, "%s.%s", name, v.Elements[0])
+		// We only generate up to the "output" part of the path inside an apply: the apply transform will take care of
+		// the rest. Outside of an apply, Python outputs lift attribute and item accesses, so the entire path is
+		// generated.
+		//
+		// The outputs of a managed resource are attributes of the resource. A data source is a single output whose
+		// properties are attributes of its resolved value.
+		g.Fgen(w, g.nodeName(v.ILNode))
+		if len(v.Elements) == 0 || g.inApplyCall && g.isDataSourceAccess(v) {
+			return
+		}
+		g.genAttribute(w, v)
+		if !g.inApplyCall {
+			g.genPropertyPath(w, v.Schemas.PropertySchemas(v.Elements[0]), v.Elements[1:])
+		}
+	case *config.CountVariable:
+		g.Fgen(w, g.countIndex)
 	default:
 		g.genNYI(w, "variables")
 	}
 }
 
+// genAttribute generates the access to the top-level property of a resource or data source that is named by the first
+// element of the given resource variable access.
+func (g *generator) genAttribute(w io.Writer, v *il.BoundVariableAccess) {
+	sch := v.Schemas.PropertySchemas(v.Elements[0])
+	g.Fgenf(w, ".%s", pyName(tfbridge.TerraformToPulumiName(v.Elements[0], sch.TF, nil, false)))
+}
+
+// isDataSourceAccess returns true if the given resource variable access refers to a data source.
+func (g *generator) isDataSourceAccess(v *il.BoundVariableAccess) bool {
+	r, ok := v.ILNode.(*il.ResourceNode)
+	return ok && r.IsDataSource
+}
+
+// genPropertyPath generates the property accesses for the given path, which is relative to a property with the given
+// schemas. Nested properties are dictionary entries keyed by their Python names. Map keys are not renamed.
+func (g *generator) genPropertyPath(w io.Writer, sch il.Schemas, elements []string) {
+	for _, e := range elements {
+		isListElement, isMapElement := sch.Type().IsList(), sch.Type() == il.TypeMap
+		projectListElement := isListElement && tfbridge.IsMaxItemsOne(sch.TF, sch.Pulumi)
+
+		sch = sch.PropertySchemas(e)
+		switch {
+		case e == "*":
+			g.genNYI(w, "splats")
+			return
+		case isMapElement:
+			g.Fgenf(w, "[%q]", e)
+		case isListElement:
+			if !projectListElement {
+				g.Fgenf(w, "[%s]", e)
+			}
+		case isIndex(e):
+			g.Fgenf(w, "[%s]", e)
+		default:
+			g.Fgenf(w, "[%q]", pyName(tfbridge.TerraformToPulumiName(e, sch.TF, nil, false)))
+		}
+	}
+}
+
+// isIndex returns true if the given property path element is a list index.
+func isIndex(element string) bool {
+	_, err := strconv.ParseUint(element, 10, 0)
+	return err == nil
+}
+
 func (g *generator) GenListProperty(w io.Writer, v *il.BoundListProperty) {
 	g.Fgen(w, "[")
 	for i, prop := range v.Elements {
@@ -183,7 +424,7 @@ func (g *generator) genNYI(w io.Writer, reason string) {
 // genNYIHelper emits the NYI helper, if required.
 func (g *generator) genNYIHelper(w io.Writer) {
 	if g.needNYIHelper {
-		_, err := fmt.Fprintln(w, nyiHelper)
+		_, err := fmt.Fprint(w, nyiHelper, "\n")
 		contract.IgnoreError(err)
 	}
 }
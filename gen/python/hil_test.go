@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/hashicorp/hil/ast"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pulumi/tf2pulumi/gen"
@@ -77,3 +78,73 @@ func TestHilLiteralLowerNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestHilArithmeticOperators(t *testing.T) {
+	cases := []struct {
+		Op  ast.ArithmeticOp
+		Gen string
+	}{
+		{Op: ast.ArithmeticOpAdd, Gen: "(1 + 2)"},
+		{Op: ast.ArithmeticOpLogicalAnd, Gen: "(1 and 2)"},
+		{Op: ast.ArithmeticOpLogicalOr, Gen: "(1 or 2)"},
+		{Op: ast.ArithmeticOpEqual, Gen: "(1 == 2)"},
+		{Op: ast.ArithmeticOpNotEqual, Gen: "(1 != 2)"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Gen, func(t *testing.T) {
+			node := &il.BoundArithmetic{
+				Op: test.Op,
+				Exprs: []il.BoundExpr{
+					&il.BoundLiteral{ExprType: il.TypeNumber, Value: 1.0},
+					&il.BoundLiteral{ExprType: il.TypeNumber, Value: 2.0},
+				},
+				ExprType: il.TypeNumber,
+			}
+
+			out := runGen(node)
+			assert.Equal(t, test.Gen, out)
+		})
+	}
+}
+
+func TestHilUnaryOperators(t *testing.T) {
+	x := &il.BoundLiteral{ExprType: il.TypeNumber, Value: 1.0}
+	neg := runGen(&il.BoundArithmetic{Op: ast.ArithmeticOpSub, Exprs: []il.BoundExpr{x}, ExprType: il.TypeNumber})
+	assert.Equal(t, "(-1)", neg)
+
+	b := &il.BoundLiteral{ExprType: il.TypeBool, Value: true}
+	not := runGen(&il.BoundArithmetic{Op: ast.ArithmeticOpEqual, Exprs: []il.BoundExpr{b}, ExprType: il.TypeBool})
+	assert.Equal(t, "(not True)", not)
+}
+
+func TestHilOutput(t *testing.T) {
+	cond := &il.BoundConditional{
+		CondExpr:  &il.BoundLiteral{ExprType: il.TypeBool, Value: true},
+		TrueExpr:  &il.BoundLiteral{ExprType: il.TypeNumber, Value: 1.0},
+		FalseExpr: &il.BoundLiteral{ExprType: il.TypeNumber, Value: 2.0},
+		ExprType:  il.TypeNumber,
+	}
+	out := runGen(&il.BoundOutput{
+		Exprs: []il.BoundExpr{
+			&il.BoundLiteral{ExprType: il.TypeString, Value: "{n}: "},
+			cond,
+		},
+	})
+	assert.Equal(t, `f"{{n}}: {(1 if True else 2)}"`, out)
+
+	// Quotes may not appear in the expressions of an f-string, so this string is generated as a concatenation.
+	cond = &il.BoundConditional{
+		CondExpr:  &il.BoundLiteral{ExprType: il.TypeBool, Value: true},
+		TrueExpr:  &il.BoundLiteral{ExprType: il.TypeString, Value: "a"},
+		FalseExpr: &il.BoundLiteral{ExprType: il.TypeString, Value: "b"},
+		ExprType:  il.TypeString,
+	}
+	out = runGen(&il.BoundOutput{
+		Exprs: []il.BoundExpr{
+			&il.BoundLiteral{ExprType: il.TypeString, Value: "{n}: "},
+			cond,
+		},
+	})
+	assert.Equal(t, `"{n}: " + str(("a" if True else "b"))`, out)
+}
@@ -0,0 +1,5 @@
+import pulumi
+import pulumi_aws as aws
+
+main = aws.ec2.Vpc("main", cidr_block="10.0.0.0/16")
+public = aws.ec2.Subnet("public", availability_zone="us-west-2a", cidr_block=main.cidr_block.apply(lambda cidr_block: ("10.0.1.0/24" if (cidr_block == "10.0.0.0/16") else "10.1.1.0/24")), tags={"Both": pulumi.Output.all(main.id, main.arn).apply(lambda args: f"{args[0]}/{args[1]}"), "Name": main.id.apply(lambda id: f"subnet-{id}-{{public}}"), "Size": str(((2 * 3) + 1))}, vpc_id=main.id)
@@ -0,0 +1,15 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "public" {
+  vpc_id            = "${aws_vpc.main.id}"
+  cidr_block        = "${aws_vpc.main.cidr_block == "10.0.0.0/16" ? "10.0.1.0/24" : "10.1.1.0/24"}"
+  availability_zone = "us-west-2a"
+
+  tags = {
+    Name = "subnet-${aws_vpc.main.id}-{public}"
+    Size = "${2 * 3 + 1}"
+    Both = "${aws_vpc.main.id}/${aws_vpc.main.arn}"
+  }
+}
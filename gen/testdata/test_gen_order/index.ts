@@ -7,14 +7,14 @@ const vpcId = config.require("vpcId");
 // Originally defined at variables.tf:3
 const availabilityZone = config.require("availabilityZone");
 // Originally defined at variables.tf:13
-const regionNumbers = config.get("regionNumbers") || {
+const regionNumbers = config.getObject<Record<string, number>>("regionNumbers") ?? {
     "eu-west-1": 4,
     "us-east-1": 1,
     "us-west-1": 2,
     "us-west-2": 3,
 };
 // Originally defined at variables.tf:22
-const azNumbers = config.get("azNumbers") || {
+const azNumbers = config.getObject<Record<string, number>>("azNumbers") ?? {
     a: 1,
     b: 2,
     c: 3,
@@ -15,9 +15,13 @@
 package il
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/tf2pulumi/internal/config"
@@ -25,11 +29,33 @@ import (
 
 // bindArithmetic binds an HIL arithmetic expression.
 func (b *propertyBinder) bindArithmetic(n *ast.Arithmetic) (BoundExpr, error) {
-	exprs, err := b.bindExprs(n.Exprs)
+	// HIL represents negation (e.g. `-a`) as a subtraction from zero and logical negation (e.g. `!a`) as a comparison
+	// with false. Bind these as unary expressions. Because HIL converts the operands of these operators to the type of
+	// the first operand, a subtraction from zero or comparison with false that was written explicitly has the same
+	// semantics as its unary form, so there is no need to distinguish the two.
+	operands := n.Exprs
+	if len(operands) == 2 {
+		if lit, ok := operands[0].(*ast.LiteralNode); ok {
+			switch {
+			case n.Op == ast.ArithmeticOpSub && lit.Typex == ast.TypeInt && lit.Value == 0,
+				n.Op == ast.ArithmeticOpEqual && lit.Typex == ast.TypeBool && lit.Value == false:
+				operands = operands[1:]
+			}
+		}
+	}
+
+	exprs, err := b.bindExprs(operands)
 	if err != nil {
 		return nil, err
 	}
 
+	// Fold the negation of a numeric literal into a negative literal.
+	if len(exprs) == 1 && n.Op == ast.ArithmeticOpSub {
+		if lit, ok := exprs[0].(*BoundLiteral); ok && lit.ExprType == TypeNumber {
+			return &BoundLiteral{ExprType: TypeNumber, Value: -lit.Value.(float64)}, nil
+		}
+	}
+
 	var typ Type
 	switch n.Op {
 	case ast.ArithmeticOpLogicalAnd, ast.ArithmeticOpLogicalOr,
@@ -45,84 +71,135 @@ func (b *propertyBinder) bindArithmetic(n *ast.Arithmetic) (BoundExpr, error) {
 }
 
 // bindCall binds an HIL call expression. This involves binding the call's arguments, then using the name of the called
-// function to determine the type of the call expression. The binder curretly only supports a subset of the functions
-// supported by terraform.
+// function to determine the type of the call expression. Functions that have been registered with RegisterFunction are
+// bound by their registered binders. The binder curretly only supports a subset of the functions supported by
+// terraform.
 func (b *propertyBinder) bindCall(n *ast.Call) (BoundExpr, error) {
-	args, err := b.bindExprs(n.Args)
+	// HIL parses the spread form of a call's final argument (e.g. `max(data.aws_x.d.sizes...)`) as an access to a
+	// variable whose name ends in "...". Strip the ellipsis and mark the call as expanding its final argument. Note
+	// that the config loader only accepts spreads of resource, data source, and module attributes: it rejects the
+	// dotted names of spread variables and locals.
+	argNodes, expandFinal := n.Args, false
+	if len(argNodes) > 0 {
+		if v, ok := argNodes[len(argNodes)-1].(*ast.VariableAccess); ok && strings.HasSuffix(v.Name, "...") {
+			argNodes = append(append([]ast.Node(nil), argNodes[:len(argNodes)-1]...), &ast.VariableAccess{
+				Name: strings.TrimSuffix(v.Name, "..."),
+				Posx: v.Posx,
+			})
+			expandFinal = true
+		}
+	}
+
+	args, err := b.bindExprs(argNodes)
 	if err != nil {
 		return nil, err
 	}
 
+	// A list or map local is generated as a plain value even if its elements are outputs, so its length is known.
+	if n.Func == "length" && len(args) == 1 {
+		if v, ok := args[0].(*BoundVariableAccess); ok && isCollectionLocal(v) {
+			v.ExprType = v.ExprType & ^TypeOutput
+		}
+	}
+
+	var exprType Type
+	if binder, ok := functions[n.Func]; ok {
+		exprType, err = binder(args)
+	} else {
+		exprType, err = bindBuiltinCall(n.Func, args)
+	}
+
+	boundCall := &BoundCall{Func: n.Func, ExprType: exprType, Args: args, ExpandFinal: expandFinal}
+	if err != nil {
+		return &BoundError{Value: boundCall, NodeType: exprType, Error: err}, nil
+	}
+	return boundCall, nil
+}
+
+// isCollectionLocal returns true if the given variable access refers to a local whose value is a list or map.
+func isCollectionLocal(v *BoundVariableAccess) bool {
+	if l, ok := v.ILNode.(*LocalNode); ok && len(v.Elements) == 0 {
+		switch l.Value.(type) {
+		case *BoundListProperty, *BoundMapProperty:
+			return true
+		}
+	}
+	return false
+}
+
+// bindBuiltinCall computes the type of a call to an interpolation function that is not present in the function
+// registry. If the function is unknown or the arguments are invalid, bindBuiltinCall returns an error.
+func bindBuiltinCall(name string, args []BoundExpr) (Type, error) {
+	var err error
 	exprType := TypeUnknown
-	switch n.Func {
+	switch name {
+	case "abs":
+		exprType = TypeNumber
 	case "base64decode":
 		exprType = TypeString
 	case "base64encode":
 		exprType = TypeString
-	case "chomp":
-		exprType = TypeString
-	case "cidrhost":
-		exprType = TypeString
-	case "coalesce":
-		exprType = TypeString
-	case "coalescelist", "concat":
-		if args[0].Type().IsList() {
-			exprType = args[0].Type()
-		} else {
-			exprType = TypeUnknown.ListOf()
-		}
+	case "ceil":
+		exprType = TypeNumber
 	case "compact":
 		exprType = TypeString.ListOf()
-	case "element":
-		if args[0].Type().IsList() {
-			exprType = args[0].Type().ElementType()
-		}
-	case "file":
-		exprType = TypeString
-	case "format":
+	case "floor":
+		exprType = TypeNumber
+	case "format", "formatlist":
 		exprType = TypeString
-	case "formatlist":
-		exprType = TypeString.ListOf()
+		if name == "formatlist" {
+			exprType = TypeString.ListOf()
+		}
+
+		// If the format string is a literal, check that its verbs are supported now rather than at runtime.
+		if lit, ok := args[0].(*BoundLiteral); ok && lit.ExprType == TypeString {
+			if _, ferr := ParseFormat(lit.Value.(string)); ferr != nil {
+				err = errors.Wrapf(ferr, "invalid format string for %s", name)
+			}
+		}
 	case "indent":
 		exprType = TypeString
 	case "join":
 		exprType = TypeString
-	case "length":
-		exprType = TypeNumber
 	case "list":
 		exprType = TypeUnknown.ListOf()
-	case "lookup":
-		// nothing to do
-	case "lower":
-		exprType = TypeString
 	case "map":
 		if len(args)%2 != 0 {
 			err = errors.Errorf("the number of arguments to \"map\" must be even")
 		}
 		exprType = TypeMap
-	case "merge":
-		exprType = TypeMap
-	case "min":
+	case "max", "min":
 		exprType = TypeNumber
-	case "replace":
-		exprType = TypeString
+	case "regexall":
+		// If the pattern has capture groups, each match is a list of the captured substrings. Otherwise, each match is
+		// the matched substring.
+		exprType = TypeUnknown.ListOf()
+		if lit, ok := args[0].(*BoundLiteral); ok && lit.ExprType == TypeString {
+			re, rerr := regexp.Compile(lit.Value.(string))
+			switch {
+			case rerr != nil:
+				err = errors.Wrapf(rerr, "invalid pattern for regexall")
+			case re.NumSubexp() == 0:
+				exprType = TypeString.ListOf()
+			}
+		}
 	case "signum":
 		exprType = TypeNumber
-	case "split":
-		exprType = TypeString.ListOf()
-	case "substr":
-		exprType = TypeString
-	case "zipmap":
-		exprType = TypeMap
+	case "timecmp":
+		// As in Terraform, literal timestamps must be valid RFC 3339 timestamps.
+		exprType = TypeNumber
+		for _, arg := range args {
+			if lit, ok := arg.(*BoundLiteral); ok && lit.ExprType == TypeString {
+				if _, terr := time.Parse(time.RFC3339, lit.Value.(string)); terr != nil {
+					err = errors.Errorf("not a valid RFC3339 timestamp: %q", lit.Value)
+					break
+				}
+			}
+		}
 	default:
-		err = errors.Errorf("NYI: call to %s", n.Func)
-	}
-
-	boundCall := &BoundCall{Func: n.Func, ExprType: exprType, Args: args}
-	if err != nil {
-		return &BoundError{Value: boundCall, NodeType: exprType, Error: err}, nil
+		err = errors.Errorf("NYI: call to %s", name)
 	}
-	return boundCall, nil
+	return exprType, err
 }
 
 // bindConditional binds an HIL conditional expression.
@@ -140,11 +217,16 @@ func (b *propertyBinder) bindConditional(n *ast.Conditional) (BoundExpr, error)
 		return nil, err
 	}
 
-	// If the types of both branches match, then the type of the expression is that of the branches. If the types of
-	// both branches differ, then mark the type as unknown.
-	exprType := trueExpr.Type()
-	if exprType != falseExpr.Type() {
-		exprType = TypeUnknown
+	// If the types of both branches match once any outputs are ignored, then the type of the expression is that of the
+	// branches. If the condition or either branch is an output, then so is the expression. If the types of both branches
+	// differ, then mark the type as unknown.
+	exprType := TypeUnknown
+	trueType, falseType := trueExpr.Type(), falseExpr.Type()
+	if trueType&^TypeOutput == falseType&^TypeOutput {
+		exprType = trueType &^ TypeOutput
+		if trueType.IsOutput() || falseType.IsOutput() || containsOutputs(condExpr) {
+			exprType = exprType.OutputOf()
+		}
 	}
 
 	return &BoundConditional{
@@ -166,12 +248,19 @@ func (b *propertyBinder) bindIndex(n *ast.Index) (BoundExpr, error) {
 		return nil, err
 	}
 
-	// If the target type is not a list, then the type of the expression is unknown. Otherwise it is the element type
-	// of the list.
+	// If the target type is a list, then the type of the expression is the element type of the list. If the target type
+	// is a map, then the type of the expression is the type of the map's elements, if known. Otherwise the type of the
+	// expression is unknown. Because the elements of an output-typed collection are not known until the collection is
+	// resolved, indexing such a collection produces an output.
 	exprType := TypeUnknown
 	targetType := boundTarget.Type()
 	if targetType.IsList() {
 		exprType = targetType.ElementType()
+	} else if targetType.ElementType() == TypeMap {
+		exprType = mapElementType(boundTarget)
+	}
+	if targetType.IsOutput() {
+		exprType = exprType.OutputOf()
 	}
 
 	return &BoundIndex{
@@ -208,14 +297,29 @@ func (b *propertyBinder) bindOutput(n *ast.Output) (BoundExpr, error) {
 		return nil, err
 	}
 
-	// Project a single-element output to the element itself.
+	// Project a single-element output to the element itself. Terraform converts the result of every interpolation to a
+	// string, so the output is preserved if it is a lone reference to the count index (e.g. "${count.index}"), which is
+	// typically interpolated into a string field such as a name. makeCoercion unwraps such outputs when the index is
+	// used in a context that expects a number.
 	if len(exprs) == 1 {
-		return exprs[0], nil
+		if !isCountIndex(exprs[0]) {
+			return exprs[0], nil
+		}
 	}
 
 	return &BoundOutput{Exprs: exprs}, nil
 }
 
+// isCountIndex returns true if the given expression is a reference to the count index.
+func isCountIndex(e BoundExpr) bool {
+	v, ok := e.(*BoundVariableAccess)
+	if !ok {
+		return false
+	}
+	_, ok = v.TFVar.(*config.CountVariable)
+	return ok
+}
+
 // bindVariableAccess binds an HIL variable access expression. This involves first interpreting the variable name as a
 // Terraform interpolated variable, then using the result of that interpretation to decide which graph node the
 // variable access refers to, if any: count, path, and Terraformn variables may not refer to graph nodes. It is an
@@ -226,7 +330,7 @@ func (b *propertyBinder) bindVariableAccess(n *ast.VariableAccess) (BoundExpr, e
 		return nil, err
 	}
 
-	elements, sch, exprType, ilNode := []string(nil), Schemas{}, TypeUnknown, Node(nil)
+	elements, sch, exprType, ilNode, accessErr := []string(nil), Schemas{}, TypeUnknown, Node(nil), error(nil)
 	switch v := tfVar.(type) {
 	case *config.CountVariable:
 		// "count."
@@ -258,7 +362,14 @@ func (b *propertyBinder) bindVariableAccess(n *ast.VariableAccess) (BoundExpr, e
 			return nil, err
 		}
 
+		// A local's value may be an interpolation or other expression that depends on outputs without itself being
+		// output-typed. Accesses to such a local must still be treated as outputs. The same is true of lists and maps
+		// whose elements are outputs: these are generated as plain values, but their elements must be resolved before
+		// the value as a whole can be used.
 		exprType = l.Value.Type()
+		if containsOutputs(l.Value) {
+			exprType = exprType.OutputOf()
+		}
 	case *config.ModuleVariable:
 		// "module."
 		m, ok := b.builder.modules[v.Name]
@@ -273,13 +384,34 @@ func (b *propertyBinder) bindVariableAccess(n *ast.VariableAccess) (BoundExpr, e
 		}
 		ilNode = m
 
+		// If the child module's graph is available, use the type of the referenced output. Otherwise, the type of the
+		// output is unknown.
 		exprType = TypeUnknown.OutputOf()
+		if child, ok := b.builder.children[v.Name]; ok {
+			o, ok := child.Outputs[v.Field]
+			if !ok {
+				if !b.builder.allowMissingVariables {
+					return nil, errors.Errorf("unknown output %v of module %v", v.Field, v.Name)
+				}
+			} else if o.Value != nil {
+				exprType = o.Value.Type().OutputOf()
+			}
+		}
 	case *config.PathVariable:
 		// "path."
+		if v.Type == config.PathValueInvalid {
+			return nil, errors.Errorf("unsupported key '%s'", v.FullKey())
+		}
 		exprType = TypeString
 	case *config.ResourceVariable:
 		// default
 
+		// References to provider attributes (e.g. "provider.aws.region") are parsed as references to resources of
+		// type "provider".
+		if v.Mode == config.ManagedResourceMode && v.Type == "provider" {
+			return b.bindProviderAttribute(v)
+		}
+
 		// Split the path elements.
 		elements = strings.Split(v.Field, ".")
 
@@ -327,28 +459,69 @@ func (b *propertyBinder) bindVariableAccess(n *ast.VariableAccess) (BoundExpr, e
 			v.Multi, v.Index = true, 0
 		}
 
+		// If this access explicitly indexes a resource whose count is known, ensure that the index is in range.
+		if v.Multi && v.Index != -1 {
+			count, isKnown := 1, r.Count == nil
+			if lit, ok := r.Count.(*BoundLiteral); ok && lit.ExprType == TypeNumber {
+				count, isKnown = int(lit.Value.(float64)), true
+			}
+			if isKnown && v.Index >= count {
+				accessErr = errors.Errorf("index %d is out of range for %v, which has a count of %d",
+					v.Index, v.ResourceId(), count)
+			}
+		}
+
 		// If this access refers to a non-counted resource but is a multi-access or an index, treat it as if it is
 		// a normal access.
 		if r.Count == nil && v.Multi {
 			v.Multi = false
 		}
 
-		// Handle multi-references (splats and indexes).
+		// Handle multi-references (splats and indexes). Splats over nested blocks (e.g. `network_interface.*.id`) also
+		// produce lists. Terraform flattens the lists produced by nested splats, so these accesses are typed as flat
+		// lists of the accessed property's element type. Splats over list-typed properties produce lists of lists.
 		exprType = elemSch.Type().OutputOf()
-		if v.Multi && v.Index == -1 {
+		switch {
+		case hasNestedSplat(elements):
+			if !exprType.IsList() {
+				exprType = exprType.ListOf()
+			}
+		case v.Multi && v.Index == -1:
 			exprType = exprType.ListOf()
 		}
 	case *config.SelfVariable:
 		// "self."
-		return nil, errors.New("NYI: self variables")
+		//
+		// Self-references are only meaningful within a resource, and are typed using the resource's schema. Terraform
+		// only allows them in provisioner and connection blocks, which are not converted. A resource's inputs cannot
+		// depend on its own outputs, so self-references are bound as errors. Note that the access does not refer to a
+		// node: doing so would make the resource depend on itself.
+		elements, exprType = strings.Split(v.Field, "."), TypeUnknown
+		if b.self == nil {
+			accessErr = errors.Errorf("self-references are only valid within a resource (%v)", v.FullKey())
+			break
+		}
+
+		sch = b.self.Schemas()
+		elemSch := sch
+		for _, e := range elements {
+			elemSch = elemSch.PropertySchemas(e)
+		}
+		exprType = elemSch.Type()
+		accessErr = errors.Errorf("self-references are not supported: a resource's inputs cannot depend on its "+
+			"own outputs (%v)", v.FullKey())
 	case *config.SimpleVariable:
 		// "[^.]\+"
 		return nil, errors.New("NYI: simple variables")
 	case *config.TerraformVariable:
-		if v.Field != "workspace" {
+		switch intrinsic, _ := TerraformVariableIntrinsic(v.Field); intrinsic {
+		case IntrinsicGetProject:
+			return NewGetProjectCall(), nil
+		case IntrinsicGetStack:
+			return NewGetStackCall(), nil
+		default:
 			return nil, errors.Errorf("unsupported key 'terraform.%s'", v.Field)
 		}
-		return NewGetStackCall(), nil
 	case *config.UserVariable:
 		// "var."
 		if v.Elem != "" {
@@ -366,25 +539,134 @@ func (b *propertyBinder) bindVariableAccess(n *ast.VariableAccess) (BoundExpr, e
 			}
 			return nil, errors.Errorf("unknown variable %s", v.Name)
 		}
-		ilNode = vn
-
-		// If the variable does not have a default, its type is string. If it does have a default, its type is the type
-		// of the default.
-		exprType = TypeString
-		if vn.DefaultValue != nil {
-			exprType = vn.DefaultValue.Type()
-		}
+		ilNode, exprType = vn, vn.Type()
 	default:
 		return nil, errors.Errorf("unexpected variable type %T", v)
 	}
 
-	return &BoundVariableAccess{
+	access := &BoundVariableAccess{
 		Elements: elements,
 		Schemas:  sch,
 		ExprType: exprType,
 		TFVar:    tfVar,
 		ILNode:   ilNode,
-	}, nil
+	}
+	if accessErr != nil {
+		return &BoundError{Value: access, NodeType: exprType, Error: accessErr}, nil
+	}
+	return access, nil
+}
+
+// bindProviderAttribute binds a reference to an attribute of a provider configuration, e.g. "provider.aws.region" or
+// "provider.aws.west.region" for a provider with an alias. Pulumi providers do not expose their configuration, so
+// such a reference is only supported if the attribute is set to a literal value in the provider's configuration, in
+// which case the reference is replaced by that value. Any other reference is bound as an error.
+func (b *propertyBinder) bindProviderAttribute(v *config.ResourceVariable) (BoundExpr, error) {
+	elements := strings.Split(v.Field, ".")
+
+	p, ok := b.builder.providers[v.Name]
+	if len(elements) > 1 {
+		if aliased, hasAlias := b.builder.providers[v.Name+"."+elements[0]]; hasAlias {
+			p, ok, elements = aliased, true, elements[1:]
+		}
+	}
+
+	var err error
+	switch {
+	case !ok:
+		err = errors.Errorf("unknown provider %v", v.Name)
+	case len(elements) != 1:
+		err = errors.Errorf("unsupported reference to nested provider attribute %v", v.FullKey())
+	default:
+		if err = b.builder.ensureBound(p); err != nil {
+			return nil, err
+		}
+		if lit, isLiteral := p.Properties.Elements[elements[0]].(*BoundLiteral); isLiteral {
+			return &BoundLiteral{ExprType: lit.ExprType, Value: lit.Value}, nil
+		}
+		err = errors.Errorf("provider attribute %v must be set to a literal value in the provider's configuration",
+			v.FullKey())
+	}
+
+	access := &BoundVariableAccess{
+		Elements: elements,
+		ExprType: TypeUnknown,
+		TFVar:    v,
+	}
+	return &BoundError{Value: access, NodeType: TypeUnknown, Error: err}, nil
+}
+
+// hasNestedSplat returns true if the given property path contains a splat over a nested block, e.g. the path
+// `network_interface.*.id`.
+func hasNestedSplat(elements []string) bool {
+	for _, e := range elements {
+		if e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// mapElementType returns the type of the elements of the given map-typed expression if that type is known and
+// TypeUnknown otherwise. The element type is known if the expression accesses a map-typed property with a primitive
+// element schema, a variable whose default value is a map with elements of a single primitive type, or if the
+// expression is a call to `zipmap` whose list of values has a known element type.
+func mapElementType(e BoundExpr) Type {
+	if call, ok := e.(*BoundCall); ok && call.Func == "zipmap" {
+		if valuesType := call.Args[1].Type(); valuesType.IsList() {
+			return valuesType.ElementType()
+		}
+		return TypeUnknown
+	}
+
+	v, ok := e.(*BoundVariableAccess)
+	if !ok {
+		return TypeUnknown
+	}
+
+	switch n := v.ILNode.(type) {
+	case *ResourceNode:
+		sch := v.Schemas
+		for _, e := range v.Elements {
+			sch = sch.PropertySchemas(e)
+		}
+		if sch.TF == nil || sch.TF.Type != schema.TypeMap {
+			return TypeUnknown
+		}
+		return sch.ElemSchemas().Type()
+	case *VariableNode:
+		m, ok := n.DefaultValue.(*BoundMapProperty)
+		if !ok {
+			return TypeUnknown
+		}
+		elements := make([]BoundNode, 0, len(m.Elements))
+		for _, e := range m.Elements {
+			elements = append(elements, e)
+		}
+		return commonElementType(elements)
+	default:
+		return TypeUnknown
+	}
+}
+
+// commonElementType returns the primitive or list type shared by all of the given elements, or TypeUnknown if there is
+// no such type. The element types of list properties are computed from their elements.
+func commonElementType(elements []BoundNode) Type {
+	elemType := TypeUnknown
+	for _, e := range elements {
+		t := e.Type()
+		if l, ok := e.(*BoundListProperty); ok {
+			t = commonElementType(l.Elements).ListOf()
+		}
+		switch {
+		case t != TypeBool && t != TypeNumber && t != TypeString && (!t.IsList() || t.IsOutput()):
+			return TypeUnknown
+		case elemType != TypeUnknown && t != elemType:
+			return TypeUnknown
+		}
+		elemType = t
+	}
+	return elemType
 }
 
 // bindExprs binds the list of HIL expressions and returns the resulting list.
@@ -400,24 +682,65 @@ func (b *propertyBinder) bindExprs(ns []ast.Node) ([]BoundExpr, error) {
 	return boundExprs, nil
 }
 
-// bindExpr binds a single HIL expression.
+// bindExpr binds a single HIL expression. Errors that occur while binding the expression, including those that are
+// attached to the bound expression, are annotated with the position of the offending node. If the binder is not
+// strict, an expression that fails to bind is bound as an error node rather than failing the binding.
 func (b *propertyBinder) bindExpr(n ast.Node) (BoundExpr, error) {
+	var expr BoundExpr
+	var err error
 	switch n := n.(type) {
 	case *ast.Arithmetic:
-		return b.bindArithmetic(n)
+		expr, err = b.bindArithmetic(n)
 	case *ast.Call:
-		return b.bindCall(n)
+		expr, err = b.bindCall(n)
 	case *ast.Conditional:
-		return b.bindConditional(n)
+		expr, err = b.bindConditional(n)
 	case *ast.Index:
-		return b.bindIndex(n)
+		expr, err = b.bindIndex(n)
 	case *ast.LiteralNode:
-		return b.bindLiteral(n)
+		expr, err = b.bindLiteral(n)
 	case *ast.Output:
-		return b.bindOutput(n)
+		expr, err = b.bindOutput(n)
 	case *ast.VariableAccess:
-		return b.bindVariableAccess(n)
+		expr, err = b.bindVariableAccess(n)
 	default:
-		return nil, errors.Errorf("unexpected HIL node type %T", n)
+		err = errors.Errorf("unexpected HIL node type %T", n)
+	}
+	if err != nil {
+		// If the binder is not strict, bind the expression as an error so that binding can continue.
+		if !b.strict {
+			return &BoundError{NodeType: TypeUnknown, Error: errorAt(n, err)}, nil
+		}
+		return nil, errorAt(n, err)
+	}
+	if boundErr, ok := expr.(*BoundError); ok {
+		boundErr.Error = errorAt(n, boundErr.Error)
+	}
+	return expr, nil
+}
+
+// hilError is an error that occurred while binding the HIL node at a particular position.
+type hilError struct {
+	pos ast.Pos
+	err error
+}
+
+// Error returns the error's message prefixed with the position of the node that caused the error, e.g. "1:3: NYI: call
+// to foo".
+func (e *hilError) Error() string {
+	return fmt.Sprintf("%v: %v", e.pos, e.err)
+}
+
+// Cause returns the underlying error.
+func (e *hilError) Cause() error {
+	return e.err
+}
+
+// errorAt annotates the given error with the position of the given node. If the error has already been annotated
+// with the position of a nested node, it is returned as-is.
+func errorAt(n ast.Node, err error) error {
+	if _, ok := err.(*hilError); ok {
+		return err
 	}
+	return &hilError{pos: n.Pos(), err: err}
 }
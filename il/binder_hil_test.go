@@ -0,0 +1,628 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/tf2pulumi/internal/config"
+	"github.com/pulumi/tf2pulumi/internal/config/module"
+	"github.com/pulumi/tf2pulumi/internal/testutil"
+)
+
+// bindHIL parses and binds the given HIL expression. The expression must not reference any variables.
+func bindHIL(t *testing.T, expr string) BoundExpr {
+	rootNode, err := hil.Parse(expr)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", expr, err)
+	}
+
+	b := &propertyBinder{builder: newBuilder(&BuildOptions{})}
+	bound, err := b.bindExpr(rootNode)
+	if err != nil {
+		t.Fatalf("could not bind %q: %v", expr, err)
+	}
+	return bound
+}
+
+func TestBindCIDRFunctions(t *testing.T) {
+	netmask := bindHIL(t, `${cidrnetmask("10.0.0.0/16")}`)
+	assert.IsType(t, &BoundCall{}, netmask)
+	assert.Equal(t, TypeString, netmask.Type())
+
+	nested := bindHIL(t, `${cidrnetmask(cidrsubnet("10.0.0.0/16", 8, 2))}`)
+	assert.IsType(t, &BoundCall{}, nested)
+	assert.Equal(t, TypeString, nested.Type())
+	assert.Equal(t, TypeString, nested.(*BoundCall).Args[0].Type())
+
+	ipv6 := bindHIL(t, `${cidrnetmask("fd00::/8")}`)
+	if assert.IsType(t, &BoundError{}, ipv6) {
+		assert.Equal(t, TypeString, ipv6.Type())
+		assert.Contains(t, ipv6.(*BoundError).Error.Error(), "only supports IPv4 prefixes")
+	}
+}
+
+func TestBindRegexall(t *testing.T) {
+	matches := bindHIL(t, `${regexall("[a-z]+", "foo bar")}`)
+	assert.IsType(t, &BoundCall{}, matches)
+	assert.Equal(t, TypeString.ListOf(), matches.Type())
+
+	groups := bindHIL(t, `${regexall("([a-z])([0-9])", "a1 b2")}`)
+	assert.Equal(t, TypeUnknown.ListOf(), groups.Type())
+
+	length := bindHIL(t, `${length(regexall("[a-z]+", "foo bar"))}`)
+	assert.Equal(t, TypeNumber, length.Type())
+
+	element := bindHIL(t, `${element(regexall("[a-z]+", "foo bar"), 1)}`)
+	assert.Equal(t, TypeString, element.Type())
+
+	join := bindHIL(t, `${join(",", regexall("[a-z]+", "foo bar"))}`)
+	assert.Equal(t, TypeString, join.Type())
+
+	invalid := bindHIL(t, `${regexall("[a-z", "foo bar")}`)
+	if assert.IsType(t, &BoundError{}, invalid) {
+		assert.Contains(t, invalid.(*BoundError).Error.Error(), "invalid pattern for regexall")
+	}
+}
+
+func TestBindExpandFinal(t *testing.T) {
+	rootNode, err := hil.Parse(`${max(1, var.sizes...)}`)
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	b := &propertyBinder{builder: newBuilder(&BuildOptions{AllowMissingVariables: true})}
+	bound, err := b.bindExpr(rootNode)
+	if err != nil {
+		t.Fatalf("could not bind: %v", err)
+	}
+	if assert.IsType(t, &BoundCall{}, bound) {
+		call := bound.(*BoundCall)
+		assert.Equal(t, TypeNumber, call.Type())
+		assert.True(t, call.ExpandFinal)
+		if assert.Len(t, call.Args, 2) && assert.IsType(t, &BoundVariableAccess{}, call.Args[1]) {
+			assert.Equal(t, "var.sizes", call.Args[1].(*BoundVariableAccess).TFVar.FullKey())
+		}
+	}
+
+	plain := bindHIL(t, `${max(1, 2)}`)
+	if assert.IsType(t, &BoundCall{}, plain) {
+		assert.False(t, plain.(*BoundCall).ExpandFinal)
+	}
+}
+
+func TestBindSelf(t *testing.T) {
+	// Outside of a resource, self-references are always errors.
+	self := bindHIL(t, `${self.private_ip}`)
+	if assert.IsType(t, &BoundError{}, self) {
+		err := self.(*BoundError)
+		assert.Contains(t, err.Error.Error(), "self-references are only valid within a resource")
+	}
+
+	// Within a resource, self-references are typed using the resource's schema, but are still errors.
+	const source = `
+resource "archive_file" "dist" {
+  type        = "zip"
+  output_path = "dist-${self.output_size}.zip"
+}
+`
+	g := buildSource(t, source)
+	path := g.Resources["archive_file.dist"].Properties.Elements["output_path"].(*BoundOutput)
+	self = path.Exprs[1]
+	if assert.IsType(t, &BoundError{}, self) {
+		err := self.(*BoundError)
+		assert.Contains(t, err.Error.Error(), "self-references are not supported")
+		assert.Equal(t, TypeNumber, err.Type())
+
+		// The access must not refer to a node, as that would make the resource depend on itself.
+		if assert.IsType(t, &BoundVariableAccess{}, err.Value) {
+			access := err.Value.(*BoundVariableAccess)
+			assert.Nil(t, access.ILNode)
+			assert.Equal(t, []string{"output_size"}, access.Elements)
+		}
+	}
+}
+
+func TestBindArithmeticTypes(t *testing.T) {
+	const source = `
+variable "x" {
+  default = 1
+}
+
+resource "aws_instance" "web" {
+  tags {
+    Size    = "${var.x > 5 ? "a" : "b"}"
+    Sum     = "${var.x + 5}"
+    Enabled = "${var.x == 1 || var.x != 2}"
+  }
+}
+`
+	g := buildSource(t, source)
+	tags := g.Resources["aws_instance.web"].Properties.Elements["tags"].(*BoundListProperty).Elements[0]
+	elements := tags.(*BoundMapProperty).Elements
+
+	// Comparison and logical operators produce booleans; other arithmetic operators produce numbers.
+	size := elements["Size"].(*BoundConditional)
+	assert.Equal(t, TypeBool, size.CondExpr.Type())
+	assert.Equal(t, TypeString, size.Type())
+	assert.Equal(t, TypeNumber, elements["Sum"].(*BoundArithmetic).Type())
+	assert.Equal(t, TypeBool, elements["Enabled"].(*BoundArithmetic).Type())
+}
+
+func TestBindPathVariables(t *testing.T) {
+	for _, key := range []string{"cwd", "module", "root"} {
+		access := bindHIL(t, "${path."+key+"}")
+		if assert.IsType(t, &BoundVariableAccess{}, access) {
+			assert.Equal(t, TypeString, access.Type())
+		}
+	}
+
+	// Unrecognized keys are errors.
+	rootNode, err := hil.Parse("${path.bogus}")
+	if err != nil {
+		t.Fatalf("could not parse expression: %v", err)
+	}
+	b := &propertyBinder{builder: newBuilder(&BuildOptions{}), strict: true}
+	_, err = b.bindExpr(rootNode)
+	assert.EqualError(t, err, "1:3: unsupported key 'path.bogus'")
+}
+
+func TestBindIndexTypes(t *testing.T) {
+	const source = `
+variable "zones" {
+  default = ["a", "b"]
+}
+
+variable "sizes" {
+  default = {
+    small = 1
+    large = 2
+  }
+}
+
+variable "mixed" {
+  default = {
+    name = "x"
+    size = 1
+  }
+}
+
+resource "aws_instance" "web" {
+  tags {
+    Zone  = "${var.zones[0]}"
+    Size  = "${var.sizes["small"]}"
+    Mixed = "${var.mixed["size"]}"
+  }
+}
+`
+	g := buildSource(t, source)
+	tags := g.Resources["aws_instance.web"].Properties.Elements["tags"].(*BoundListProperty).Elements[0]
+	elements := tags.(*BoundMapProperty).Elements
+
+	// Indexing a list produces its element type. Indexing a map produces the type of its elements if all of its
+	// elements share a single primitive type.
+	assert.Equal(t, TypeString, elements["Zone"].(*BoundIndex).Type())
+	assert.Equal(t, TypeNumber, elements["Size"].(*BoundIndex).Type())
+	assert.Equal(t, TypeUnknown, elements["Mixed"].(*BoundIndex).Type())
+}
+
+func TestRegisterFunction(t *testing.T) {
+	t.Cleanup(RegisterFunction("shout", func(args []BoundExpr) (Type, error) {
+		if len(args) != 1 {
+			return TypeString, errors.New("shout requires exactly one argument")
+		}
+		return TypeString, nil
+	}))
+
+	call := bindHIL(t, `${shout("hello")}`)
+	if assert.IsType(t, &BoundCall{}, call) {
+		assert.Equal(t, TypeString, call.Type())
+	}
+
+	// Errors reported by registered binders are attached to the call.
+	call = bindHIL(t, `${shout("hello", "world")}`)
+	if assert.IsType(t, &BoundError{}, call) {
+		assert.EqualError(t, call.(*BoundError).Error, "1:3: shout requires exactly one argument")
+	}
+
+	// Unregistered functions that are not built in are not yet implemented.
+	call = bindHIL(t, `${whisper("hello")}`)
+	if assert.IsType(t, &BoundError{}, call) {
+		assert.EqualError(t, call.(*BoundError).Error, "1:3: NYI: call to whisper")
+	}
+
+	// Restoring a registration reinstates the binder it replaced.
+	restore := RegisterFunction("upper", func(args []BoundExpr) (Type, error) {
+		return TypeNumber, nil
+	})
+	assert.Equal(t, TypeNumber, bindHIL(t, `${upper("hello")}`).Type())
+	restore()
+	assert.Equal(t, TypeString, bindHIL(t, `${upper("hello")}`).Type())
+}
+
+func TestBindTerraformVariables(t *testing.T) {
+	for _, key := range []string{"env", "workspace"} {
+		call := bindHIL(t, "${terraform."+key+"}")
+		if assert.IsType(t, &BoundCall{}, call) {
+			assert.Equal(t, IntrinsicGetStack, call.(*BoundCall).Func)
+			assert.Equal(t, TypeString, call.Type())
+		}
+	}
+
+	// Unrecognized keys are errors.
+	rootNode, err := hil.Parse("${terraform.bogus}")
+	if err != nil {
+		t.Fatalf("could not parse expression: %v", err)
+	}
+	b := &propertyBinder{builder: newBuilder(&BuildOptions{}), strict: true}
+	_, err = b.bindExpr(rootNode)
+	assert.EqualError(t, err, "1:3: unsupported key 'terraform.bogus'")
+}
+
+func TestBindConditionalTypes(t *testing.T) {
+	const source = `
+variable "enabled" {
+  default = true
+}
+
+variable "size" {
+  default = 1
+}
+
+data "external" "x" {
+  program = ["python", "x.py"]
+}
+
+resource "aws_instance" "web" {
+  tags {
+    Output    = "${var.enabled ? data.external.x.result["name"] : "none"}"
+    Condition = "${data.external.x.result["name"] == "" ? "a" : "b"}"
+    Plain     = "${var.enabled ? "a" : "b"}"
+    Mixed     = "${var.enabled ? "a" : var.size}"
+  }
+}
+`
+	g := buildSource(t, source)
+	tags := g.Resources["aws_instance.web"].Properties.Elements["tags"].(*BoundListProperty).Elements[0]
+	elements := tags.(*BoundMapProperty).Elements
+
+	// A conditional is an output if its condition or either of its branches is an output. Branches whose types differ
+	// by more than output-ness produce an unknown type.
+	assert.Equal(t, TypeString.OutputOf(), elements["Output"].(*BoundConditional).Type())
+	assert.Equal(t, TypeString.OutputOf(), elements["Condition"].(*BoundConditional).Type())
+	assert.Equal(t, TypeString, elements["Plain"].(*BoundConditional).Type())
+	assert.Equal(t, TypeUnknown, elements["Mixed"].(*BoundConditional).Type())
+}
+
+func TestBindConcatTypes(t *testing.T) {
+	const source = `
+variable "zones" {
+  default = ["a", "b"]
+}
+
+resource "aws_vpc" "main" {
+  count = 2
+}
+
+resource "aws_instance" "web" {
+  strings = "${concat(split(",", "a,b"), split(",", "c"))}"
+  outputs = "${concat(var.zones, aws_vpc.main.*.id)}"
+  mixed   = "${concat(var.zones, list("c"))}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	// The result of concat has the element type shared by its arguments, and is an output if any argument is an output.
+	// The elements of a list built by the list function have an unknown type.
+	assert.Equal(t, TypeString.ListOf(), props["strings"].(*BoundCall).Type())
+	assert.Equal(t, TypeString.ListOf().OutputOf(), props["outputs"].(*BoundCall).Type())
+	assert.Equal(t, TypeUnknown.ListOf(), props["mixed"].(*BoundCall).Type())
+}
+
+func TestBindMergeTypes(t *testing.T) {
+	const source = `
+variable "tags" {
+  default = {
+    Name = "web"
+  }
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  prompt = "${merge(var.tags, map("Env", "dev"))}"
+  output = "${merge(var.tags, aws_vpc.main.tags)}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	assert.Equal(t, TypeMap, props["prompt"].(*BoundCall).Type())
+	assert.Equal(t, TypeMap.OutputOf(), props["output"].(*BoundCall).Type())
+}
+
+func TestBindCoalesceTypes(t *testing.T) {
+	assert.Equal(t, TypeString, bindHIL(t, `${coalesce("", "a")}`).Type())
+	assert.Equal(t, TypeNumber, bindHIL(t, `${coalesce(1, 2)}`).Type())
+	assert.Equal(t, TypeUnknown, bindHIL(t, `${coalesce("", 2)}`).Type())
+	assert.Equal(t, TypeString.ListOf(), bindHIL(t, `${coalescelist(split(",", ""), split(",", "a"))}`).Type())
+}
+
+func TestBindNestedListTypes(t *testing.T) {
+	// Lists of lists retain their element types. HIL does not allow a list to be indexed twice in the same expression,
+	// but the elements of nested lists may be indexed through locals or function calls.
+	nested := TypeString.ListOf().ListOf()
+	assert.True(t, nested.IsList())
+	assert.Equal(t, TypeString.ListOf(), nested.ElementType())
+	assert.Equal(t, TypeString, nested.ElementType().ElementType())
+	assert.Equal(t, TypeString.ListOf(), nested.OutputOf().ElementType())
+	assert.Equal(t, "output<list<list<string>>>", nested.OutputOf().String())
+
+	const source = `
+variable "subnets" {
+  default = [["10.0.0.0/24", "10.0.1.0/24"], ["10.1.0.0/24"]]
+}
+
+variable "zones" {
+  default = {
+    east = ["a", "b"]
+    west = ["c"]
+  }
+}
+
+locals {
+  first = "${var.subnets[0]}"
+}
+
+resource "aws_instance" "web" {
+  subnets = "${var.subnets[0]}"
+  subnet  = "${local.first[1]}"
+  element = "${element(var.subnets[1], 0)}"
+  zones   = "${var.zones["east"]}"
+}
+`
+	g := buildSource(t, source)
+	assert.Equal(t, nested, g.Variables["subnets"].Type())
+
+	props := g.Resources["aws_instance.web"].Properties.Elements
+	assert.Equal(t, TypeString.ListOf(), props["subnets"].(*BoundIndex).Type())
+	assert.Equal(t, TypeString, props["subnet"].(*BoundIndex).Type())
+	assert.Equal(t, TypeString, props["element"].(*BoundCall).Type())
+
+	// The elements of maps of lists are also lists.
+	assert.Equal(t, TypeString.ListOf(), props["zones"].(*BoundIndex).Type())
+}
+
+func TestBindKeysAndValuesTypes(t *testing.T) {
+	const source = `
+variable "sizes" {
+  default = {
+    small = 1
+    large = 2
+  }
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  keys          = "${keys(var.sizes)}"
+  values        = "${values(var.sizes)}"
+  output_keys   = "${keys(aws_vpc.main.tags)}"
+  output_values = "${values(aws_vpc.main.tags)}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	assert.Equal(t, TypeString.ListOf(), props["keys"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber.ListOf(), props["values"].(*BoundCall).Type())
+	assert.Equal(t, TypeString.ListOf().OutputOf(), props["output_keys"].(*BoundCall).Type())
+	assert.Equal(t, TypeUnknown.ListOf().OutputOf(), props["output_values"].(*BoundCall).Type())
+}
+
+func TestBindListFunctionTypes(t *testing.T) {
+	const source = `
+variable "zones" {
+  default = ["a", "b", "a"]
+}
+
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "web" {
+  contains        = "${contains(var.zones, "a")}"
+  index           = "${index(var.zones, "b")}"
+  distinct        = "${distinct(var.zones)}"
+  output_contains = "${contains(aws_vpc.main.tags, "a")}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	assert.Equal(t, TypeBool, props["contains"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber, props["index"].(*BoundCall).Type())
+	assert.Equal(t, TypeString.ListOf(), props["distinct"].(*BoundCall).Type())
+	assert.Equal(t, TypeBool.OutputOf(), props["output_contains"].(*BoundCall).Type())
+}
+
+func TestBindListShapingTypes(t *testing.T) {
+	const source = `
+variable "sizes" {
+  default = [10, 9, 100]
+}
+
+resource "aws_instance" "web" {
+  slice        = "${slice(var.sizes, 0, 2)}"
+  sort         = "${sort(var.sizes)}"
+  reverse      = "${reverse(var.sizes)}"
+  element      = "${element(reverse(var.sizes), 0)}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	assert.Equal(t, TypeNumber.ListOf(), props["slice"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber.ListOf(), props["sort"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber.ListOf(), props["reverse"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber, props["element"].(*BoundCall).Type())
+}
+
+func TestBindZipmapTypes(t *testing.T) {
+	const source = `
+variable "sizes" {
+  default = [1, 2]
+}
+
+resource "aws_vpc" "main" {
+  count = 2
+}
+
+resource "aws_instance" "web" {
+  zipmap        = "${zipmap(list("a", "b"), var.sizes)}"
+  lookup        = "${lookup(zipmap(list("a", "b"), var.sizes), "a")}"
+  output_zipmap = "${zipmap(aws_vpc.main.*.id, var.sizes)}"
+}
+`
+	g := buildSource(t, source)
+	props := g.Resources["aws_instance.web"].Properties.Elements
+
+	assert.Equal(t, TypeMap, props["zipmap"].(*BoundCall).Type())
+	assert.Equal(t, TypeNumber, props["lookup"].(*BoundCall).Type())
+	assert.Equal(t, TypeMap.OutputOf(), props["output_zipmap"].(*BoundCall).Type())
+}
+
+func TestBindErrorPositions(t *testing.T) {
+	// Errors attached to bound expressions report the position of the offending node rather than that of the
+	// enclosing expression.
+	call := bindHIL(t, `${upper(whisper("hello"))}`)
+	if assert.IsType(t, &BoundCall{}, call) {
+		arg := call.(*BoundCall).Args[0]
+		if assert.IsType(t, &BoundError{}, arg) {
+			assert.EqualError(t, arg.(*BoundError).Error, "1:9: NYI: call to whisper")
+		}
+	}
+
+	// Errors returned by the binder are prefixed with the path of the property and the position of the offending node
+	// within the property's source file.
+	const source = `
+resource "aws_instance" "web" {
+  ami = "ami-${path.bogus}"
+
+  tags {
+    Name = <<EOF
+web-${whisper("hello")}
+EOF
+  }
+}
+`
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/main.tf", []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+	conf, err := config.LoadFs(fs)
+	if err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	_, err = BuildGraph(module.NewTree("main", conf), &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "aws_instance.web.ami: main.tf:3:16: unsupported key 'path.bogus'")
+	}
+
+	// Errors attached to bound expressions are also reported within the property's source file. The contents of a
+	// heredoc begin on the line after its marker.
+	g, err := BuildGraph(module.NewTree("main", conf), &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+		AccumulateErrors:      true,
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "aws_instance.web.tags[0].Name: main.tf:7:7: NYI: call to whisper")
+	}
+	tags := g.Resources["aws_instance.web"].Properties.Elements["tags"].(*BoundListProperty)
+	name := tags.Elements[0].(*BoundMapProperty).Elements["Name"].(*BoundOutput)
+	if assert.IsType(t, &BoundError{}, name.Exprs[1]) {
+		assert.EqualError(t, name.Exprs[1].(*BoundError).Error, "main.tf:7:7: NYI: call to whisper")
+	}
+}
+
+func TestBindSplatElementTypes(t *testing.T) {
+	const source = `
+resource "test_instance" "web" {
+  count = 2
+}
+
+resource "test_instance" "x" {
+  private_ips     = "${test_instance.web.*.private_ip}"
+  ports           = "${test_instance.web.*.port}"
+  security_groups = "${test_instance.web.*.security_groups}"
+  volume_sizes    = "${test_instance.web.*.root_block_device.0.volume_size}"
+  interface_ids   = "${test_instance.web.*.network_interface.*.id}"
+  first_port      = "${element(test_instance.web.*.port, 0)}"
+}
+`
+	providers := testutil.TestProvider(map[string]*schema.Resource{
+		"test_instance": {
+			Schema: map[string]*schema.Schema{
+				"private_ip": {Type: schema.TypeString, Computed: true},
+				"port":       {Type: schema.TypeInt, Computed: true},
+				"security_groups": {
+					Type:     schema.TypeSet,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"root_block_device": {
+					Type:     schema.TypeList,
+					Computed: true,
+					MaxItems: 1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"volume_size": {Type: schema.TypeInt, Computed: true},
+						},
+					},
+				},
+				"network_interface": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"id": {Type: schema.TypeString, Computed: true},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	g := buildSourceWithProviders(t, source, providers)
+	props := g.Resources["test_instance.x"].Properties.Elements
+
+	// The elements of a splat are typed according to the schema of the accessed property.
+	assert.Equal(t, TypeString.ListOf().OutputOf(), props["private_ips"].Type())
+	assert.Equal(t, TypeNumber.ListOf().OutputOf(), props["ports"].Type())
+	assert.Equal(t, TypeString.ListOf().ListOf().OutputOf(), props["security_groups"].Type())
+	assert.Equal(t, TypeNumber.ListOf().OutputOf(), props["volume_sizes"].Type())
+	assert.Equal(t, TypeString.ListOf().OutputOf(), props["interface_ids"].Type())
+	assert.Equal(t, TypeNumber.OutputOf(), props["first_port"].Type())
+}
@@ -19,6 +19,7 @@ import (
 	"reflect"
 
 	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
@@ -32,6 +33,13 @@ import (
 type propertyBinder struct {
 	builder       *builder
 	hasCountIndex bool
+	// self is the resource whose properties are being bound, if any.
+	self *ResourceNode
+	// positions maps the paths of string properties to their positions in the source, if known.
+	positions map[string]ast.Pos
+	// strict causes the binder to fail at the first interpolation that cannot be bound. If strict is false, such
+	// interpolations are bound as error nodes, and the errors are recorded by the builder.
+	strict bool
 }
 
 // bindListProperty binds a list property according to the given schema information. If the schema information
@@ -84,6 +92,7 @@ func (b *propertyBinder) bindListProperty(path string, s reflect.Value, sch Sche
 
 	boundList := &BoundListProperty{Schemas: sch, Elements: elements}
 	if err != nil {
+		b.builder.recordError(err)
 		return &BoundError{Value: boundList, NodeType: boundList.Type(), Error: err}, nil
 	}
 	return boundList, nil
@@ -114,6 +123,18 @@ func (b *propertyBinder) bindMapProperty(path string, m reflect.Value, sch Schem
 	return &BoundMapProperty{Schemas: sch, Elements: elements}, nil
 }
 
+// recordErrors records the errors attached to the given bound expression and its descendants with the builder. Each
+// error is prefixed with the path of the property that contains the expression.
+func (b *propertyBinder) recordErrors(path string, n BoundExpr) {
+	err := WalkBoundNodes(n, func(n BoundNode) (WalkAction, error) {
+		if e, ok := n.(*BoundError); ok {
+			b.builder.recordError(errors.Errorf("%v: %v", path, e.Error))
+		}
+		return WalkContinue, nil
+	}, ContinueWalker)
+	contract.Assert(err == nil)
+}
+
 // bindProperty binds a single Terraform property. This property must be of kind bool, int, float64, string, slice, or
 // map. If this property is a map, its keys must be of kind string.
 func (b *propertyBinder) bindProperty(path string, p reflect.Value, sch Schemas) (BoundNode, error) {
@@ -130,15 +151,27 @@ func (b *propertyBinder) bindProperty(path string, p reflect.Value, sch Schemas)
 		return &BoundLiteral{ExprType: TypeNumber, Value: p.Float()}, nil
 	case reflect.String:
 		// As in Terraform, parse all strings as HIL, then bind the result.
-		rootNode, err := hil.Parse(p.String())
+		pos, ok := b.positions[path]
+		if !ok {
+			pos = ast.InitPos
+		}
+		rootNode, err := hil.ParseWithPosition(p.String(), pos)
 		if err != nil {
-			return nil, errors.Errorf("%v: could not parse HIL (%v)", path, err)
+			err = errors.Errorf("%v: could not parse HIL (%v)", path, err)
+			if b.strict {
+				return nil, err
+			}
+			b.builder.recordError(err)
+			return &BoundError{NodeType: TypeUnknown, Error: err}, nil
 		}
 		contract.Assert(rootNode != nil)
 		n, err := b.bindExpr(rootNode)
 		if err != nil {
 			return nil, errors.Errorf("%v: %v", path, err)
 		}
+		if !b.strict {
+			b.recordErrors(path, n)
+		}
 		return n, nil
 	case reflect.Slice:
 		return b.bindListProperty(path, p, sch)
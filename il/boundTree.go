@@ -20,16 +20,16 @@ import (
 	"strings"
 
 	"github.com/hashicorp/hil/ast"
-	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 
 	"github.com/pulumi/tf2pulumi/internal/config"
 )
 
 // Type represents the type of a single node in a bound property tree. Types are fairly simplistic: in addition to the
 // primitive types--bool, string, number, map, and unknown--there are the composite types list and output. A type that
-// is both a list and an output is considered to be an output of a list. Outputs have the semantic that their values may
-// not be known promptly; in particular, the target language may need to introduce special elements (e.g. `apply`) to
-// access the concrete value of an output.
+// is both a list and an output is considered to be an output of a list. Lists may be nested, in which case the type
+// records the number of lists nested within the outermost list. Outputs have the semantic that their values may not be
+// known promptly; in particular, the target language may need to introduce special elements (e.g. `apply`) to access
+// the concrete value of an output.
 type Type uint32
 
 const (
@@ -53,6 +53,10 @@ const (
 	TypeOutput Type = 1 << 6
 
 	elementTypeMask Type = TypeBool | TypeString | TypeNumber | TypeMap | TypeUnknown
+
+	// listDepthShift and listDepthMask locate the number of lists nested within the outermost list of a list type.
+	listDepthShift      = 8
+	listDepthMask  Type = 0xf << listDepthShift
 )
 
 // IsList returns true if this value represents a list type.
@@ -60,8 +64,12 @@ func (t Type) IsList() bool {
 	return t&TypeList != 0
 }
 
-// ListOf returns this a list type with this value as its element type.
+// ListOf returns this a list type with this value as its element type. If this value is already a list type, the
+// result is a list of lists.
 func (t Type) ListOf() Type {
+	if t.IsList() && t&listDepthMask != listDepthMask {
+		return t + 1<<listDepthShift
+	}
 	return t | TypeList
 }
 
@@ -75,15 +83,22 @@ func (t Type) OutputOf() Type {
 	return t | TypeOutput
 }
 
-// ElementType returns the element type of this value.
+// ElementType returns the element type of this value. The element type of a list of lists is a list; the element
+// type of any other type is a primitive type.
 func (t Type) ElementType() Type {
+	if t&listDepthMask != 0 {
+		return t&^TypeOutput - 1<<listDepthShift
+	}
 	return t & elementTypeMask
 }
 
-// String returns the string representation of this type.
+// String returns the string representation of this type, e.g. `output<list<string>>`. Types whose element type is not
+// a single primitive type are rendered as "invalid" followed by the raw value of the element type.
 func (t Type) String() string {
-	s := "invalid"
-	switch t.ElementType() {
+	var s string
+	switch elem := t.ElementType(); elem {
+	case TypeInvalid:
+		s = "invalid"
 	case TypeBool:
 		s = "bool"
 	case TypeString:
@@ -95,7 +110,11 @@ func (t Type) String() string {
 	case TypeUnknown:
 		s = "unknown"
 	default:
-		contract.Failf("unknown element type")
+		if elem.IsList() {
+			s = elem.String()
+		} else {
+			s = fmt.Sprintf("invalid(%#x)", uint32(elem))
+		}
 	}
 	if t.IsList() {
 		s = fmt.Sprintf("list<%s>", s)
@@ -104,7 +123,6 @@ func (t Type) String() string {
 		s = fmt.Sprintf("output<%s>", s)
 	}
 	return s
-
 }
 
 // dumper is used to dump bound nodes in a simple S-expression style.
@@ -149,6 +167,8 @@ type BoundNode interface {
 
 	Type() Type
 	Comments() *Comments
+	// Generate generates code for the node by dispatching to the appropriate method of the given generator.
+	Generate(w io.Writer, g BoundNodeGenerator)
 
 	dump(d *dumper)
 	isNode()
@@ -162,7 +182,9 @@ type BoundExpr interface {
 	isExpr()
 }
 
-// BoundArithmetic is the bound form of an HIL arithmetic expression (e.g. `${a + b}`).
+// BoundArithmetic is the bound form of an HIL arithmetic expression (e.g. `${a + b}`). An expression with a single
+// operand is a unary expression: if its operator is ArithmeticOpSub, the expression negates a number (e.g. `${-a}`),
+// and if its operator is ArithmeticOpEqual, the expression negates a boolean (e.g. `${!a}`).
 type BoundArithmetic struct {
 	// Op is the arithmetic operation used by this expression.
 	Op ast.ArithmeticOp
@@ -212,6 +234,9 @@ type BoundCall struct {
 	ExprType Type
 	// Args is the bound list of the call's arguments.
 	Args []BoundExpr
+	// ExpandFinal is true if the final argument is a list whose elements are passed as individual arguments to the
+	// function (e.g. `max(var.sizes...)`).
+	ExpandFinal bool
 }
 
 // Type returns the type of the call expression.
@@ -232,8 +257,11 @@ func (n *BoundCall) setComments(c *Comments) {
 func (n *BoundCall) dump(d *dumper) {
 	d.dump("(call ", fmt.Sprintf("%v %s", n.Type(), n.Func))
 	d.indented(func() {
-		for _, e := range n.Args {
+		for i, e := range n.Args {
 			d.dump("\n", d.indent, e)
+			if n.ExpandFinal && i == len(n.Args)-1 {
+				d.dump("...")
+			}
 		}
 	})
 	d.dump("\n", d.indent, ")")
@@ -0,0 +1,99 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import "io"
+
+// BoundNodeGenerator generates code for bound nodes. Each kind of bound node dispatches to the corresponding method
+// of a BoundNodeGenerator from its Generate method, so an implementation must handle every kind of bound node.
+type BoundNodeGenerator interface {
+	// GenArithmetic generates code for the indicated arithmetic node to the given writer.
+	GenArithmetic(w io.Writer, v *BoundArithmetic)
+	// GenCall generates code for the indicated call node to the given writer.
+	GenCall(w io.Writer, v *BoundCall)
+	// GenConditional generates code for the indicated conditional node to the given writer.
+	GenConditional(w io.Writer, v *BoundConditional)
+	// GenError generates code for the indicated error node to the given writer.
+	GenError(w io.Writer, v *BoundError)
+	// GenIndex generates code for the indicated index node to the given writer.
+	GenIndex(w io.Writer, v *BoundIndex)
+	// GenListProperty generates code for the indicated list property to the given writer.
+	GenListProperty(w io.Writer, v *BoundListProperty)
+	// GenLiteral generates code for the indicated literal node to the given writer.
+	GenLiteral(w io.Writer, v *BoundLiteral)
+	// GenMapProperty generates code for the indicated map property to the given writer.
+	GenMapProperty(w io.Writer, v *BoundMapProperty)
+	// GenOutput generates code for the indicated output node to the given writer.
+	GenOutput(w io.Writer, v *BoundOutput)
+	// GenPropertyValue generates code for the indicated property value node to the given writer.
+	GenPropertyValue(w io.Writer, v *BoundPropertyValue)
+	// GenVariableAccess generates code for the indicated variable access node to the given writer.
+	GenVariableAccess(w io.Writer, v *BoundVariableAccess)
+}
+
+// Generate generates code for the arithmetic expression using the given generator.
+func (n *BoundArithmetic) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenArithmetic(w, n)
+}
+
+// Generate generates code for the call using the given generator.
+func (n *BoundCall) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenCall(w, n)
+}
+
+// Generate generates code for the conditional expression using the given generator.
+func (n *BoundConditional) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenConditional(w, n)
+}
+
+// Generate generates code for the error using the given generator.
+func (n *BoundError) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenError(w, n)
+}
+
+// Generate generates code for the index expression using the given generator.
+func (n *BoundIndex) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenIndex(w, n)
+}
+
+// Generate generates code for the list property using the given generator.
+func (n *BoundListProperty) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenListProperty(w, n)
+}
+
+// Generate generates code for the literal using the given generator.
+func (n *BoundLiteral) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenLiteral(w, n)
+}
+
+// Generate generates code for the map property using the given generator.
+func (n *BoundMapProperty) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenMapProperty(w, n)
+}
+
+// Generate generates code for the output using the given generator.
+func (n *BoundOutput) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenOutput(w, n)
+}
+
+// Generate generates code for the property value using the given generator.
+func (n *BoundPropertyValue) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenPropertyValue(w, n)
+}
+
+// Generate generates code for the variable access using the given generator.
+func (n *BoundVariableAccess) Generate(w io.Writer, g BoundNodeGenerator) {
+	g.GenVariableAccess(w, n)
+}
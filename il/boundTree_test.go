@@ -0,0 +1,51 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeString(t *testing.T) {
+	cases := []struct {
+		typ      Type
+		expected string
+	}{
+		{typ: TypeInvalid, expected: "invalid"},
+		{typ: TypeBool, expected: "bool"},
+		{typ: TypeString, expected: "string"},
+		{typ: TypeNumber, expected: "number"},
+		{typ: TypeMap, expected: "map"},
+		{typ: TypeUnknown, expected: "unknown"},
+		{typ: TypeString.ListOf(), expected: "list<string>"},
+		{typ: TypeMap.ListOf(), expected: "list<map>"},
+		{typ: TypeNumber.ListOf().ListOf(), expected: "list<list<number>>"},
+		{typ: TypeBool.OutputOf(), expected: "output<bool>"},
+		{typ: TypeMap.OutputOf(), expected: "output<map>"},
+		{typ: TypeString.ListOf().OutputOf(), expected: "output<list<string>>"},
+		{typ: TypeUnknown.ListOf().ListOf().OutputOf(), expected: "output<list<list<unknown>>>"},
+		{typ: TypeBool | TypeString, expected: "invalid(0x3)"},
+		{typ: (TypeBool | TypeString).ListOf(), expected: "list<invalid(0x3)>"},
+	}
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			assert.Equal(t, c.expected, c.typ.String())
+			assert.Equal(t, c.expected, fmt.Sprintf("%v", c.typ))
+		})
+	}
+}
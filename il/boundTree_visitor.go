@@ -284,3 +284,134 @@ func VisitAllProperties(m *Graph, pre, post BoundNodeVisitor) error {
 	}
 	return nil
 }
+
+// WalkAction controls the progress of a walk over a bound property tree.
+type WalkAction int
+
+const (
+	// WalkContinue continues the walk.
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren skips the descendents of the current node. The post-order walker is still called for the node.
+	// If returned by a post-order walker, WalkSkipChildren is equivalent to WalkContinue.
+	WalkSkipChildren
+	// WalkStop halts the walk. No further walkers are called.
+	WalkStop
+)
+
+// A BoundNodeWalker is a function that inspects a node in a bound property tree without replacing it.
+type BoundNodeWalker func(n BoundNode) (WalkAction, error)
+
+// ContinueWalker is a BoundNodeWalker that continues the walk.
+func ContinueWalker(n BoundNode) (WalkAction, error) {
+	return WalkContinue, nil
+}
+
+// WalkBoundNodes walks each node in a property tree using the given pre- and post-order walkers. Unlike
+// VisitBoundNode, WalkBoundNodes does not modify the tree. Either walker may halt the walk by returning WalkStop, and
+// the pre-order walker may skip a node's descendents by returning WalkSkipChildren. If any walker returns an error, the
+// walk halts and that error is returned.
+func WalkBoundNodes(n BoundNode, pre, post BoundNodeWalker) error {
+	_, err := walkBoundNode(n, pre, post)
+	return err
+}
+
+func walkBoundNode(n BoundNode, pre, post BoundNodeWalker) (WalkAction, error) {
+	if n == nil {
+		return WalkContinue, nil
+	}
+
+	action, err := pre(n)
+	if err != nil || action == WalkStop {
+		return WalkStop, err
+	}
+	if action != WalkSkipChildren {
+		for _, c := range boundNodeChildren(n) {
+			if action, err := walkBoundNode(c, pre, post); err != nil || action == WalkStop {
+				return WalkStop, err
+			}
+		}
+	}
+
+	action, err = post(n)
+	if err != nil || action == WalkStop {
+		return WalkStop, err
+	}
+	return WalkContinue, nil
+}
+
+// boundNodeChildren returns the children of the given node in the order in which they are visited by VisitBoundNode.
+func boundNodeChildren(n BoundNode) []BoundNode {
+	var children []BoundNode
+	appendExprs := func(exprs []BoundExpr) {
+		for _, e := range exprs {
+			children = append(children, e)
+		}
+	}
+
+	switch n := n.(type) {
+	case *BoundArithmetic:
+		appendExprs(n.Exprs)
+	case *BoundCall:
+		appendExprs(n.Args)
+	case *BoundConditional:
+		appendExprs([]BoundExpr{n.CondExpr, n.TrueExpr, n.FalseExpr})
+	case *BoundError:
+		children = append(children, n.Value)
+	case *BoundIndex:
+		appendExprs([]BoundExpr{n.TargetExpr, n.KeyExpr})
+	case *BoundListProperty:
+		children = append(children, n.Elements...)
+	case *BoundLiteral, *BoundVariableAccess:
+		// No children.
+	case *BoundMapProperty:
+		// Sort the keys to ensure a deterministic walk order.
+		var keys []string
+		for k := range n.Elements {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			children = append(children, n.Elements[k])
+		}
+	case *BoundOutput:
+		appendExprs(n.Exprs)
+	case *BoundPropertyValue:
+		children = append(children, n.Value)
+	default:
+		contract.Failf("unexpected node type in boundNodeChildren: %T", n)
+	}
+	return children
+}
+
+// WalkAllProperties walks all property nodes in the graph using the given pre- and post-order walkers.
+func WalkAllProperties(m *Graph, pre, post BoundNodeWalker) error {
+	var roots []BoundNode
+	for _, n := range m.Modules {
+		roots = append(roots, n.Properties)
+	}
+	for _, n := range m.Providers {
+		roots = append(roots, n.Properties)
+	}
+	for _, n := range m.Resources {
+		if n.Count != nil {
+			roots = append(roots, n.Count)
+		}
+		roots = append(roots, n.Properties)
+	}
+	for _, n := range m.Outputs {
+		roots = append(roots, n.Value)
+	}
+	for _, n := range m.Locals {
+		roots = append(roots, n.Value)
+	}
+	for _, n := range m.Variables {
+		roots = append(roots, n.DefaultValue)
+	}
+
+	for _, root := range roots {
+		if action, err := walkBoundNode(root, pre, post); err != nil || action == WalkStop {
+			return err
+		}
+	}
+	return nil
+}
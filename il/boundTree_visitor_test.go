@@ -0,0 +1,123 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkBoundNodes(t *testing.T) {
+	// {
+	//     name = "${var.enabled ? upper(var.name) : "none"}"
+	//     size = "${var.size * 2}"
+	// }
+	variable := func(name string, typ Type) *BoundVariableAccess {
+		return &BoundVariableAccess{Elements: []string{name}, ExprType: typ}
+	}
+	tree := &BoundMapProperty{
+		Elements: map[string]BoundNode{
+			"name": &BoundOutput{
+				Exprs: []BoundExpr{&BoundConditional{
+					ExprType: TypeString,
+					CondExpr: variable("enabled", TypeBool),
+					TrueExpr: &BoundCall{
+						Func:     "upper",
+						ExprType: TypeString,
+						Args:     []BoundExpr{variable("name", TypeString)},
+					},
+					FalseExpr: &BoundLiteral{ExprType: TypeString, Value: "none"},
+				}},
+			},
+			"size": &BoundArithmetic{
+				Op:       ast.ArithmeticOpMul,
+				ExprType: TypeNumber,
+				Exprs:    []BoundExpr{variable("size", TypeNumber), &BoundLiteral{ExprType: TypeNumber, Value: 2}},
+			},
+		},
+	}
+
+	var pre, post []string
+	counts := map[string]int{}
+	err := WalkBoundNodes(tree, func(n BoundNode) (WalkAction, error) {
+		pre = append(pre, fmt.Sprintf("%T", n))
+		return WalkContinue, nil
+	}, func(n BoundNode) (WalkAction, error) {
+		post = append(post, fmt.Sprintf("%T", n))
+		counts[fmt.Sprintf("%T", n)]++
+		return WalkContinue, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"*il.BoundMapProperty":    1,
+		"*il.BoundOutput":         1,
+		"*il.BoundConditional":    1,
+		"*il.BoundCall":           1,
+		"*il.BoundArithmetic":     1,
+		"*il.BoundVariableAccess": 3,
+		"*il.BoundLiteral":        2,
+	}, counts)
+
+	// Nodes are walked in pre- and post-order, with map elements in key order.
+	assert.Equal(t, []string{
+		"*il.BoundMapProperty",
+		"*il.BoundOutput",
+		"*il.BoundConditional",
+		"*il.BoundVariableAccess",
+		"*il.BoundCall",
+		"*il.BoundVariableAccess",
+		"*il.BoundLiteral",
+		"*il.BoundArithmetic",
+		"*il.BoundVariableAccess",
+		"*il.BoundLiteral",
+	}, pre)
+	assert.Equal(t, "*il.BoundMapProperty", post[len(post)-1])
+
+	// Skipping the children of the conditional skips its branches but still calls the post-order walker.
+	var walked []string
+	err = WalkBoundNodes(tree, func(n BoundNode) (WalkAction, error) {
+		if _, ok := n.(*BoundConditional); ok {
+			return WalkSkipChildren, nil
+		}
+		return WalkContinue, nil
+	}, func(n BoundNode) (WalkAction, error) {
+		walked = append(walked, fmt.Sprintf("%T", n))
+		return WalkContinue, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"*il.BoundConditional",
+		"*il.BoundOutput",
+		"*il.BoundVariableAccess",
+		"*il.BoundLiteral",
+		"*il.BoundArithmetic",
+		"*il.BoundMapProperty",
+	}, walked)
+
+	// Stopping the walk at the first call halts the walk immediately.
+	visited := 0
+	err = WalkBoundNodes(tree, func(n BoundNode) (WalkAction, error) {
+		visited++
+		if _, ok := n.(*BoundCall); ok {
+			return WalkStop, nil
+		}
+		return WalkContinue, nil
+	}, ContinueWalker)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, visited)
+}
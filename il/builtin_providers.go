@@ -46,3 +46,46 @@ var builtinProviderInfo = map[string]*tfbridge.ProviderInfo{
 		Resources: map[string]*tfbridge.ResourceInfo{},
 	},
 }
+
+// fallbackProviderInfo provides a static map from provider name to provider information for providers whose schemas
+// are simple enough to describe here. This information is used if the provider's own information is unavailable so
+// that references to these providers' resources are still typed correctly. Currently this includes the external
+// provider, whose single data source returns a dynamically-populated map of strings.
+var fallbackProviderInfo = map[string]*tfbridge.ProviderInfo{
+	"external": {
+		P: &schema.Provider{
+			DataSourcesMap: map[string]*schema.Resource{
+				"external": {
+					Schema: map[string]*schema.Schema{
+						"program": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"working_dir": {Type: schema.TypeString, Optional: true},
+						"query": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"result": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+		Config: map[string]*tfbridge.SchemaInfo{},
+		DataSources: map[string]*tfbridge.DataSourceInfo{
+			"external": {
+				Tok: "external:index/getExternal:getExternal",
+				Fields: map[string]*tfbridge.SchemaInfo{
+					"program": {Name: "program"},
+				},
+			},
+		},
+		Resources: map[string]*tfbridge.ResourceInfo{},
+	},
+}
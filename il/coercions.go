@@ -16,7 +16,13 @@ package il
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 )
 
 func coerceLiteral(lit *BoundLiteral, from, to Type) (*BoundLiteral, bool) {
@@ -29,7 +35,7 @@ func coerceLiteral(lit *BoundLiteral, from, to Type) (*BoundLiteral, bool) {
 	case TypeString:
 		str = lit.Value.(string)
 	default:
-		panic(fmt.Sprintf("unexpected literal type in coerceLiteral: %v", lit.Value))
+		panic(fmt.Sprintf("unexpected literal type in coerceLiteral: %v", from))
 	}
 
 	switch to {
@@ -74,11 +80,37 @@ func makeCoercion(n BoundNode, toType Type) BoundNode {
 	// TODO: we really need dynamic coercions for the negative case.
 	from, to := n.Type().ElementType(), toType.ElementType()
 
+	// A single-element output that preserves the string conversion of the count index can be replaced by the index
+	// itself if the index already has the desired type.
+	if out, ok := n.(*BoundOutput); ok && len(out.Exprs) == 1 {
+		if e := out.Exprs[0]; e.Type().ElementType() == to && !e.Type().IsList() && !toType.IsList() {
+			return e
+		}
+	}
+
+	// Coerce each branch of a conditional rather than the conditional as a whole so that e.g. literal branches can be
+	// coerced statically. This also handles conditionals whose branches have different types, which are untyped.
+	if cond, ok := n.(*BoundConditional); ok && from != to && !n.Type().IsList() && !toType.IsList() {
+		if to == TypeBool || to == TypeNumber || to == TypeString {
+			cond.TrueExpr = makeCoercion(cond.TrueExpr, to).(BoundExpr)
+			cond.FalseExpr = makeCoercion(cond.FalseExpr, to).(BoundExpr)
+			if t, f := cond.TrueExpr.Type(), cond.FalseExpr.Type(); t.ElementType() == to && f.ElementType() == to {
+				cond.ExprType = t | f | cond.ExprType&TypeOutput
+			}
+			return cond
+		}
+	}
+
 	e, ok := n.(BoundExpr)
 	if !ok || from == to {
 		return n
 	}
 
+	// Lists are never coerced as a whole.
+	if n.Type().IsList() || toType.IsList() {
+		return n
+	}
+
 	// If we're dealing with a literal, we can always try to convert through a string.
 	if lit, ok := n.(*BoundLiteral); ok {
 		if result, ok := coerceLiteral(lit, from, to); ok {
@@ -94,11 +126,69 @@ func makeCoercion(n BoundNode, toType Type) BoundNode {
 	return NewCoerceCall(e, toType)
 }
 
+// arithmeticOperandType returns the type expected of the operands of the given arithmetic expression. Operands of the
+// equality operators may be of any type, so TypeUnknown is returned for these operators. The operand of a logical
+// negation is a boolean.
+func arithmeticOperandType(n *BoundArithmetic) Type {
+	if len(n.Exprs) == 1 && n.Op == ast.ArithmeticOpEqual {
+		return TypeBool
+	}
+
+	switch n.Op {
+	case ast.ArithmeticOpEqual, ast.ArithmeticOpNotEqual:
+		return TypeUnknown
+	case ast.ArithmeticOpLogicalAnd, ast.ArithmeticOpLogicalOr:
+		return TypeBool
+	default:
+		return TypeNumber
+	}
+}
+
+// numericArgsIndex returns the index of the first argument of the given interpolation function that is expected to be
+// a number. All subsequent arguments are also expected to be numbers. If the function does not expect any numeric
+// arguments, numericArgsIndex returns -1.
+func numericArgsIndex(name string) int {
+	switch name {
+	case "abs", "ceil", "floor", "max", "min", "signum":
+		return 0
+	case "cidrhost", "cidrsubnet", "slice", "substr":
+		return 1
+	default:
+		return -1
+	}
+}
+
 // AddCoercions inserts calls to the `__coerce` intrinsic in cases where a list or map element's type disagrees with
-// the element type present in the list or map's schema.
+// the element type present in the list or map's schema, where an arithmetic operand's type disagrees with the type
+// expected by its operator, where a numeric argument to a function is not a number, or where a key used to index a
+// map is not a string.
 func AddCoercions(prop BoundNode) (BoundNode, error) {
 	rewriter := func(n BoundNode) (BoundNode, error) {
 		switch n := n.(type) {
+		case *BoundArithmetic:
+			// HIL converts the operands of arithmetic operators to the appropriate type (e.g. "1" + 2 is 3), so we do
+			// the same.
+			if operandType := arithmeticOperandType(n); operandType != TypeUnknown {
+				for i := range n.Exprs {
+					n.Exprs[i] = makeCoercion(n.Exprs[i], operandType).(BoundExpr)
+				}
+			}
+		case *BoundCall:
+			// HIL converts the numeric arguments of functions to numbers, so we do the same. List arguments (e.g. the
+			// single argument to `max(list)` or an expanded final argument) are left as-is.
+			if first := numericArgsIndex(n.Func); first != -1 {
+				for i := first; i < len(n.Args); i++ {
+					isExpanded := n.ExpandFinal && i == len(n.Args)-1
+					if !isExpanded && !n.Args[i].Type().IsList() {
+						n.Args[i] = makeCoercion(n.Args[i], TypeNumber).(BoundExpr)
+					}
+				}
+			}
+		case *BoundIndex:
+			// Map keys are always strings.
+			if t := n.TargetExpr.Type(); !t.IsList() && t.ElementType() == TypeMap {
+				n.KeyExpr = makeCoercion(n.KeyExpr, TypeString).(BoundExpr)
+			}
 		case *BoundListProperty:
 			elemType := n.Schemas.ElemSchemas().Type()
 			for i := range n.Elements {
@@ -114,3 +204,106 @@ func AddCoercions(prop BoundNode) (BoundNode, error) {
 
 	return VisitBoundNode(prop, IdentityVisitor, rewriter)
 }
+
+// LossyCoercion describes a coercion that may change the semantics of the coerced value (e.g. a conversion from string
+// to number, which fails or produces NaN if the string is not numeric). A coercion is either a call to the `__coerce`
+// intrinsic or the implicit conversion of a property's value to the type required by the property's schema.
+type LossyCoercion struct {
+	// Path is the path to the property that contains the coercion (e.g. "tags.Name" or "ports[0]"). The path is empty
+	// if the coercion is not contained in a list or map property.
+	Path string
+	// From is the type of the coerced value.
+	From Type
+	// To is the type to which the value is coerced.
+	To Type
+	// ToInteger is true if the value is coerced to an integer, in which case any fractional part of the value is
+	// discarded.
+	ToInteger bool
+}
+
+// isLossyCoercion returns true if a dynamic coercion between the given types may change the semantics of the coerced
+// value.
+func isLossyCoercion(from, to Type) bool {
+	return from.ElementType() == TypeString && (to.ElementType() == TypeNumber || to.ElementType() == TypeBool)
+}
+
+// findLossyConversion returns the potentially-lossy coercion, if any, that occurs when the given value is assigned to a
+// property with the given schemas. Such a coercion occurs if a list is assigned to a scalar property, or if a number
+// that may have a fractional part is assigned to an integer property.
+func findLossyConversion(value BoundExpr, sch Schemas) (LossyCoercion, bool) {
+	from, to := value.Type()&^TypeOutput, sch.Type()
+	switch {
+	case from.IsList() && (to == TypeBool || to == TypeNumber || to == TypeString):
+		return LossyCoercion{From: from, To: to}, true
+	case from == TypeNumber && sch.TF != nil && sch.TF.Type == schema.TypeInt && mayBeFractional(value):
+		return LossyCoercion{From: from, To: to, ToInteger: true}, true
+	default:
+		return LossyCoercion{}, false
+	}
+}
+
+// mayBeFractional returns true if the given number-typed expression may evaluate to a number with a fractional part:
+// that is, if the expression is a non-integral literal, a division, a reference to a floating-point attribute, or a
+// conditional with such a branch.
+func mayBeFractional(e BoundExpr) bool {
+	switch e := e.(type) {
+	case *BoundLiteral:
+		v, ok := e.Value.(float64)
+		return ok && v != math.Trunc(v)
+	case *BoundArithmetic:
+		return e.Op == ast.ArithmeticOpDiv
+	case *BoundConditional:
+		return mayBeFractional(e.TrueExpr) || mayBeFractional(e.FalseExpr)
+	case *BoundVariableAccess:
+		return e.Schemas.TF != nil && e.Schemas.TF.Type == schema.TypeFloat
+	default:
+		return false
+	}
+}
+
+// FindLossyCoercions returns the list of potentially-lossy coercions present in the given property in the order in
+// which they appear. Map elements are visited in key order.
+func FindLossyCoercions(prop BoundNode) []LossyCoercion {
+	var coercions []LossyCoercion
+	var find func(n BoundNode, path string, sch Schemas)
+	find = func(n BoundNode, path string, sch Schemas) {
+		switch n := n.(type) {
+		case *BoundListProperty:
+			for i, e := range n.Elements {
+				find(e, fmt.Sprintf("%s[%d]", path, i), n.Schemas.ElemSchemas())
+			}
+		case *BoundMapProperty:
+			keys := make([]string, 0, len(n.Elements))
+			for k := range n.Elements {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				elementPath := k
+				if path != "" {
+					elementPath = path + "." + k
+				}
+				find(n.Elements[k], elementPath, n.Schemas.PropertySchemas(k))
+			}
+		case BoundExpr:
+			_, err := VisitBoundExpr(n, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
+				if call, ok := n.(*BoundCall); ok && call.Func == IntrinsicCoerce {
+					value, toType := ParseCoerceCall(call)
+					if isLossyCoercion(value.Type(), toType) {
+						coercions = append(coercions, LossyCoercion{Path: path, From: value.Type(), To: toType})
+					}
+				}
+				return n, nil
+			})
+			contract.Assert(err == nil)
+
+			if c, ok := findLossyConversion(n, sch); ok {
+				c.Path = path
+				coercions = append(coercions, c)
+			}
+		}
+	}
+	find(prop, "", Schemas{})
+	return coercions
+}
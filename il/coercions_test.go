@@ -3,6 +3,8 @@ package il
 import (
 	"testing"
 
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -63,3 +65,172 @@ func TestStringCoercions(t *testing.T) {
 		assert.True(t, ok)
 	}
 }
+
+func TestArithmeticCoercions(t *testing.T) {
+	str := &BoundVariableAccess{ExprType: TypeString}
+	num := &BoundVariableAccess{ExprType: TypeNumber}
+
+	sum := &BoundArithmetic{Op: ast.ArithmeticOpAdd, Exprs: []BoundExpr{str, num}, ExprType: TypeNumber}
+	_, err := AddCoercions(sum)
+	assert.NoError(t, err)
+	value, toType := ParseCoerceCall(sum.Exprs[0].(*BoundCall))
+	assert.Equal(t, str, value)
+	assert.Equal(t, TypeNumber, toType)
+	assert.Equal(t, num, sum.Exprs[1])
+
+	and := &BoundArithmetic{Op: ast.ArithmeticOpLogicalAnd, Exprs: []BoundExpr{str, &BoundLiteral{
+		ExprType: TypeString, Value: "true"}}, ExprType: TypeBool}
+	_, err = AddCoercions(and)
+	assert.NoError(t, err)
+	value, toType = ParseCoerceCall(and.Exprs[0].(*BoundCall))
+	assert.Equal(t, str, value)
+	assert.Equal(t, TypeBool, toType)
+	assert.Equal(t, &BoundLiteral{ExprType: TypeBool, Value: true}, and.Exprs[1])
+
+	eq := &BoundArithmetic{Op: ast.ArithmeticOpEqual, Exprs: []BoundExpr{str, num}, ExprType: TypeBool}
+	_, err = AddCoercions(eq)
+	assert.NoError(t, err)
+	assert.Equal(t, []BoundExpr{str, num}, eq.Exprs)
+}
+
+func TestNumericFunctionCoercions(t *testing.T) {
+	str := &BoundVariableAccess{ExprType: TypeString}
+	num := &BoundVariableAccess{ExprType: TypeNumber}
+	list := &BoundVariableAccess{ExprType: TypeNumber.ListOf()}
+
+	max := &BoundCall{Func: "max", Args: []BoundExpr{str, num}, ExprType: TypeNumber}
+	_, err := AddCoercions(max)
+	assert.NoError(t, err)
+	value, toType := ParseCoerceCall(max.Args[0].(*BoundCall))
+	assert.Equal(t, str, value)
+	assert.Equal(t, TypeNumber, toType)
+	assert.Equal(t, num, max.Args[1])
+
+	maxList := &BoundCall{Func: "max", Args: []BoundExpr{list}, ExprType: TypeNumber}
+	_, err = AddCoercions(maxList)
+	assert.NoError(t, err)
+	assert.Equal(t, []BoundExpr{list}, maxList.Args)
+
+	expanded := &BoundCall{Func: "min", Args: []BoundExpr{num, str}, ExpandFinal: true, ExprType: TypeNumber}
+	_, err = AddCoercions(expanded)
+	assert.NoError(t, err)
+	assert.Equal(t, []BoundExpr{num, str}, expanded.Args)
+}
+
+func TestIndexCoercions(t *testing.T) {
+	num := &BoundVariableAccess{ExprType: TypeNumber}
+
+	// Keys used to index maps are converted to strings.
+	mapIndex := &BoundIndex{
+		TargetExpr: &BoundVariableAccess{ExprType: TypeMap},
+		KeyExpr:    num,
+		ExprType:   TypeUnknown,
+	}
+	_, err := AddCoercions(mapIndex)
+	assert.NoError(t, err)
+	value, toType := ParseCoerceCall(mapIndex.KeyExpr.(*BoundCall))
+	assert.Equal(t, num, value)
+	assert.Equal(t, TypeString, toType)
+
+	literalIndex := &BoundIndex{
+		TargetExpr: &BoundVariableAccess{ExprType: TypeMap.OutputOf()},
+		KeyExpr:    &BoundLiteral{ExprType: TypeNumber, Value: 1.0},
+		ExprType:   TypeUnknown.OutputOf(),
+	}
+	_, err = AddCoercions(literalIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, &BoundLiteral{ExprType: TypeString, Value: "1"}, literalIndex.KeyExpr)
+
+	// Keys used to index lists, including lists of maps, are left as-is.
+	listIndex := &BoundIndex{
+		TargetExpr: &BoundVariableAccess{ExprType: TypeMap.ListOf()},
+		KeyExpr:    num,
+		ExprType:   TypeMap,
+	}
+	_, err = AddCoercions(listIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, num, listIndex.KeyExpr)
+}
+
+func TestFindLossyCoercions(t *testing.T) {
+	str := &BoundVariableAccess{ExprType: TypeString}
+	num := &BoundVariableAccess{ExprType: TypeNumber}
+
+	prop := &BoundMapProperty{
+		Elements: map[string]BoundNode{
+			"count": NewCoerceCall(str, TypeNumber),
+			"label": NewCoerceCall(num, TypeString),
+			"tags": &BoundMapProperty{
+				Elements: map[string]BoundNode{
+					"enabled": NewCoerceCall(str, TypeBool),
+				},
+			},
+			"ports": &BoundListProperty{
+				Elements: []BoundNode{
+					&BoundLiteral{ExprType: TypeNumber, Value: 80.0},
+					NewCoerceCall(str, TypeNumber),
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []LossyCoercion{
+		{Path: "count", From: TypeString, To: TypeNumber},
+		{Path: "ports[1]", From: TypeString, To: TypeNumber},
+		{Path: "tags.enabled", From: TypeString, To: TypeBool},
+	}, FindLossyCoercions(prop))
+}
+
+func TestFindLossyConversions(t *testing.T) {
+	num := &BoundVariableAccess{ExprType: TypeNumber}
+	float := &BoundVariableAccess{ExprType: TypeNumber, Schemas: Schemas{TF: &schema.Schema{Type: schema.TypeFloat}}}
+	strs := &BoundVariableAccess{ExprType: TypeString.ListOf().OutputOf()}
+
+	res := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"count":  {Type: schema.TypeInt},
+			"half":   {Type: schema.TypeInt},
+			"ratio":  {Type: schema.TypeInt},
+			"size":   {Type: schema.TypeInt},
+			"weight": {Type: schema.TypeFloat},
+			"name":   {Type: schema.TypeString},
+			"ports": {
+				Type: schema.TypeList,
+				Elem: &schema.Schema{Type: schema.TypeInt},
+			},
+			"zones": {
+				Type: schema.TypeList,
+				Elem: &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+	prop := &BoundMapProperty{
+		Schemas: Schemas{TFRes: res},
+		Elements: map[string]BoundNode{
+			// Integral numbers, numbers of unknown precision, and floats assigned to float properties are not lossy.
+			"count":  &BoundLiteral{ExprType: TypeNumber, Value: 2.0},
+			"size":   num,
+			"weight": float,
+			// Fractional numbers assigned to integer properties are lossy.
+			"half":  &BoundArithmetic{Op: ast.ArithmeticOpDiv, Exprs: []BoundExpr{num, num}, ExprType: TypeNumber},
+			"ratio": float,
+			"ports": &BoundListProperty{
+				Schemas: Schemas{TF: res.Schema["ports"]},
+				Elements: []BoundNode{
+					&BoundLiteral{ExprType: TypeNumber, Value: 80.0},
+					&BoundLiteral{ExprType: TypeNumber, Value: 80.5},
+				},
+			},
+			// Lists assigned to scalar properties are lossy; lists assigned to list properties are not.
+			"name":  strs,
+			"zones": strs,
+		},
+	}
+
+	assert.Equal(t, []LossyCoercion{
+		{Path: "half", From: TypeNumber, To: TypeNumber, ToInteger: true},
+		{Path: "name", From: TypeString.ListOf(), To: TypeString},
+		{Path: "ports[1]", From: TypeNumber, To: TypeNumber, ToInteger: true},
+		{Path: "ratio", From: TypeNumber, To: TypeNumber, ToInteger: true},
+	}, FindLossyCoercions(prop))
+}
@@ -0,0 +1,156 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FormatVerb represents a single verb in the format string passed to `format` or `formatlist`, e.g. `%-10s`.
+type FormatVerb struct {
+	// Plus is true if the verb has the '+' flag, which always prints the sign of numeric values.
+	Plus bool
+	// Minus is true if the verb has the '-' flag, which left-justifies the value within its width.
+	Minus bool
+	// Zero is true if the verb has the '0' flag, which pads the value with leading zeros.
+	Zero bool
+	// Width is the minimum width of the formatted value, or -1 if the verb has no width.
+	Width int
+	// Precision is the precision of the formatted value, or -1 if the verb has no precision.
+	Precision int
+	// Verb is the verb character, e.g. 's' or 'd'.
+	Verb byte
+}
+
+// String returns the canonical text of the verb.
+func (v *FormatVerb) String() string {
+	var b strings.Builder
+	b.WriteByte('%')
+	if v.Plus {
+		b.WriteByte('+')
+	}
+	if v.Zero {
+		b.WriteByte('0')
+	}
+	if v.Minus {
+		b.WriteByte('-')
+	}
+	if v.Width != -1 {
+		b.WriteString(strconv.Itoa(v.Width))
+	}
+	if v.Precision != -1 {
+		b.WriteByte('.')
+		b.WriteString(strconv.Itoa(v.Precision))
+	}
+	b.WriteByte(v.Verb)
+	return b.String()
+}
+
+// FormatPart is a single part of a format string. Each part is either literal text or a verb.
+type FormatPart struct {
+	// Text is the literal text of the part, if the part is not a verb. Escaped percent signs have been unescaped.
+	Text string
+	// Verb is the verb, if any.
+	Verb *FormatVerb
+}
+
+// ParseFormat parses a format string as accepted by `format` and `formatlist` into its literal text and verbs. Only the
+// subset of Terraform's verbs and flags that can be faithfully translated is accepted: the '+', '-', and '0' flags,
+// widths, precisions, and the b, d, e, f, g, o, s, t, v, x, and X verbs. Combining the '-' and '0' flags is an error.
+func ParseFormat(format string) ([]FormatPart, error) {
+	var parts []FormatPart
+	var text strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+
+		start := i
+		i++
+		if i < len(format) && format[i] == '%' {
+			text.WriteByte('%')
+			continue
+		}
+
+		verb := &FormatVerb{Width: -1, Precision: -1}
+	flags:
+		for ; i < len(format); i++ {
+			switch format[i] {
+			case '+':
+				verb.Plus = true
+			case '-':
+				verb.Minus = true
+			case '0':
+				verb.Zero = true
+			case '#', ' ':
+				return nil, errors.Errorf("unsupported flag '%c' in format verb %q", format[i], format[start:i+1])
+			default:
+				break flags
+			}
+		}
+
+		verb.Width, i = parseFormatNumber(format, i)
+		if i < len(format) && format[i] == '.' {
+			verb.Precision, i = parseFormatNumber(format, i+1)
+			if verb.Precision == -1 {
+				verb.Precision = 0
+			}
+		}
+
+		if i >= len(format) {
+			return nil, errors.Errorf("incomplete format verb %q", format[start:])
+		}
+		switch format[i] {
+		case 'b', 'd', 'e', 'f', 'g', 'o', 's', 't', 'v', 'x', 'X':
+			verb.Verb = format[i]
+		default:
+			return nil, errors.Errorf("unsupported format verb %q", format[start:i+1])
+		}
+		if verb.Minus && verb.Zero {
+			return nil, errors.Errorf("the '-' and '0' flags cannot be combined in format verb %q", format[start:i+1])
+		}
+
+		if text.Len() != 0 {
+			parts = append(parts, FormatPart{Text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, FormatPart{Verb: verb})
+	}
+	if text.Len() != 0 {
+		parts = append(parts, FormatPart{Text: text.String()})
+	}
+	return parts, nil
+}
+
+// parseFormatNumber parses the decimal number that starts at the given offset in a format string, if any. It returns
+// the number (or -1 if there is no number) and the offset of the first character after the number.
+func parseFormatNumber(format string, i int) (int, int) {
+	start := i
+	for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+		i++
+	}
+	if i == start {
+		return -1, i
+	}
+	n, err := strconv.Atoi(format[start:i])
+	if err != nil {
+		return -1, start
+	}
+	return n, i
+}
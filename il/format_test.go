@@ -0,0 +1,51 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	parts, err := ParseFormat("web-%02d")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormatPart{
+		{Text: "web-"},
+		{Verb: &FormatVerb{Zero: true, Width: 2, Precision: -1, Verb: 'd'}},
+	}, parts)
+
+	parts, err = ParseFormat("[%-10s] 100%%")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormatPart{
+		{Text: "["},
+		{Verb: &FormatVerb{Minus: true, Width: 10, Precision: -1, Verb: 's'}},
+		{Text: "] 100%"},
+	}, parts)
+
+	parts, err = ParseFormat("%-+8.3f")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormatPart{
+		{Verb: &FormatVerb{Plus: true, Minus: true, Width: 8, Precision: 3, Verb: 'f'}},
+	}, parts)
+	assert.Equal(t, "%+-8.3f", parts[0].Verb.String())
+
+	invalid := []string{"%-05d", "%#x", "% d", "%q", "%[1]s", "%5"}
+	for _, format := range invalid {
+		_, err = ParseFormat(format)
+		assert.Error(t, err, format)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A FunctionBinder computes the type of a call to a Terraform interpolation function from the call's bound arguments.
+// If the arguments are invalid, the binder returns the type of the call along with an error that describes the problem.
+type FunctionBinder func(args []BoundExpr) (Type, error)
+
+// functions maps the names of interpolation functions to their binders. Calls to functions that are not present in
+// this map are bound by bindBuiltinCall.
+var functions = map[string]FunctionBinder{}
+
+// RegisterFunction registers the binder for the named Terraform interpolation function, replacing any existing binder
+// for that function. RegisterFunction returns a function that restores the previous registration. RegisterFunction is
+// not safe for concurrent use, and is intended to be called from init functions.
+func RegisterFunction(name string, binder FunctionBinder) (restore func()) {
+	previous, hadPrevious := functions[name]
+	functions[name] = binder
+	return func() {
+		if hadPrevious {
+			functions[name] = previous
+		} else {
+			delete(functions, name)
+		}
+	}
+}
+
+func init() {
+	RegisterFunction("chomp", bindStringTransform)
+	RegisterFunction("cidrhost", bindCIDRHost)
+	RegisterFunction("cidrnetmask", bindCIDRNetmask)
+	RegisterFunction("cidrsubnet", bindCIDRSubnet)
+	RegisterFunction("coalesce", bindCoalesce)
+	RegisterFunction("coalescelist", bindCoalesceList)
+	RegisterFunction("concat", bindConcat)
+	RegisterFunction("contains", bindContains)
+	RegisterFunction("distinct", bindListTransform)
+	RegisterFunction("element", bindElement)
+	RegisterFunction("file", bindFile)
+	RegisterFunction("formatdate", bindFormatDate)
+	RegisterFunction("index", bindIndexOf)
+	RegisterFunction("jsondecode", bindJSONDecode)
+	RegisterFunction("jsonencode", bindJSONEncode)
+	RegisterFunction("keys", bindKeys)
+	RegisterFunction("length", bindLength)
+	RegisterFunction("lookup", bindLookup)
+	RegisterFunction("lower", bindStringTransform)
+	RegisterFunction("merge", bindMerge)
+	RegisterFunction("replace", bindReplace)
+	RegisterFunction("reverse", bindListTransform)
+	RegisterFunction("slice", bindListTransform)
+	RegisterFunction("sort", bindListTransform)
+	RegisterFunction("split", bindSplit)
+	RegisterFunction("substr", bindSubstr)
+	RegisterFunction("timestamp", bindTimestamp)
+	RegisterFunction("title", bindStringTransform)
+	RegisterFunction("trimspace", bindStringTransform)
+	RegisterFunction("upper", bindStringTransform)
+	RegisterFunction("uuid", bindUUID)
+	RegisterFunction("values", bindValues)
+	RegisterFunction("zipmap", bindZipmap)
+}
+
+// bindCIDRHost binds a call to `cidrhost`.
+func bindCIDRHost(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindCIDRNetmask binds a call to `cidrnetmask`. The netmask form is specific to IPv4. If the prefix is a literal IPv6
+// prefix, report an error now rather than at runtime.
+func bindCIDRNetmask(args []BoundExpr) (Type, error) {
+	if lit, ok := args[0].(*BoundLiteral); ok && lit.ExprType == TypeString {
+		if ip, _, err := net.ParseCIDR(lit.Value.(string)); err == nil && ip.To4() == nil {
+			return TypeString, errors.Errorf("cidrnetmask only supports IPv4 prefixes; %q is an IPv6 prefix", lit.Value)
+		}
+	}
+	return TypeString, nil
+}
+
+// bindCIDRSubnet binds a call to `cidrsubnet`.
+func bindCIDRSubnet(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindCoalesce binds a call to `coalesce`. The result has the type shared by all of the arguments, if any. If any
+// argument is an output, so is the result.
+func bindCoalesce(args []BoundExpr) (Type, error) {
+	exprType, isOutput := TypeInvalid, false
+	for _, arg := range args {
+		argType := arg.Type()
+		if exprType == TypeInvalid {
+			exprType = argType.ElementType()
+		} else if exprType != argType.ElementType() {
+			exprType = TypeUnknown
+		}
+		isOutput = isOutput || argType.IsOutput()
+	}
+	if exprType == TypeInvalid {
+		exprType = TypeUnknown
+	}
+
+	if isOutput {
+		exprType = exprType.OutputOf()
+	}
+	return exprType, nil
+}
+
+// bindCoalesceList binds a call to `coalescelist`. The result is typed in the same way as the result of `concat`.
+func bindCoalesceList(args []BoundExpr) (Type, error) {
+	return bindConcat(args)
+}
+
+// bindConcat binds a call to `concat`. The result is a list whose element type is the element type shared by all of
+// the arguments, if any. If any argument is an output, so is the result.
+func bindConcat(args []BoundExpr) (Type, error) {
+	elemType, isOutput := TypeInvalid, false
+	for _, arg := range args {
+		argType := arg.Type()
+		switch {
+		case !argType.IsList():
+			elemType = TypeUnknown
+		case elemType == TypeInvalid:
+			elemType = argType.ElementType()
+		case elemType != argType.ElementType():
+			elemType = TypeUnknown
+		}
+		isOutput = isOutput || argType.IsOutput()
+	}
+	if elemType == TypeInvalid {
+		elemType = TypeUnknown
+	}
+
+	exprType := elemType.ListOf()
+	if isOutput {
+		exprType = exprType.OutputOf()
+	}
+	return exprType, nil
+}
+
+// bindContains binds a call to `contains`. If any argument is an output, so is the result.
+func bindContains(args []BoundExpr) (Type, error) {
+	if isAnyOutput(args) {
+		return TypeBool.OutputOf(), nil
+	}
+	return TypeBool, nil
+}
+
+// bindElement binds a call to `element`. The result has the element type of the list, and is an output if the list is
+// an output.
+func bindElement(args []BoundExpr) (Type, error) {
+	exprType := TypeUnknown
+	if args[0].Type().IsList() {
+		exprType = args[0].Type().ElementType()
+	}
+	if args[0].Type().IsOutput() {
+		exprType = exprType.OutputOf()
+	}
+	return exprType, nil
+}
+
+// bindFile binds a call to `file`.
+func bindFile(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindFormatDate binds a call to `formatdate`. As in Terraform, a literal timestamp must be a valid RFC 3339
+// timestamp.
+func bindFormatDate(args []BoundExpr) (Type, error) {
+	if lit, ok := args[1].(*BoundLiteral); ok && lit.ExprType == TypeString {
+		if _, err := time.Parse(time.RFC3339, lit.Value.(string)); err != nil {
+			return TypeString, errors.Errorf("not a valid RFC3339 timestamp: %q", lit.Value)
+		}
+	}
+	return TypeString, nil
+}
+
+// bindIndexOf binds a call to `index`. If any argument is an output, so is the result.
+func bindIndexOf(args []BoundExpr) (Type, error) {
+	if isAnyOutput(args) {
+		return TypeNumber.OutputOf(), nil
+	}
+	return TypeNumber, nil
+}
+
+// bindJSONDecode binds a call to `jsondecode`. The type of the decoded value is not known until runtime.
+func bindJSONDecode(args []BoundExpr) (Type, error) {
+	return TypeUnknown, nil
+}
+
+// bindJSONEncode binds a call to `jsonencode`.
+func bindJSONEncode(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindKeys binds a call to `keys`. If the map is an output, so is the result.
+func bindKeys(args []BoundExpr) (Type, error) {
+	exprType := TypeString.ListOf()
+	if args[0].Type().IsOutput() {
+		exprType = exprType.OutputOf()
+	}
+	return exprType, nil
+}
+
+// bindLength binds a call to `length`, which accepts a string, a list, or a map.
+func bindLength(args []BoundExpr) (Type, error) {
+	return TypeNumber, nil
+}
+
+// bindListTransform binds a call to a function that produces a new list from the elements of a single list, e.g.
+// `distinct` or `sort`. The result has the type of the list.
+func bindListTransform(args []BoundExpr) (Type, error) {
+	if !args[0].Type().IsList() {
+		return TypeUnknown.ListOf(), nil
+	}
+	return args[0].Type(), nil
+}
+
+// bindLookup binds a call to `lookup`. If the type of the map's elements is unknown, a boolean or numeric default
+// determines the type of the result.
+func bindLookup(args []BoundExpr) (Type, error) {
+	exprType := mapElementType(args[0])
+	if exprType == TypeUnknown && len(args) == 3 {
+		if t := args[2].Type().ElementType(); t == TypeBool || t == TypeNumber {
+			exprType = t
+		}
+	}
+	return exprType, nil
+}
+
+// bindMerge binds a call to `merge`. If any argument is an output, so is the result.
+func bindMerge(args []BoundExpr) (Type, error) {
+	for _, arg := range args {
+		if arg.Type().IsOutput() {
+			return TypeMap.OutputOf(), nil
+		}
+	}
+	return TypeMap, nil
+}
+
+// bindReplace binds a call to `replace`.
+func bindReplace(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindSplit binds a call to `split`.
+func bindSplit(args []BoundExpr) (Type, error) {
+	return TypeString.ListOf(), nil
+}
+
+// bindSubstr binds a call to `substr`.
+func bindSubstr(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindStringTransform binds a call to a function that transforms a single string, e.g. `upper` or `trimspace`.
+func bindStringTransform(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindTimestamp binds a call to `timestamp`.
+func bindTimestamp(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindUUID binds a call to `uuid`.
+func bindUUID(args []BoundExpr) (Type, error) {
+	return TypeString, nil
+}
+
+// bindValues binds a call to `values`. The result is a list of the map's element type, if known. If the map is an
+// output, so is the result.
+func bindValues(args []BoundExpr) (Type, error) {
+	exprType := mapElementType(args[0]).ListOf()
+	if args[0].Type().IsOutput() {
+		exprType = exprType.OutputOf()
+	}
+	return exprType, nil
+}
+
+// bindZipmap binds a call to `zipmap`. The type of the map's elements is computed from the list of values by
+// mapElementType. If either argument is an output, so is the result.
+func bindZipmap(args []BoundExpr) (Type, error) {
+	if isAnyOutput(args) {
+		return TypeMap.OutputOf(), nil
+	}
+	return TypeMap, nil
+}
+
+// isAnyOutput returns true if any of the given expressions is an output.
+func isAnyOutput(args []BoundExpr) bool {
+	for _, arg := range args {
+		if arg.Type().IsOutput() {
+			return true
+		}
+	}
+	return false
+}
@@ -22,7 +22,9 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl/hcl/token"
+	hilast "github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
@@ -62,6 +64,19 @@ type Graph struct {
 	// Variables maps from variable name to variable node for this module's variables. This map is used to bind a
 	// variable access in an interpolation to the corresponding variable node.
 	Variables map[string]*VariableNode
+	// Backend is the module's Terraform state backend, if any.
+	Backend *Backend
+}
+
+// Backend is the analyzed form of a Terraform state backend. Pulumi programs do not configure their own state backend,
+// but the backend's settings are bound so that code generators can describe them.
+type Backend struct {
+	// Config is the backend's raw Terraform configuration.
+	Config *config.Backend
+	// Type is the backend's type (e.g. "s3").
+	Type string
+	// Properties is the bound form of the backend's settings. These settings may only refer to variables.
+	Properties *BoundMapProperty
 }
 
 // A Node represents a single node in a dependency graph. A node is connected to other nodes by dependency edges.
@@ -207,6 +222,26 @@ type VariableNode struct {
 	DefaultValue BoundNode
 }
 
+// Type returns the type of the variable. If the variable does not have a default, its type is its declared type: a list
+// of unknown elements, a map, or a string. If it does have a default, its type is the type of the default. The element
+// type of a list default is determined by the list's elements.
+func (v *VariableNode) Type() Type {
+	if v.DefaultValue == nil {
+		switch v.Config.DeclaredType {
+		case "list":
+			return TypeUnknown.ListOf()
+		case "map":
+			return TypeMap
+		default:
+			return TypeString
+		}
+	}
+	if l, ok := v.DefaultValue.(*BoundListProperty); ok {
+		return commonElementType(l.Elements).ListOf()
+	}
+	return v.DefaultValue.Type()
+}
+
 // nodeSet is a set of Node values.
 type nodeSet map[Node]struct{}
 
@@ -451,8 +486,13 @@ type builder struct {
 	logger                *log.Logger
 	allowMissingProviders bool
 	allowMissingVariables bool
+	accumulateErrors      bool
+
+	// errors holds the binding errors that have been accumulated, if any.
+	errors *multierror.Error
 
 	providerInfo ProviderInfoSource
+	children     map[string]*Graph
 	modules      map[string]*ModuleNode
 	providers    map[string]*ProviderNode
 	resources    map[string]*ResourceNode
@@ -460,14 +500,20 @@ type builder struct {
 	locals       map[string]*LocalNode
 	variables    map[string]*VariableNode
 
+	// sources holds the parsed HCL sources of the config that is being built. See parseSources.
+	sources []sourceFile
+	// positions maps each node to the source positions of its string properties. See extractPositions.
+	positions map[Node]map[string]hilast.Pos
+
 	binding map[Node]bool
 	bound   map[Node]bool
 }
 
 func newBuilder(opts *BuildOptions) *builder {
-	allowMissingProviders, allowMissingVariables := false, false
+	allowMissingProviders, allowMissingVariables, accumulateErrors := false, false, false
 	if opts != nil {
 		allowMissingProviders, allowMissingVariables = opts.AllowMissingProviders, opts.AllowMissingVariables
+		accumulateErrors = opts.AccumulateErrors
 	}
 
 	providerInfo := PluginProviderInfoSource
@@ -476,22 +522,26 @@ func newBuilder(opts *BuildOptions) *builder {
 	}
 
 	var logger *log.Logger
+	var children map[string]*Graph
 	if opts != nil {
-		logger = opts.Logger
+		logger, children = opts.Logger, opts.ChildModules
 	}
 
 	return &builder{
 		logger:                logger,
 		allowMissingProviders: allowMissingProviders,
 		allowMissingVariables: allowMissingVariables,
+		accumulateErrors:      accumulateErrors,
 
 		providerInfo: providerInfo,
+		children:     children,
 		modules:      make(map[string]*ModuleNode),
 		providers:    make(map[string]*ProviderNode),
 		resources:    make(map[string]*ResourceNode),
 		outputs:      make(map[string]*OutputNode),
 		locals:       make(map[string]*LocalNode),
 		variables:    make(map[string]*VariableNode),
+		positions:    make(map[Node]map[string]hilast.Pos),
 
 		binding: make(map[Node]bool),
 		bound:   make(map[Node]bool),
@@ -508,22 +558,35 @@ func (b *builder) logf(format string, arguments ...interface{}) {
 	log.Printf(format, arguments...)
 }
 
-// bindProperty binds a paroperty value with the given schemas. If hasCountIndex is true, this property's
-// interpolations may legally contain references to their container's count variable (i.e. `count,index`).
+// recordError records a binding error if the builder is accumulating errors.
+func (b *builder) recordError(err error) {
+	if b.accumulateErrors {
+		b.errors = multierror.Append(b.errors, err)
+	}
+}
+
+// bindProperty binds a paroperty value of the given owner with the given schemas. If hasCountIndex is true, this
+// property's interpolations may legally contain references to their container's count variable (i.e. `count,index`).
+// If the owner is a resource, references to `self` within this property's interpolations are resolved against its
+// schema. Errors in the property's interpolations are reported at their positions in the owner's source, if known.
 //
 // In addition to the bound property, this function returns the set of nodes referenced by the property's
 // interpolations. If v is nil, the returned BoundNode will also be nil.
 func (b *builder) bindProperty(
-	path string, v interface{}, sch Schemas, hasCountIndex bool) (BoundNode, nodeSet, error) {
+	path string, v interface{}, sch Schemas, hasCountIndex bool, owner Node) (BoundNode, nodeSet, error) {
 
 	if v == nil {
 		return nil, nil, nil
 	}
 
 	// Bind the value.
+	self, _ := owner.(*ResourceNode)
 	binder := &propertyBinder{
 		builder:       b,
 		hasCountIndex: hasCountIndex,
+		self:          self,
+		positions:     b.positions[owner],
+		strict:        !b.accumulateErrors,
 	}
 	prop, err := binder.bindProperty(path, reflect.ValueOf(v), sch)
 	if err != nil {
@@ -547,14 +610,14 @@ func (b *builder) bindProperty(
 
 // bindProperties binds the set of properties represented by the given Terraform config with using the given schema. If
 // hasCountIndex is true, this property's interpolations may legally contain references to their container's count
-// variable (i.e. `count,index`).
+// variable (i.e. `count,index`). The properties belong to the given owner, if any: see bindProperty.
 //
 // In addition to the bound property, this function returns the set of nodes referenced by the property's
 // interpolations.
 func (b *builder) bindProperties(name string, raw *config.RawConfig, sch Schemas,
-	hasCountIndex bool) (*BoundMapProperty, nodeSet, error) {
+	hasCountIndex bool, owner Node) (*BoundMapProperty, nodeSet, error) {
 
-	v, deps, err := b.bindProperty(name, raw.Raw, sch, hasCountIndex)
+	v, deps, err := b.bindProperty(name, raw.Raw, sch, hasCountIndex, owner)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -615,7 +678,13 @@ func (b *builder) getProviderInfo(p *ProviderNode) (*tfbridge.ProviderInfo, stri
 
 // buildModule binds the given module node's properties and computes its dependency edges.
 func (b *builder) buildModule(m *ModuleNode) error {
-	props, deps, err := b.bindProperties(m.Name, m.Config.RawConfig, Schemas{}, false)
+	// If the child module's graph is available, use the types of its variables to type the module's inputs.
+	var sch Schemas
+	if child, ok := b.children[m.Name]; ok {
+		sch = moduleInputSchemas(child)
+	}
+
+	props, deps, err := b.bindProperties(m.Name, m.Config.RawConfig, sch, false, m)
 	if err != nil {
 		return err
 	}
@@ -631,6 +700,32 @@ func (b *builder) buildModule(m *ModuleNode) error {
 	return nil
 }
 
+// moduleInputSchemas returns Schemas that describe the inputs of the given module. Only inputs with primitive types
+// are described: HIL converts values passed to these inputs to the appropriate type. Variables that are declared as
+// lists or maps are not described even if they have no default.
+func moduleInputSchemas(m *Graph) Schemas {
+	fields := make(map[string]*schema.Schema)
+	for name, v := range m.Variables {
+		if v.Config.DeclaredType != "" && v.Config.DeclaredType != "string" {
+			continue
+		}
+
+		var typ schema.ValueType
+		switch v.Type() {
+		case TypeBool:
+			typ = schema.TypeBool
+		case TypeNumber:
+			typ = schema.TypeFloat
+		case TypeString:
+			typ = schema.TypeString
+		default:
+			continue
+		}
+		fields[name] = &schema.Schema{Type: typ, Optional: v.DefaultValue != nil, Required: v.DefaultValue == nil}
+	}
+	return Schemas{TFRes: &schema.Resource{Schema: fields}}
+}
+
 // buildProvider fetches the given provider's tfbridge data, binds its properties, and computes its dependency edges.
 func (b *builder) buildProvider(p *ProviderNode) error {
 	info, pluginName, err := b.getProviderInfo(p)
@@ -639,12 +734,16 @@ func (b *builder) buildProvider(p *ProviderNode) error {
 			return err
 		}
 
-		b.logf("warning: %v\ngenerated code for resources using this provider may be incorrect", err)
+		if fallback, ok := fallbackProviderInfo[p.Name]; ok {
+			info = fallback
+		} else {
+			b.logf("warning: %v\ngenerated code for resources using this provider may be incorrect", err)
+		}
 		pluginName = p.Name
 	}
 	p.Info, p.PluginName = info, pluginName
 
-	props, deps, err := b.bindProperties(p.Name, p.Config.RawConfig, Schemas{}, false)
+	props, deps, err := b.bindProperties(p.Name, p.Config.RawConfig, Schemas{}, false, p)
 	if err != nil {
 		return err
 	}
@@ -666,18 +765,27 @@ func (b *builder) ensureProvider(r *ResourceNode) error {
 	p, ok := b.providers[providerName]
 	if !ok {
 		// It is possible to reference a provider that is not present in the Terraform configuration. In this case,
-		// we create a new provider node with an empty configuration and insert it into the graph.
+		// we create a new provider node with an empty configuration and insert it into the graph. This is also the case
+		// for aliased providers that are passed to a child module by its parent, e.g. `providers = { aws = aws.west }`:
+		// the provider's type is the portion of its full name before the alias.
 		rawConfig, err := config.NewRawConfig(map[string]interface{}{})
 		if err != nil {
 			return err
 		}
 
+		name, alias := providerName, ""
+		if dot := strings.Index(providerName, "."); dot != -1 {
+			name, alias = providerName[:dot], providerName[dot+1:]
+		}
+
 		p = &ProviderNode{
 			Config: &config.ProviderConfig{
-				Name:      providerName,
+				Name:      name,
+				Alias:     alias,
 				RawConfig: rawConfig,
 			},
-			Name:     providerName,
+			Name:     name,
+			Alias:    alias,
 			Implicit: true,
 		}
 		b.providers[providerName] = p
@@ -781,7 +889,7 @@ func (b *builder) buildResource(r *ResourceNode) error {
 
 	tfName := r.Type + "." + r.Name
 
-	count, countDeps, err := b.bindProperty(tfName+".count", r.Config.RawCount.Value(), Schemas{}, false)
+	count, countDeps, err := b.bindProperty(tfName+".count", r.Config.RawCount.Value(), Schemas{}, false, r)
 	if err != nil {
 		return err
 	}
@@ -798,8 +906,24 @@ func (b *builder) buildResource(r *ResourceNode) error {
 		}
 	}
 
+	// Pulumi cannot create a number of resources that depends on the value of an output. If the count refers to any
+	// managed resources, fail binding or, if errors are being accumulated, replace the count with an error that
+	// explains why it cannot be converted.
+	if countExpr, ok := count.(BoundExpr); ok {
+		if err := b.checkCountDependencies(tfName, countExpr); err != nil {
+			if !b.accumulateErrors {
+				return err
+			}
+			count = &BoundError{NodeType: TypeNumber, Error: err}
+			b.recordError(err)
+		} else {
+			// Terraform 0.11 configs often pass counts as strings (e.g. "${var.count}"). Coerce these to numbers.
+			count = makeCoercion(countExpr, TypeNumber)
+		}
+	}
+
 	// Bind the resource's properties.
-	props, deps, err := b.bindProperties(tfName, r.Config.RawConfig, r.Schemas(), count != nil)
+	props, deps, err := b.bindProperties(tfName, r.Config.RawConfig, r.Schemas(), count != nil, r)
 	if err != nil {
 		return err
 	}
@@ -837,9 +961,63 @@ func (b *builder) buildResource(r *ResourceNode) error {
 	return nil
 }
 
+// checkCountDependencies returns an error if the given count expression depends on the outputs of any managed
+// resources or of any data sources that are not prompt (i.e. data sources whose inputs depend on outputs). Such counts
+// are not known until the program runs, and cannot be used to determine the number of instances to create.
+func (b *builder) checkCountDependencies(tfName string, count BoundExpr) error {
+	resourceSet := map[string]bool{}
+	_, err := VisitBoundExpr(count, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
+		if v, ok := n.(*BoundVariableAccess); ok {
+			if r, ok := v.ILNode.(*ResourceNode); ok && b.dependsOnOutputs(v) {
+				resourceSet[r.Config.Id()] = true
+			}
+		}
+		return n, nil
+	})
+	contract.Assert(err == nil)
+
+	if len(resourceSet) == 0 {
+		return nil
+	}
+	resources := make([]string, 0, len(resourceSet))
+	for r := range resourceSet {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	return errors.Errorf("the count of %s depends on the outputs of %s, which are not known until the program runs; "+
+		"the count must be resolved to a known value (e.g. a literal or a variable)", tfName,
+		strings.Join(resources, ", "))
+}
+
+// dependsOnOutputs returns true if the given node refers to the outputs of a managed resource, a data source whose
+// inputs depend on outputs, or a module, either directly or via a local value. This mirrors the analysis performed by
+// MarkPromptDataSources, which can only be run once the graph has been built: a data source is prompt if and only if
+// its properties do not depend on outputs.
+func (b *builder) dependsOnOutputs(n BoundNode) bool {
+	dependsOnOutputs := false
+	err := WalkBoundNodes(n, func(n BoundNode) (WalkAction, error) {
+		if v, ok := n.(*BoundVariableAccess); ok {
+			switch node := v.ILNode.(type) {
+			case *ResourceNode:
+				dependsOnOutputs = !node.IsDataSource || node.Properties != nil && b.dependsOnOutputs(node.Properties)
+			case *LocalNode:
+				dependsOnOutputs = node.Value != nil && b.dependsOnOutputs(node.Value)
+			case *ModuleNode:
+				dependsOnOutputs = v.Type().IsOutput()
+			}
+			if dependsOnOutputs {
+				return WalkStop, nil
+			}
+		}
+		return WalkContinue, nil
+	}, ContinueWalker)
+	contract.Assert(err == nil)
+	return dependsOnOutputs
+}
+
 // buildOutput binds an output's value and computes its dependency edges.
 func (b *builder) buildOutput(o *OutputNode) error {
-	props, deps, err := b.bindProperties(o.Name, o.Config.RawConfig, Schemas{}, false)
+	props, deps, err := b.bindProperties(o.Name, o.Config.RawConfig, Schemas{}, false, o)
 	if err != nil {
 		return err
 	}
@@ -863,7 +1041,7 @@ func (b *builder) buildOutput(o *OutputNode) error {
 
 // buildLocal binds a local value's value and computes its dependency edges.
 func (b *builder) buildLocal(l *LocalNode) error {
-	props, deps, err := b.bindProperties(l.Name, l.Config.RawConfig, Schemas{}, false)
+	props, deps, err := b.bindProperties(l.Name, l.Config.RawConfig, Schemas{}, false, l)
 	if err != nil {
 		return err
 	}
@@ -887,17 +1065,39 @@ func (b *builder) buildLocal(l *LocalNode) error {
 
 // buildVariable builds a variable's default value (if any). This value must not depend on any other nodes.
 func (b *builder) buildVariable(v *VariableNode) error {
-	defaultValue, deps, err := b.bindProperty(v.Name+".default", v.Config.Default, Schemas{}, false)
+	defaultValue, deps, err := b.bindProperty(v.Name+".default", v.Config.Default, Schemas{}, false, v)
 	if err != nil {
 		return err
 	}
 	if len(deps) != 0 {
 		return errors.Errorf("variables may not depend on other nodes (%v)", v.Name)
 	}
+
+	// The keys of a map-typed default are map keys rather than property names, so mark the default as a map in order
+	// to prevent them from being renamed.
+	if m, ok := defaultValue.(*BoundMapProperty); ok && m.Schemas.TF == nil {
+		m.Schemas.TF = &schema.Schema{Type: schema.TypeMap}
+	}
 	v.DefaultValue = defaultValue
 	return nil
 }
 
+// buildBackend binds the settings of the given Terraform state backend. Terraform itself does not allow these settings
+// to contain interpolations, but some tools that wrap Terraform do. Such settings may only refer to variables.
+func (b *builder) buildBackend(backend *config.Backend) (*Backend, error) {
+	props, deps, err := b.bindProperties("terraform.backend", backend.RawConfig, Schemas{}, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	for n := range deps {
+		if _, ok := n.(*VariableNode); !ok {
+			return nil, errors.Errorf("terraform.backend: backend settings may only refer to variables (%v)",
+				n.displayName())
+		}
+	}
+	return &Backend{Config: backend, Type: backend.Type, Properties: props}, nil
+}
+
 // ensureBound ensures that the indicated node is bound. If the node is not bound, this method will bind it. If the
 // node is currently being bound, this method will return an error due to the circular reference.
 func (b *builder) ensureBound(n Node) error {
@@ -973,6 +1173,9 @@ func (b *builder) buildNodes(conf *config.Config) error {
 		}
 	}
 
+	// Record the source positions of the nodes' properties so that binding errors can refer to them.
+	b.extractPositions()
+
 	// Now bind each node's properties and compute any dependency edges.
 	for _, v := range b.variables {
 		if err := b.ensureBound(v); err != nil {
@@ -1021,26 +1224,46 @@ type BuildOptions struct {
 	AllowMissingVariables bool
 	// AllowMissingComments allows binding to succeed even if there are errors extracting comments from the source.
 	AllowMissingComments bool
+	// ChildModules maps from module name to the graph for each of the module's children, if available. If a child's
+	// graph is present, references to its outputs are typed according to the outputs' values.
+	ChildModules map[string]*Graph
+	// AccumulateErrors allows binding to continue past interpolations that fail to bind. Each such interpolation is
+	// bound as an error node, and all of the binding errors in the module--including errors that are always bound as
+	// error nodes, such as calls to unsupported functions--are returned together once the graph has been built.
+	AccumulateErrors bool
 }
 
 // BuildGraph analyzes the various entities present in the given module's configuration and constructs the
 // corresponding dependency graph. Building the graph involves binding each entity's properties (if any) and
 // computing its list of dependency edges.
+//
+// If opts.AccumulateErrors is set and any binding errors occurred, BuildGraph returns the partially-bound graph along
+// with a *multierror.Error that describes each of the errors.
 func BuildGraph(tree *module.Tree, opts *BuildOptions) (*Graph, error) {
 	b := newBuilder(opts)
 
 	conf := tree.Config()
 
-	if err := b.buildNodes(conf); err != nil {
+	// Parse the tree's sources so that comments and source positions can be extracted from them.
+	if err := b.parseSources(conf); err != nil && !opts.AllowMissingComments {
 		return nil, err
 	}
 
-	// Attempt to extract comments from the tree's sources and associate them with the appropriate constructs in the
-	// bound graph.
-	if err := b.extractComments(conf); err != nil && !opts.AllowMissingComments {
+	if err := b.buildNodes(conf); err != nil {
 		return nil, err
 	}
 
+	var backend *Backend
+	if conf.Terraform != nil && conf.Terraform.Backend != nil {
+		var err error
+		if backend, err = b.buildBackend(conf.Terraform.Backend); err != nil {
+			return nil, err
+		}
+	}
+
+	// Extract comments from the tree's sources and associate them with the appropriate constructs in the bound graph.
+	b.extractComments()
+
 	// Put the graph together
 	return &Graph{
 		Tree:      tree,
@@ -1053,5 +1276,6 @@ func BuildGraph(tree *module.Tree, opts *BuildOptions) (*Graph, error) {
 		Outputs:   b.outputs,
 		Locals:    b.locals,
 		Variables: b.variables,
-	}, nil
+		Backend:   backend,
+	}, b.errors.ErrorOrNil()
 }
@@ -40,19 +40,54 @@ type commentable interface {
 	setComments(c *Comments)
 }
 
-// extractComments annotates the builder's nodes with comments extracted from the given config's HCL sources. This
-// is a best-effort process: not all comments are extractable due to weaknesses in the HCL parser. This process will
-// only fail if files are unreadable or unparseable.
-func (b *builder) extractComments(c *config.Config) error {
+// sourceFile is a parsed HCL source file.
+type sourceFile struct {
+	name string
+	file *ast.File
+}
+
+// parseSources parses the given config's HCL sources. The parsed sources are used to extract comments and the
+// positions of properties. This process will only fail if files are unreadable or unparseable, in which case the
+// sources that were parsed before the failure are retained.
+func (b *builder) parseSources(c *config.Config) error {
+	files, err := sourceFiles(c)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		t, err := afero.ReadFile(c.Fs, f)
+		if err != nil {
+			return err
+		}
+		file, err := hcl.ParseBytes(t)
+		if err != nil {
+			return err
+		}
+		b.sources = append(b.sources, sourceFile{name: path.Base(f), file: file})
+	}
+	return nil
+}
+
+// extractComments annotates the builder's nodes with comments extracted from its parsed sources. This is a
+// best-effort process: not all comments are extractable due to weaknesses in the HCL parser.
+func (b *builder) extractComments() {
+	for _, s := range b.sources {
+		b.extractHCLComments(s.file, s.name)
+	}
+}
+
+// sourceFiles returns the paths of the given config's HCL sources. Override files are returned after all other files.
+// If the config has no directory, sourceFiles returns no paths.
+func sourceFiles(c *config.Config) ([]string, error) {
 	// Nothing we can do if `Dir` is empty.
 	if c.Dir == "" {
-		return nil
+		return nil, nil
 	}
 
 	// Find all config and/or override files in the directory.
 	files, err := afero.ReadDir(c.Fs, c.Dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var configs, overrides []string
 	for _, f := range files {
@@ -62,39 +97,12 @@ func (b *builder) extractComments(c *config.Config) error {
 
 		// Check to see if the file is an override.
 		if n := f.Name()[:len(f.Name())-len(".tf")]; n == "override" || strings.HasSuffix(n, "_override") {
-			overrides = append(overrides, f.Name())
+			overrides = append(overrides, path.Join(c.Dir, f.Name()))
 		} else {
-			configs = append(configs, f.Name())
-		}
-	}
-
-	for _, f := range configs {
-		if err := b.extractFileComments(c.Fs, path.Join(c.Dir, f)); err != nil {
-			return err
+			configs = append(configs, path.Join(c.Dir, f.Name()))
 		}
 	}
-	for _, f := range overrides {
-		if err := b.extractFileComments(c.Fs, path.Join(c.Dir, f)); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// extractFileComments extracts comments from a particular HCL source file.
-func (b *builder) extractFileComments(fs afero.Fs, filePath string) error {
-	t, err := afero.ReadFile(fs, filePath)
-	if err != nil {
-		return err
-	}
-
-	f, err := hcl.ParseBytes(t)
-	if err != nil {
-		return err
-	}
-
-	b.extractHCLComments(f, path.Base(filePath))
-	return nil
+	return append(configs, overrides...), nil
 }
 
 // extractHCLComments extracts comments from the given HCL file.
@@ -149,10 +157,22 @@ func (b *builder) extractVariableComments(item *ast.ObjectItem, path string) {
 // extractProviderComments extracts comments from the given HCL object item and attaches them to the corresponding
 // provider node, if any exists.
 func (b *builder) extractProviderComments(item *ast.ObjectItem, path string) {
+	p, ok := b.providerNode(item)
+	if !ok {
+		return
+	}
+
+	attachLocation(p, item.Pos(), path)
+	attachComments(p, item.LeadComment, item.LineComment)
+	b.extractNodeComments(item.Val, p.Properties)
+}
+
+// providerNode returns the provider node that corresponds to the given HCL object item, if any exists.
+func (b *builder) providerNode(item *ast.ObjectItem) (*ProviderNode, bool) {
 	// We need the provider's alias in order to look up its node. This requires parsing the body of the item.
 	object, ok := item.Val.(*ast.ObjectType)
 	if !ok {
-		return
+		return nil, false
 	}
 
 	alias := ""
@@ -167,13 +187,7 @@ func (b *builder) extractProviderComments(item *ast.ObjectItem, path string) {
 
 	name := item.Keys[1].Token.Value().(string)
 	p, ok := b.providers[(&config.ProviderConfig{Name: name, Alias: alias}).FullName()]
-	if !ok {
-		return
-	}
-
-	attachLocation(p, item.Pos(), path)
-	attachComments(p, item.LeadComment, item.LineComment)
-	b.extractNodeComments(item.Val, p.Properties)
+	return p, ok
 }
 
 // extractModuleComments extracts comments from the given HCL object item and attaches them to the corresponding
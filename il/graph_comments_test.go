@@ -141,12 +141,14 @@ output "security_group_name" {
 		AllowMissingVariables: true,
 		AllowMissingComments:  true,
 	})
-	err = b.buildNodes(conf)
+	err = b.parseSources(conf)
 	assert.NoError(t, err)
 
-	err = b.extractComments(conf)
+	err = b.buildNodes(conf)
 	assert.NoError(t, err)
 
+	b.extractComments()
+
 	v := b.variables["aws_region"]
 	assert.True(t, v.Location.IsValid())
 	assert.Equal(t, "main.tf", v.Location.Filename)
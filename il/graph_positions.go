@@ -0,0 +1,149 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+	hilast "github.com/hashicorp/hil/ast"
+
+	"github.com/pulumi/tf2pulumi/internal/config"
+)
+
+// extractPositions records the source positions of the string properties of the builder's nodes. These positions are
+// used to report the positions of binding errors relative to the source file rather than to the interpolation string
+// that contains them. Positions are extracted from the builder's parsed sources, and must be extracted before the
+// nodes are bound.
+func (b *builder) extractPositions() {
+	for _, s := range b.sources {
+		b.extractHCLPositions(s.file, s.name)
+	}
+}
+
+// extractHCLPositions records the positions of the string properties of each node defined in the given HCL file.
+func (b *builder) extractHCLPositions(f *ast.File, filename string) {
+	root, ok := f.Node.(*ast.ObjectList)
+	if !ok {
+		return
+	}
+
+	for _, n := range root.Items {
+		switch n.Keys[0].Token.Value().(string) {
+		case "variable":
+			if v, ok := b.variables[n.Keys[1].Token.Value().(string)]; ok {
+				b.recordPositions(v, v.Name, n.Val, filename)
+			}
+		case "provider":
+			if p, ok := b.providerNode(n); ok {
+				b.recordPositions(p, p.Name, n.Val, filename)
+			}
+		case "module":
+			if m, ok := b.modules[n.Keys[1].Token.Value().(string)]; ok {
+				b.recordPositions(m, m.Name, n.Val, filename)
+			}
+		case "resource", "data":
+			mode := config.ManagedResourceMode
+			if n.Keys[0].Token.Value().(string) == "data" {
+				mode = config.DataResourceMode
+			}
+			cfg := &config.Resource{
+				Mode: mode,
+				Name: n.Keys[2].Token.Value().(string),
+				Type: n.Keys[1].Token.Value().(string),
+			}
+			if r, ok := b.resources[cfg.Id()]; ok {
+				b.recordPositions(r, r.Type+"."+r.Name, n.Val, filename)
+			}
+		case "locals":
+			if object, ok := n.Val.(*ast.ObjectType); ok {
+				for _, ln := range object.List.Items {
+					if l, ok := b.locals[ln.Keys[0].Token.Value().(string)]; ok {
+						// A local's value is bound as the "value" property of its config.
+						positions := b.nodePositions(l)
+						recordValuePositions(positions, l.Name+".value", ln.Val, filename)
+					}
+				}
+			}
+		case "output":
+			if o, ok := b.outputs[n.Keys[1].Token.Value().(string)]; ok {
+				b.recordPositions(o, o.Name, n.Val, filename)
+			}
+		}
+	}
+}
+
+// nodePositions returns the map of property positions for the given node, creating it if necessary.
+func (b *builder) nodePositions(n Node) map[string]hilast.Pos {
+	positions, ok := b.positions[n]
+	if !ok {
+		positions = make(map[string]hilast.Pos)
+		b.positions[n] = positions
+	}
+	return positions
+}
+
+// recordPositions records the positions of the string properties in the given node's HCL body. The paths of these
+// properties are relative to the given prefix.
+func (b *builder) recordPositions(n Node, prefix string, body ast.Node, filename string) {
+	if object, ok := body.(*ast.ObjectType); ok {
+		recordObjectPositions(b.nodePositions(n), prefix, object, filename)
+	}
+}
+
+// recordObjectPositions records the positions of the string properties in the given HCL object. Nested objects are
+// decoded as lists of maps, so the path of each nested object includes its index among the items with the same key.
+func recordObjectPositions(positions map[string]hilast.Pos, prefix string, object *ast.ObjectType, filename string) {
+	counts := make(map[string]int)
+	for _, item := range object.List.Items {
+		if len(item.Keys) != 1 {
+			continue
+		}
+
+		key, ok := item.Keys[0].Token.Value().(string)
+		if !ok {
+			continue
+		}
+		if nested, ok := item.Val.(*ast.ObjectType); ok {
+			recordObjectPositions(positions, fmt.Sprintf("%s.%s[%d]", prefix, key, counts[key]), nested, filename)
+			counts[key]++
+			continue
+		}
+		recordValuePositions(positions, prefix+"."+key, item.Val, filename)
+	}
+}
+
+// recordValuePositions records the positions of the string properties in the given HCL value.
+func recordValuePositions(positions map[string]hilast.Pos, path string, node ast.Node, filename string) {
+	switch node := node.(type) {
+	case *ast.LiteralType:
+		pos := node.Token.Pos
+		switch node.Token.Type {
+		case token.STRING:
+			// Skip the opening quote.
+			positions[path] = hilast.Pos{Filename: filename, Line: pos.Line, Column: pos.Column + 1}
+		case token.HEREDOC:
+			// The heredoc's contents begin on the line after its marker.
+			positions[path] = hilast.Pos{Filename: filename, Line: pos.Line + 1, Column: 1}
+		}
+	case *ast.ListType:
+		for i, elem := range node.List {
+			recordValuePositions(positions, fmt.Sprintf("%s[%d]", path, i), elem, filename)
+		}
+	case *ast.ObjectType:
+		recordObjectPositions(positions, path+"[0]", node, filename)
+	}
+}
@@ -1,8 +1,13 @@
 package il
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
+	"github.com/hashicorp/go-multierror"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pulumi/tf2pulumi/internal/config"
@@ -136,3 +141,547 @@ func TestMetaProperties(t *testing.T) {
 		"userDataBase64",
 	}, r3.IgnoreChanges)
 }
+
+// loadSource loads the Terraform config for the given source. The config's directory is removed when the test
+// completes so that the source is available to the graph builder.
+func loadSource(t *testing.T, source string) *config.Config {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	t.Cleanup(func() {
+		contract.IgnoreError(os.RemoveAll(dir))
+	})
+
+	err = ioutil.WriteFile(path.Join(dir, "main.tf"), []byte(source), 0600)
+	if err != nil {
+		t.Fatalf("could not create main.tf: %v", err)
+	}
+
+	conf, err := config.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	return conf
+}
+
+// buildSource builds the graph for the given Terraform source. Missing providers are allowed.
+func buildSource(t *testing.T, source string) *Graph {
+	return buildSourceWithProviders(t, source, nil)
+}
+
+// buildSourceWithProviders builds the graph for the given Terraform source using the given provider information.
+// Missing providers are allowed.
+func buildSourceWithProviders(t *testing.T, source string, providers ProviderInfoSource) *Graph {
+	g, err := BuildGraph(module.NewTree("main", loadSource(t, source)), &BuildOptions{
+		ProviderInfoSource:    providers,
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	})
+	if err != nil {
+		t.Fatalf("could not build graph: %v", err)
+	}
+	return g
+}
+
+func TestOutputDependentCount(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+data "aws_availability_zones" "available" {}
+
+data "aws_subnet_ids" "main" {
+  vpc_id = "${aws_vpc.main.id}"
+}
+
+resource "aws_subnet" "known" {
+  count = "${length(data.aws_availability_zones.available.names)}"
+}
+
+resource "aws_subnet" "unknown" {
+  count  = "${length(aws_vpc.main.ipv6_cidr_block) + length(aws_vpc.main.id)}"
+  vpc_id = "${aws_vpc.main.id}"
+}
+
+resource "aws_instance" "unknown" {
+  count = "${length(data.aws_subnet_ids.main.ids)}"
+}
+`
+	tree := module.NewTree("main", loadSource(t, source))
+
+	// By default, binding fails at the first count that depends on outputs.
+	_, err := BuildGraph(tree, &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingVariables: true,
+		AllowMissingComments:  true,
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "depends on the outputs of")
+	}
+
+	// When accumulating errors, each such count is replaced with an error.
+	g, err := BuildGraph(tree, &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingVariables: true,
+		AllowMissingComments:  true,
+		AccumulateErrors:      true,
+	})
+	if assert.IsType(t, &multierror.Error{}, err) {
+		assert.Len(t, err.(*multierror.Error).Errors, 2)
+	}
+	if g == nil {
+		t.FailNow()
+	}
+
+	// A count that depends on a data source whose inputs are known is itself known.
+	assert.IsType(t, &BoundCall{}, g.Resources["aws_subnet.known"].Count)
+
+	// Counts that depend on the outputs of managed resources or of data sources whose inputs depend on outputs are
+	// flagged.
+	count := g.Resources["aws_subnet.unknown"].Count
+	if assert.IsType(t, &BoundError{}, count) {
+		assert.Equal(t, "the count of aws_subnet.unknown depends on the outputs of aws_vpc.main, which are not "+
+			"known until the program runs; the count must be resolved to a known value (e.g. a literal or a "+
+			"variable)", count.(*BoundError).Error.Error())
+	}
+	count = g.Resources["aws_instance.unknown"].Count
+	if assert.IsType(t, &BoundError{}, count) {
+		assert.Equal(t, "the count of aws_instance.unknown depends on the outputs of data.aws_subnet_ids.main, which "+
+			"are not known until the program runs; the count must be resolved to a known value (e.g. a literal or a "+
+			"variable)", count.(*BoundError).Error.Error())
+	}
+}
+
+func TestChildModuleOutputs(t *testing.T) {
+	const childSource = `
+variable "name" {}
+
+resource "aws_vpc" "main" {
+  tags {
+    Name = "${var.name}"
+  }
+}
+
+output "vpc_id" {
+  value = "${aws_vpc.main.id}"
+}
+
+output "names" {
+  value = ["${var.name}"]
+}
+`
+	const parentSource = `
+module "child" {
+  source = "./child"
+  name   = "parent"
+}
+
+resource "aws_subnet" "subnet" {
+  vpc_id = "${module.child.vpc_id}"
+  name   = "${element(module.child.names, 0)}"
+}
+`
+	opts := &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	}
+
+	child, err := BuildGraph(module.NewTree("child", loadSource(t, childSource)), opts)
+	if err != nil {
+		t.Fatalf("could not build child graph: %v", err)
+	}
+
+	opts.ChildModules = map[string]*Graph{"child": child}
+	parent, err := BuildGraph(module.NewTree("main", loadSource(t, parentSource)), opts)
+	if err != nil {
+		t.Fatalf("could not build parent graph: %v", err)
+	}
+
+	subnet := parent.Resources["aws_subnet.subnet"]
+	vpcID := subnet.Properties.Elements["vpc_id"].(*BoundVariableAccess)
+	assert.Equal(t, parent.Modules["child"], vpcID.ILNode)
+	assert.Equal(t, TypeString.OutputOf(), vpcID.Type())
+
+	name := subnet.Properties.Elements["name"].(*BoundCall)
+	assert.Equal(t, TypeUnknown.ListOf().OutputOf(), name.Args[0].Type())
+
+	// References to outputs that do not exist in the child module are errors.
+	const missingSource = `
+module "child" {
+  source = "./child"
+}
+
+resource "aws_subnet" "subnet" {
+  vpc_id = "${module.child.missing}"
+}
+`
+	_, err = BuildGraph(module.NewTree("main", loadSource(t, missingSource)), opts)
+	assert.Error(t, err)
+}
+
+func TestModuleProviderPassing(t *testing.T) {
+	const childSource = `
+data "external" "west" {
+  provider = "external.west"
+  program  = ["echo"]
+}
+
+output "result" {
+  value = "${data.external.west.result}"
+}
+`
+	const parentSource = `
+provider "external" {
+  alias = "west"
+}
+
+module "child" {
+  source = "./child"
+
+  providers = {
+    "external.west" = "external.west"
+  }
+}
+
+resource "aws_subnet" "subnet" {
+  vpc_id = "${lookup(module.child.result, "vpc_id")}"
+}
+`
+	opts := &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	}
+
+	child, err := BuildGraph(module.NewTree("child", loadSource(t, childSource)), opts)
+	if err != nil {
+		t.Fatalf("could not build child graph: %v", err)
+	}
+
+	// The aliased provider passed by the parent is typed by its provider name rather than its full name.
+	west := child.Resources["data.external.west"]
+	assert.Equal(t, "external", west.Provider.Name)
+	assert.Equal(t, "west", west.Provider.Alias)
+	assert.Equal(t, "external.west", west.Provider.Config.FullName())
+	assert.NotNil(t, west.Schemas().TFRes)
+
+	opts.ChildModules = map[string]*Graph{"child": child}
+	parent, err := BuildGraph(module.NewTree("main", loadSource(t, parentSource)), opts)
+	if err != nil {
+		t.Fatalf("could not build parent graph: %v", err)
+	}
+
+	lookup := parent.Resources["aws_subnet.subnet"].Properties.Elements["vpc_id"].(*BoundCall)
+	assert.Equal(t, TypeMap.OutputOf(), lookup.Args[0].Type())
+}
+
+func TestExplicitInstanceIndex(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+}
+
+resource "aws_instance" "single" {}
+
+resource "aws_eip" "eip" {
+  first        = "${aws_instance.web.0.id}"
+  last         = "${aws_instance.web.1.id}"
+  out_of_range = "${aws_instance.web.2.id}"
+  single       = "${aws_instance.single.0.id}"
+  past_single  = "${aws_instance.single.1.id}"
+}
+`
+	g := buildSource(t, source)
+
+	eip := g.Resources["aws_eip.eip"]
+	if !assert.NotNil(t, eip) {
+		return
+	}
+	props := eip.Properties.Elements
+
+	for _, k := range []string{"first", "last", "single"} {
+		assert.IsType(t, &BoundVariableAccess{}, props[k], k)
+	}
+
+	if assert.IsType(t, &BoundError{}, props["out_of_range"]) {
+		err := props["out_of_range"].(*BoundError)
+		assert.Equal(t, TypeString.OutputOf(), err.Type())
+		assert.Equal(t, "main.tf:11:21: index 2 is out of range for aws_instance.web, which has a count of 2",
+			err.Error.Error())
+	}
+	if assert.IsType(t, &BoundError{}, props["past_single"]) {
+		assert.Equal(t, "main.tf:13:21: index 1 is out of range for aws_instance.single, which has a count of 1",
+			props["past_single"].(*BoundError).Error.Error())
+	}
+}
+
+func TestExternalDataSource(t *testing.T) {
+	const source = `
+data "external" "x" {
+  program = ["python", "x.py"]
+}
+
+resource "aws_s3_bucket" "b" {
+  result = "${data.external.x.result}"
+  foo    = "${data.external.x.result["foo"]}"
+  bar    = "${data.external.x.result.bar}"
+}
+`
+	g := buildSource(t, source)
+
+	x := g.Resources["data.external.x"]
+	if !assert.NotNil(t, x) {
+		return
+	}
+	tok, ok := x.Tok()
+	assert.True(t, ok)
+	assert.Equal(t, "external:index/getExternal:getExternal", tok)
+
+	props := g.Resources["aws_s3_bucket.b"].Properties.Elements
+	assert.Equal(t, TypeMap.OutputOf(), props["result"].Type())
+	assert.Equal(t, TypeString.OutputOf(), props["foo"].Type())
+	assert.Equal(t, TypeString.OutputOf(), props["bar"].Type())
+}
+
+func TestBackend(t *testing.T) {
+	const source = `
+variable "bucket" {}
+
+terraform {
+  backend "s3" {
+    bucket = "${var.bucket}"
+    key    = "state"
+  }
+}
+`
+	g := buildSource(t, source)
+	if !assert.NotNil(t, g.Backend) {
+		return
+	}
+	assert.Equal(t, "s3", g.Backend.Type)
+	bucket, ok := g.Backend.Properties.Elements["bucket"].(*BoundVariableAccess)
+	if assert.True(t, ok) {
+		assert.Equal(t, g.Variables["bucket"], bucket.ILNode)
+	}
+
+	// Backend settings may not refer to anything other than variables.
+	const resourceSource = `
+resource "aws_s3_bucket" "state" {}
+
+terraform {
+  backend "s3" {
+    bucket = "${aws_s3_bucket.state.bucket}"
+  }
+}
+`
+	_, err := BuildGraph(module.NewTree("main", loadSource(t, resourceSource)), &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+	})
+	assert.Error(t, err)
+}
+
+func TestOutputIndex(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["a", "b"]
+}
+
+resource "aws_instance" "x" {
+  count = 2
+}
+
+resource "aws_s3_bucket" "b" {
+  index   = "${aws_instance.x.*.id[0]}"
+  element = "${element(aws_instance.x.*.id, 1)}"
+  lookup  = "${lookup(aws_instance.x.0.root_block_device[0], "volume_size")}"
+  known   = "${var.names[0]}"
+}
+`
+	g := buildSource(t, source)
+
+	props := g.Resources["aws_s3_bucket.b"].Properties.Elements
+	assert.Equal(t, TypeString.OutputOf(), props["index"].Type())
+	assert.Equal(t, TypeString.OutputOf(), props["element"].Type())
+	if assert.IsType(t, &BoundCall{}, props["lookup"]) {
+		assert.Equal(t, TypeUnknown.OutputOf(), props["lookup"].(*BoundCall).Args[0].Type())
+	}
+	assert.Equal(t, TypeString, props["known"].Type())
+}
+
+func TestCountIndexAndSplat(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  count = 2
+}
+
+locals {
+  ids = "${aws_instance.web.*.id}"
+}
+
+resource "aws_eip" "ip" {
+  count    = 2
+  instance = "${element(aws_instance.web.*.id, count.index)}"
+  name     = "${element(local.ids, count.index)}-${count.index}"
+}
+`
+	g := buildSource(t, source)
+
+	props := g.Resources["aws_eip.ip"].Properties.Elements
+	if assert.IsType(t, &BoundCall{}, props["instance"]) {
+		args := props["instance"].(*BoundCall).Args
+
+		splat := args[0].(*BoundVariableAccess)
+		rv := splat.TFVar.(*config.ResourceVariable)
+		assert.True(t, rv.Multi)
+		assert.Equal(t, -1, rv.Index)
+		assert.Equal(t, TypeString.ListOf().OutputOf(), splat.Type())
+
+		index := args[1].(*BoundVariableAccess)
+		assert.IsType(t, &config.CountVariable{}, index.TFVar)
+		assert.Equal(t, TypeNumber, index.Type())
+	}
+	if assert.IsType(t, &BoundOutput{}, props["name"]) {
+		exprs := props["name"].(*BoundOutput).Exprs
+		element := exprs[0].(*BoundCall)
+		assert.Equal(t, g.Locals["ids"], element.Args[0].(*BoundVariableAccess).ILNode)
+		assert.IsType(t, &config.CountVariable{}, element.Args[1].(*BoundVariableAccess).TFVar)
+		assert.IsType(t, &config.CountVariable{}, exprs[2].(*BoundVariableAccess).TFVar)
+	}
+}
+
+func TestProviderAttributes(t *testing.T) {
+	const source = `
+variable "region" {}
+
+provider "aws" {
+  region = "${var.region}"
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+}
+
+resource "aws_instance" "x" {
+  provider = "aws.west"
+
+  tags {
+    Region        = "${provider.aws.west.region}"
+    DefaultRegion = "${provider.aws.region}"
+    Project       = "${provider.google.project}"
+  }
+}
+`
+	g := buildSource(t, source)
+
+	tags := g.Resources["aws_instance.x"].Properties.Elements["tags"].(*BoundListProperty)
+	props := tags.Elements[0].(*BoundMapProperty).Elements
+
+	// Attributes that are set to literals are resolved against the provider's configuration.
+	if assert.IsType(t, &BoundLiteral{}, props["Region"]) {
+		assert.Equal(t, "us-west-2", props["Region"].(*BoundLiteral).Value)
+	}
+
+	// Other references are bound as errors.
+	if assert.IsType(t, &BoundError{}, props["DefaultRegion"]) {
+		assert.Contains(t, props["DefaultRegion"].(*BoundError).Error.Error(), "must be set to a literal value")
+	}
+	if assert.IsType(t, &BoundError{}, props["Project"]) {
+		assert.Contains(t, props["Project"].(*BoundError).Error.Error(), "unknown provider google")
+	}
+}
+
+func TestAccumulateErrors(t *testing.T) {
+	const source = `
+resource "aws_instance" "web" {
+  ami           = "${whisper("hello")}"
+  instance_type = "${shout("hello")}"
+  subnet_id     = "${aws_subnet.missing.id}"
+  key_name      = "web"
+}
+`
+	tree := module.NewTree("main", loadSource(t, source))
+
+	// By default, binding fails at the first interpolation that cannot be bound.
+	_, err := BuildGraph(tree, &BuildOptions{AllowMissingProviders: true, AllowMissingComments: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown resource aws_subnet.missing")
+	}
+
+	// When accumulating errors, every binding error is reported, and the graph is still built.
+	g, err := BuildGraph(tree, &BuildOptions{
+		AllowMissingProviders: true,
+		AllowMissingComments:  true,
+		AccumulateErrors:      true,
+	})
+	if assert.IsType(t, &multierror.Error{}, err) {
+		message := err.Error()
+		assert.Len(t, err.(*multierror.Error).Errors, 3)
+		assert.Contains(t, message, "ami: main.tf:3:22: NYI: call to whisper")
+		assert.Contains(t, message, "instance_type: main.tf:4:22: NYI: call to shout")
+		assert.Contains(t, message, "subnet_id: main.tf:5:22: unknown resource aws_subnet.missing")
+	}
+	if assert.NotNil(t, g) {
+		props := g.Resources["aws_instance.web"].Properties.Elements
+		assert.IsType(t, &BoundError{}, props["subnet_id"])
+		assert.Equal(t, &BoundLiteral{ExprType: TypeString, Value: "web"}, props["key_name"])
+	}
+}
+
+func TestVariableTypes(t *testing.T) {
+	const source = `
+variable "name" {}
+
+variable "typed_name" {
+  type = "string"
+}
+
+variable "names" {
+  type = "list"
+}
+
+variable "tags" {
+  type = "map"
+}
+
+variable "default_names" {
+  default = ["a", "b"]
+}
+
+variable "default_tags" {
+  default = {
+    a = "b"
+  }
+}
+
+resource "aws_instance" "web" {
+  count = "${length(var.names)}"
+  name  = "${var.names[count.index]}"
+  owner = "${lookup(var.tags, "owner")}"
+}
+`
+	g := buildSource(t, source)
+
+	// Variables without defaults have their declared types. Lists of unknown elements are used for declared lists, as
+	// Terraform does not record the element types of list variables.
+	assert.Equal(t, TypeString, g.Variables["name"].Type())
+	assert.Equal(t, TypeString, g.Variables["typed_name"].Type())
+	assert.Equal(t, TypeUnknown.ListOf(), g.Variables["names"].Type())
+	assert.Equal(t, TypeMap, g.Variables["tags"].Type())
+
+	// Variables with defaults have the types of their defaults.
+	assert.Equal(t, TypeString.ListOf(), g.Variables["default_names"].Type())
+	assert.Equal(t, TypeMap, g.Variables["default_tags"].Type())
+
+	// References to declared collections are typed accordingly, so they can be counted, indexed, and looked up.
+	web := g.Resources["aws_instance.web"]
+	if assert.IsType(t, &BoundCall{}, web.Count) {
+		assert.Equal(t, TypeUnknown.ListOf(), web.Count.(*BoundCall).Args[0].Type())
+	}
+	if assert.IsType(t, &BoundIndex{}, web.Properties.Elements["name"]) {
+		assert.Equal(t, TypeUnknown, web.Properties.Elements["name"].Type())
+	}
+	if assert.IsType(t, &BoundCall{}, web.Properties.Elements["owner"]) {
+		assert.Equal(t, TypeMap, web.Properties.Elements["owner"].(*BoundCall).Args[0].Type())
+	}
+}
@@ -29,8 +29,12 @@ const (
 	IntrinsicAsset = "__asset"
 	// IntrinsicCoerce is the name of the coerce intrinsic.
 	IntrinsicCoerce = "__coerce"
+	// IntrinsicGetProject is the name of the get project intrinsic.
+	IntrinsicGetProject = "__getProject"
 	// IntrinsicGetStack is the name of the get stack intrinsic.
 	IntrinsicGetStack = "__getStack"
+	// IntrinsicStringAsset is the name of the string asset intrinsic.
+	IntrinsicStringAsset = "__stringAsset"
 )
 
 // NewApplyCall returns a new IL tree that represents a call to IntrinsicApply.
@@ -106,6 +110,22 @@ func ParseAssetCall(c *BoundCall) (arg BoundExpr) {
 	return c.Args[0]
 }
 
+// NewStringAssetCall creates a call to IntrinsicStringAsset, which is used to represent an asset with the given
+// contents.
+func NewStringAssetCall(arg BoundExpr) *BoundCall {
+	return &BoundCall{
+		Func:     IntrinsicStringAsset,
+		ExprType: TypeUnknown,
+		Args:     []BoundExpr{arg},
+	}
+}
+
+// ParseStringAssetCall extracts the single argument expression from a call to the string asset intrinsic.
+func ParseStringAssetCall(c *BoundCall) (arg BoundExpr) {
+	contract.Assert(c.Func == IntrinsicStringAsset)
+	return c.Args[0]
+}
+
 // NewCoerceCall creates a call to IntrisicCoerce, which is used to represent the coercion of a value from one type to
 // another.
 func NewCoerceCall(value BoundExpr, toType Type) *BoundCall {
@@ -123,7 +143,28 @@ func ParseCoerceCall(c *BoundCall) (value BoundExpr, toType Type) {
 	return c.Args[0], c.ExprType
 }
 
+// NewGetProjectCall creates a call to IntrinsicGetProject.
+func NewGetProjectCall() *BoundCall {
+	return &BoundCall{Func: IntrinsicGetProject, ExprType: TypeString}
+}
+
 // NewGetStackCall creates a call to IntrinsicGetStack.
 func NewGetStackCall() *BoundCall {
 	return &BoundCall{Func: IntrinsicGetStack, ExprType: TypeString}
 }
+
+// terraformVariableIntrinsics maps the supported fields of `terraform.*` references to the intrinsics that produce
+// the equivalent Pulumi values. Terraform workspaces correspond to Pulumi stacks; `terraform.env` is the deprecated
+// name of `terraform.workspace`.
+var terraformVariableIntrinsics = map[string]string{
+	"env":       IntrinsicGetStack,
+	"workspace": IntrinsicGetStack,
+}
+
+// TerraformVariableIntrinsic returns the name of the intrinsic that produces the Pulumi equivalent of the given field
+// of a `terraform.*` reference, e.g. IntrinsicGetStack for `terraform.workspace`. The second return value is false if
+// the field is not supported.
+func TerraformVariableIntrinsic(field string) (string, bool) {
+	intrinsic, ok := terraformVariableIntrinsics[field]
+	return intrinsic, ok
+}
@@ -71,6 +71,17 @@ func TestIntrinsicAsset(t *testing.T) {
 	assert.Equal(t, arg, ParseAssetCall(c))
 }
 
+func TestIntrinsicStringAsset(t *testing.T) {
+	arg := &BoundLiteral{}
+
+	c := NewStringAssetCall(arg)
+	assert.Equal(t, IntrinsicStringAsset, c.Func)
+	assert.Equal(t, TypeUnknown, c.Type())
+	assert.Equal(t, 1, len(c.Args))
+
+	assert.Equal(t, arg, ParseStringAssetCall(c))
+}
+
 func TestIntrinsicCoerce(t *testing.T) {
 	value, toType := &BoundLiteral{}, TypeNumber
 
@@ -90,3 +101,23 @@ func TestIntrinsicGetStack(t *testing.T) {
 	assert.Equal(t, TypeString, c.Type())
 	assert.Equal(t, 0, len(c.Args))
 }
+
+func TestIntrinsicGetProject(t *testing.T) {
+	c := NewGetProjectCall()
+	assert.Equal(t, IntrinsicGetProject, c.Func)
+	assert.Equal(t, TypeString, c.Type())
+	assert.Equal(t, 0, len(c.Args))
+}
+
+func TestTerraformVariableIntrinsic(t *testing.T) {
+	intrinsic, ok := TerraformVariableIntrinsic("workspace")
+	assert.True(t, ok)
+	assert.Equal(t, IntrinsicGetStack, intrinsic)
+
+	intrinsic, ok = TerraformVariableIntrinsic("env")
+	assert.True(t, ok)
+	assert.Equal(t, IntrinsicGetStack, intrinsic)
+
+	_, ok = TerraformVariableIntrinsic("version")
+	assert.False(t, ok)
+}
@@ -38,13 +38,24 @@ func (r *applyRewriter) rewriteBoundVariableAccess(n *BoundVariableAccess) (Boun
 		return n, nil
 	}
 
+	// If an identical access is already an argument to the apply, reuse its resolved value.
+	for idx, arg := range r.applyArgs {
+		if isSameAccess(arg, n) {
+			return NewApplyArgCall(idx, n.Type()&^TypeOutput), nil
+		}
+	}
+
 	// Otherwise, append the access to the list of apply arguments and return an appropriate call to __applyArg.
-	//
-	// TODO: deduplicate multiple accesses to the same variable and field.
 	idx := len(r.applyArgs)
 	r.applyArgs = append(r.applyArgs, n)
 
-	return NewApplyArgCall(idx, n.Type().ElementType()), nil
+	return NewApplyArgCall(idx, n.Type()&^TypeOutput), nil
+}
+
+// isSameAccess returns true if the given variable accesses refer to the same field of the same node and have the same
+// type.
+func isSameAccess(a, b *BoundVariableAccess) bool {
+	return a.ILNode == b.ILNode && a.TFVar.FullKey() == b.TFVar.FullKey() && a.Type() == b.Type()
 }
 
 // rewriteRoot replaces the root node in a bound expression with a call to the __apply intrinsic if necessary.
@@ -113,7 +124,8 @@ func (r *applyRewriter) enterNode(n BoundNode) (BoundNode, error) {
 //         - otherwise, replace the root with a call to the __apply intrinstic. The first n arguments to this call are
 //           the elementss of the list of outputs. The final argument is the original root node.
 //     - otherwise, if the root is an output-typed variable access, replace the variable access with a call to the
-//       __applyArg instrinsic and append the access to the list of outputs.
+//       __applyArg instrinsic and append the access to the list of outputs. If an identical access is already in the
+//       list of outputs, the call to __applyArg refers to the existing output instead.
 //
 // As an example, this transforms the following expression:
 //     (output string
@@ -135,7 +147,6 @@ func (r *applyRewriter) enterNode(n BoundNode) (BoundNode, error) {
 //         (aws_eks_cluster.demo.certificate_authority.0.data output<unknown> *config.ResourceVariable)
 //         (aws_eks_cluster.demo.endpoint output<string> *config.ResourceVariable)
 //         (data.aws_region.current.name output<string> *config.ResourceVariable)
-//         (aws_eks_cluster.demo.endpoint output<string> *config.ResourceVariable)
 //         (output string
 //             "#!/bin/bash -xe\n\nCA_CERTIFICATE_DIRECTORY=/etc/kubernetes/pki\necho \""
 //             (call unknown __applyArg
@@ -153,7 +164,7 @@ func (r *applyRewriter) enterNode(n BoundNode) (BoundNode, error) {
 //             )
 //             ",g /etc/systemd/system/kubelet.servicesed -i s,MASTER_ENDPOINT,"
 //             (call string __applyArg
-//                 3
+//                 1
 //             )
 //             ",g /etc/systemd/system/kubelet.service"
 //         )
@@ -167,7 +178,7 @@ func RewriteApplies(n BoundNode) (BoundNode, error) {
 }
 
 // RewriteAssets transforms all arguments to Terraform properties that are projected as Pulumi assets or archives into
-// calls to the appropriate __asset or __archive intrinsic.
+// calls to the appropriate __asset, __stringAsset, or __archive intrinsic.
 func RewriteAssets(n BoundNode) (BoundNode, error) {
 	rewriter := func(n BoundNode) (BoundNode, error) {
 		m, ok := n.(*BoundMapProperty)
@@ -206,10 +217,20 @@ func RewriteAssets(n BoundNode) (BoundNode, error) {
 
 			if !isArchiveResource {
 				var call BoundExpr
-				if asset.Kind == tfbridge.FileArchive || asset.Kind == tfbridge.BytesArchive {
+				switch asset.Kind {
+				case tfbridge.FileArchive, tfbridge.BytesArchive:
 					call = NewArchiveCall(e)
-				} else {
-					call = NewAssetCall(e)
+				default:
+					// If the argument is the contents of a file, pass the file's path to the asset rather than its
+					// contents. Otherwise, the argument is a path for file assets and the asset's contents for bytes
+					// assets.
+					if c, ok := e.(*BoundCall); ok && c.Func == "file" {
+						call = NewAssetCall(c.Args[0])
+					} else if asset.Kind == tfbridge.BytesAsset {
+						call = NewStringAssetCall(e)
+					} else {
+						call = NewAssetCall(e)
+					}
 				}
 				m.Elements[k] = call
 			}
@@ -225,6 +246,84 @@ func RewriteAssets(n BoundNode) (BoundNode, error) {
 	return VisitBoundNode(n, IdentityVisitor, rewriter)
 }
 
+// acceptsResource returns true if the Pulumi type of a property with the given schemas accepts the resource itself in
+// place of its ID.
+func acceptsResource(sch Schemas, r *ResourceNode) bool {
+	if sch.Pulumi == nil {
+		return false
+	}
+	tok, ok := r.Tok()
+	if !ok {
+		return false
+	}
+	if string(sch.Pulumi.Type) == tok {
+		return true
+	}
+	for _, alt := range sch.Pulumi.AltTypes {
+		if string(alt) == tok {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteResourceReference strips the `id` field off of an access to a managed resource if the Pulumi type of the
+// property it is passed to accepts the resource itself. The schemas for a single resource are given by sch; those for
+// the elements of a splat are given by splatSch.
+func rewriteResourceReference(n BoundNode, sch, splatSch Schemas) BoundNode {
+	v, ok := n.(*BoundVariableAccess)
+	if !ok || len(v.Elements) != 1 || v.Elements[0] != "id" {
+		return n
+	}
+	tfVar, ok := v.TFVar.(*config.ResourceVariable)
+	if !ok || tfVar.Mode != config.ManagedResourceMode {
+		return n
+	}
+	r, ok := v.ILNode.(*ResourceNode)
+	if !ok {
+		return n
+	}
+
+	isSplat := tfVar.Multi && tfVar.Index == -1
+	if isSplat {
+		sch = splatSch
+	}
+	if !acceptsResource(sch, r) {
+		return n
+	}
+
+	// The resource itself is not an output, so the access must not be typed as one.
+	v.Elements, v.ExprType = nil, TypeUnknown
+	if isSplat {
+		v.ExprType = TypeUnknown.ListOf()
+	}
+	return v
+}
+
+// RewriteResourceReferences transforms references to the IDs of managed resources into references to the resources
+// themselves in cases where the Pulumi type of the property that receives the reference accepts the resource (e.g. an
+// `Input<string | SecurityGroup>`).
+func RewriteResourceReferences(n BoundNode) (BoundNode, error) {
+	rewriter := func(n BoundNode) (BoundNode, error) {
+		switch n := n.(type) {
+		case *BoundListProperty:
+			// Splats that appear as list elements are flattened into the list.
+			elemSch := n.Schemas.ElemSchemas()
+			for i := range n.Elements {
+				n.Elements[i] = rewriteResourceReference(n.Elements[i], elemSch, elemSch)
+			}
+		case *BoundMapProperty:
+			for k := range n.Elements {
+				propSch := n.Schemas.PropertySchemas(k)
+				n.Elements[k] = rewriteResourceReference(n.Elements[k], propSch, propSch.ElemSchemas())
+			}
+		}
+		return n, nil
+	}
+
+	return VisitBoundNode(n, IdentityVisitor, rewriter)
+}
+
 // FilterProperties removes any properties at the root of the given resource for which the given filter function
 // returns false.
 func FilterProperties(r *ResourceNode, filter func(key string, property BoundNode) bool) {
@@ -247,14 +346,7 @@ func MarkPromptDataSources(g *Graph) map[*ResourceNode]bool {
 		// First, check all data sources for output-typed inputs.
 		for _, r := range g.Resources {
 			if r.IsDataSource {
-				containsOutputs := false
-				_, err := VisitBoundNode(r.Properties, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
-					containsOutputs = containsOutputs || n.Type().IsOutput()
-					return n, nil
-				})
-				contract.Assert(err == nil)
-
-				if !containsOutputs && !promptDataSources[r] {
+				if !containsOutputs(r.Properties) && !promptDataSources[r] {
 					promptDataSources[r] = true
 					changed = true
 				}
@@ -266,22 +358,60 @@ func MarkPromptDataSources(g *Graph) map[*ResourceNode]bool {
 			return promptDataSources
 		}
 
-		// Otherwise, retype any data source accesses as appropriate.
-		err := VisitAllProperties(g, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
-			if n, ok := n.(*BoundVariableAccess); ok {
-				if r, ok := n.ILNode.(*ResourceNode); ok {
-					if promptDataSources[r] {
-						n.ExprType = n.ExprType & ^TypeOutput
+		// Otherwise, retype any data source accesses as appropriate. Indices into these accesses and conditionals that
+		// depend on them are retyped as well, as are accesses to locals whose values no longer contain outputs. Because
+		// locals may be visited after the nodes that refer to them, repeat this until no further nodes are retyped.
+		for retyped := true; retyped; {
+			retyped = false
+			err := VisitAllProperties(g, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
+				switch n := n.(type) {
+				case *BoundVariableAccess:
+					isPrompt := false
+					switch r := n.ILNode.(type) {
+					case *ResourceNode:
+						isPrompt = promptDataSources[r]
+					case *LocalNode:
+						isPrompt = !containsOutputs(r.Value)
+					}
+					if isPrompt && n.ExprType.IsOutput() {
+						n.ExprType, retyped = n.ExprType & ^TypeOutput, true
+					}
+				case *BoundIndex:
+					if !n.TargetExpr.Type().IsOutput() && n.ExprType.IsOutput() {
+						n.ExprType, retyped = n.ExprType & ^TypeOutput, true
+					}
+				case *BoundCall:
+					if n.Func == "element" && !n.Args[0].Type().IsOutput() && n.ExprType.IsOutput() {
+						n.ExprType, retyped = n.ExprType & ^TypeOutput, true
+					}
+				case *BoundConditional:
+					if !containsOutputs(n.CondExpr) && !n.TrueExpr.Type().IsOutput() && !n.FalseExpr.Type().IsOutput() &&
+						n.ExprType.IsOutput() {
+						n.ExprType, retyped = n.ExprType & ^TypeOutput, true
 					}
 				}
-			}
-			return n, nil
-		})
-		contract.Assert(err == nil)
+				return n, nil
+			})
+			contract.Assert(err == nil)
+		}
 	}
 
 }
 
+// containsOutputs returns true if any node in the given property tree is output-typed.
+func containsOutputs(n BoundNode) bool {
+	containsOutputs := false
+	err := WalkBoundNodes(n, func(n BoundNode) (WalkAction, error) {
+		if n.Type().IsOutput() {
+			containsOutputs = true
+			return WalkStop, nil
+		}
+		return WalkContinue, nil
+	}, ContinueWalker)
+	contract.Assert(err == nil)
+	return containsOutputs
+}
+
 // isBooleanValue rerturns true if the given expression produces a value that can be considered to be true or false.
 func isBooleanValue(expr BoundExpr) bool {
 	// Any expression that is boolean-typed is by definition a boolean value.
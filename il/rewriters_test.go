@@ -100,3 +100,35 @@ data "aws_subnet" "example" {
 `
 	runTest(flowEventualDataSource, map[string]bool{})
 }
+
+func TestRewriteAppliesDeduplicatesArgs(t *testing.T) {
+	const source = `
+resource "aws_vpc" "main" {}
+
+resource "aws_instance" "x" {
+  user_data = "${aws_vpc.main.id == "" ? "none" : aws_vpc.main.id}-${aws_vpc.main.arn}"
+}
+`
+	g := buildSource(t, source)
+
+	userData := g.Resources["aws_instance.x"].Properties.Elements["user_data"]
+	rewritten, err := RewriteApplies(userData)
+	assert.NoError(t, err)
+
+	// Both references to aws_vpc.main.id share a single apply argument.
+	args, then := ParseApplyCall(rewritten.(*BoundCall))
+	if assert.Len(t, args, 2) {
+		assert.Equal(t, "aws_vpc.main.id", args[0].TFVar.FullKey())
+		assert.Equal(t, "aws_vpc.main.arn", args[1].TFVar.FullKey())
+	}
+
+	var indices []int
+	_, err = VisitBoundNode(then, IdentityVisitor, func(n BoundNode) (BoundNode, error) {
+		if c, ok := n.(*BoundCall); ok && c.Func == IntrinsicApplyArg {
+			indices = append(indices, ParseApplyArgCall(c))
+		}
+		return n, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 0, 1}, indices)
+}
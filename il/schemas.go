@@ -31,15 +31,25 @@ type Schemas struct {
 	Pulumi *tfbridge.SchemaInfo
 }
 
-// PropertySchemas returns the Schemas for the child property with the given name. If the name is an integer, this
-// function returns the value of a call to ElemSchemas.
+// PropertySchemas returns the Schemas for the child property with the given name. If the name is an integer or a splat
+// ("*"), this function returns the value of a call to ElemSchemas.
 func (s Schemas) PropertySchemas(key string) Schemas {
 	var propSch Schemas
 
+	if key == "*" {
+		return s.ElemSchemas()
+	}
 	if _, err := strconv.ParseInt(key, 0, 0); err == nil {
 		return s.ElemSchemas()
 	}
 
+	// The keys of a map of primitives all share the map's element schema.
+	if s.TF != nil && s.TF.Type == schema.TypeMap {
+		if _, ok := s.TF.Elem.(*schema.Schema); ok {
+			return s.ElemSchemas()
+		}
+	}
+
 	if s.TFRes != nil && s.TFRes.Schema != nil {
 		propSch.TF = s.TFRes.Schema[key]
 	}
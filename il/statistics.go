@@ -0,0 +1,112 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package il
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// Statistics summarizes the contents of a set of bound graphs. These statistics can be used to gauge the completeness
+// of a conversion.
+type Statistics struct {
+	// Resources is the number of managed resources.
+	Resources int
+	// DataSources is the number of data sources.
+	DataSources int
+	// Modules is the number of module instantiations.
+	Modules int
+	// Interpolations is the number of bound interpolation expressions.
+	Interpolations int
+	// Functions maps from the name of each interpolation function that is called to the number of calls to that
+	// function.
+	Functions map[string]int
+	// Unsupported is the number of constructs that could not be bound, e.g. calls to unsupported functions.
+	Unsupported int
+}
+
+// ComputeStatistics computes statistics for the given graphs.
+func ComputeStatistics(graphs []*Graph) *Statistics {
+	stats := &Statistics{Functions: map[string]int{}}
+
+	// An interpolation is the root of a bound expression tree. Literals are not interpolations.
+	countFunctions := func(n BoundNode) (WalkAction, error) {
+		switch n := n.(type) {
+		case *BoundCall:
+			// Intrinsics are not interpolation functions.
+			if !strings.HasPrefix(n.Func, "__") {
+				stats.Functions[n.Func]++
+			}
+		case *BoundError:
+			stats.Unsupported++
+		}
+		return WalkContinue, nil
+	}
+	findInterpolations := func(n BoundNode) (WalkAction, error) {
+		e, ok := n.(BoundExpr)
+		if !ok {
+			return WalkContinue, nil
+		}
+		if _, isLiteral := e.(*BoundLiteral); !isLiteral {
+			stats.Interpolations++
+		}
+		return WalkSkipChildren, WalkBoundNodes(e, ContinueWalker, countFunctions)
+	}
+
+	for _, g := range graphs {
+		stats.Modules += len(g.Modules)
+		for _, r := range g.Resources {
+			if r.IsDataSource {
+				stats.DataSources++
+			} else {
+				stats.Resources++
+			}
+		}
+
+		err := WalkAllProperties(g, findInterpolations, ContinueWalker)
+		contract.Assert(err == nil)
+	}
+
+	return stats
+}
+
+// WriteSummary writes a human-readable summary of the statistics to the given writer.
+func (s *Statistics) WriteSummary(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "resources: %d\n", s.Resources)
+	fmt.Fprintf(&b, "data sources: %d\n", s.DataSources)
+	fmt.Fprintf(&b, "module instantiations: %d\n", s.Modules)
+	fmt.Fprintf(&b, "interpolations: %d\n", s.Interpolations)
+	if len(s.Functions) != 0 {
+		names := make([]string, 0, len(s.Functions))
+		for name := range s.Functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(&b, "function calls:\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "    %s: %d\n", name, s.Functions[name])
+		}
+	}
+	fmt.Fprintf(&b, "unsupported constructs: %d\n", s.Unsupported)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,61 @@
+package il
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatistics(t *testing.T) {
+	const source = `
+variable "names" {
+  default = ["a", "b"]
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}
+
+resource "aws_instance" "web" {
+  count = 2
+  ami   = "${data.aws_ami.ubuntu.id}"
+  tags  = {
+    Name  = "${element(var.names, count.index)}"
+    Names = "${join(",", var.names)}"
+    Hash  = "${sha1("web")}"
+  }
+}
+
+resource "aws_eip" "ip" {
+  instance = "${aws_instance.web.0.id}"
+}
+
+output "names" {
+  value = "${join(",", var.names)}"
+}
+`
+	g := buildSource(t, source)
+
+	stats := ComputeStatistics([]*Graph{g})
+	assert.Equal(t, 2, stats.Resources)
+	assert.Equal(t, 1, stats.DataSources)
+	assert.Equal(t, 0, stats.Modules)
+	assert.Equal(t, 6, stats.Interpolations)
+	assert.Equal(t, map[string]int{"element": 1, "join": 2, "sha1": 1}, stats.Functions)
+	assert.Equal(t, 1, stats.Unsupported)
+
+	var b strings.Builder
+	err := stats.WriteSummary(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, `resources: 2
+data sources: 1
+module instantiations: 0
+interpolations: 6
+function calls:
+    element: 1
+    join: 2
+    sha1: 1
+unsupported constructs: 1
+`, b.String())
+}
@@ -0,0 +1,62 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+
+	"github.com/pulumi/tf2pulumi/internal/config/module"
+)
+
+// LoadModuleTree writes the given files to a temporary directory and loads the module tree rooted at that directory.
+// The files are keyed by their slash-separated paths relative to the root module, e.g. "network/main.tf" for the
+// source of a child module with the source "./network". The directory is removed when the test completes.
+func LoadModuleTree(t *testing.T, files map[string]string) *module.Tree {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	t.Cleanup(func() {
+		contract.IgnoreError(os.RemoveAll(dir))
+	})
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("could not create directory for %s: %v", name, err)
+		}
+		if err = ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatalf("could not create %s: %v", name, err)
+		}
+	}
+
+	tree, err := module.NewTreeModule("", dir)
+	if err != nil {
+		t.Fatalf("could not create module tree: %v", err)
+	}
+	err = tree.Load(&module.Storage{
+		StorageDir: filepath.Join(dir, ".terraform", "modules"),
+		Mode:       module.GetModeGet,
+	})
+	if err != nil {
+		t.Fatalf("could not load module tree: %v", err)
+	}
+	return tree
+}
@@ -0,0 +1,59 @@
+// Copyright 2016-2020, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil contains helpers that are shared by the tests of tf2pulumi's packages.
+package testutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-terraform-bridge/v2/pkg/tfbridge"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
+)
+
+// ProviderInfoSource is a provider info source that serves provider information from a fixed map.
+type ProviderInfoSource map[string]*tfbridge.ProviderInfo
+
+// GetProviderInfo returns the provider information for the named provider, or an error if the source has no
+// information for that provider.
+func (s ProviderInfoSource) GetProviderInfo(tfProviderName string) (*tfbridge.ProviderInfo, error) {
+	if info, ok := s[tfProviderName]; ok {
+		return info, nil
+	}
+	return nil, errors.Errorf("no provider info for %s", tfProviderName)
+}
+
+// TestProvider returns a provider info source that serves information for a "test" provider with the given resources.
+// Each resource is mapped to a type in the provider's index module whose name is derived from the resource's name, e.g.
+// "test_security_group" is mapped to "test:index/securityGroup:SecurityGroup". Tests that need additional information
+// about a resource (e.g. field overrides) may modify the returned resource info.
+func TestProvider(resources map[string]*schema.Resource) ProviderInfoSource {
+	infos := make(map[string]*tfbridge.ResourceInfo)
+	for name := range resources {
+		resourceType := strings.TrimPrefix(name, "test_")
+		module := tfbridge.TerraformToPulumiName(resourceType, nil, nil, false)
+		typ := tfbridge.TerraformToPulumiName(resourceType, nil, nil, true)
+		infos[name] = &tfbridge.ResourceInfo{Tok: tokens.Type(fmt.Sprintf("test:index/%s:%s", module, typ))}
+	}
+
+	return ProviderInfoSource{
+		"test": &tfbridge.ProviderInfo{
+			P:         &schema.Provider{ResourcesMap: resources},
+			Resources: infos,
+		},
+	}
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"archive/tar"
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -24,12 +25,14 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pulumi/tf2pulumi/convert"
+	"github.com/pulumi/tf2pulumi/gen"
+	"github.com/pulumi/tf2pulumi/il"
 	"github.com/pulumi/tf2pulumi/version"
 )
 
 func main() {
 	var opts convert.Options
-	resourceNameProperty, filterAutoNames, tarout := "", false, false
+	resourceNameProperty, filterAutoNames, tarout, stats, manifestPath := "", false, false, false, ""
 
 	rootCmd := &cobra.Command{
 		Use:   "tf2pulumi",
@@ -48,6 +51,12 @@ Pulumi TypeScript program that describes the same resource graph.`,
 
 			opts.FilterResourceNames = resourceNameProperty != "" || filterAutoNames
 			opts.ResourceNameProperty = resourceNameProperty
+			if stats {
+				opts.Statistics = &il.Statistics{}
+			}
+			if manifestPath != "" {
+				opts.Manifest = &gen.Manifest{}
+			}
 
 			files, diags, err := convert.Convert(opts)
 			if err != nil {
@@ -58,6 +67,20 @@ Pulumi TypeScript program that describes the same resource graph.`,
 					return err
 				}
 			}
+			if opts.Statistics != nil {
+				if err := opts.Statistics.WriteSummary(os.Stderr); err != nil {
+					return err
+				}
+			}
+			if opts.Manifest != nil {
+				var b bytes.Buffer
+				if err := opts.Manifest.WriteJSON(&b); err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(manifestPath, b.Bytes(), 0600); err != nil {
+					return err
+				}
+			}
 
 			if tarout {
 				w := tar.NewWriter(os.Stdout)
@@ -93,6 +116,18 @@ Pulumi TypeScript program that describes the same resource graph.`,
 		"allows code generation to continue if there are errors extracting comments")
 	flag.BoolVar(&opts.AnnotateNodesWithLocations, "record-locations", false,
 		"annotate the generated code with original source locations for each resource")
+	flag.BoolVar(&opts.CheckDelimiters, "check-delimiters", false,
+		"check that the delimiters in the generated TypeScript are terminated and balanced")
+	flag.BoolVar(&opts.InlineFiles, "inline-files", false,
+		"replace calls to file() whose paths are known with the contents of the files")
+	flag.BoolVar(&opts.AnnotateTypes, "annotate-types", false,
+		"annotate each generated expression with its inferred type (for debugging)")
+	flag.BoolVar(&opts.StrictBinding, "strict-binding", false,
+		"stop at the first interpolation that cannot be bound instead of reporting all binding errors together")
+	flag.BoolVar(&stats, "stats", false,
+		"print a summary of conversion statistics to stderr")
+	flag.StringVar(&manifestPath, "manifest", "",
+		"when set, a JSON mapping from Terraform resource addresses to Pulumi resources is written to the given file")
 	flag.BoolVar(&tarout, "tar", false,
 		"generate a TAR archive to stdout instead of writing to the filesystem")
 	flag.StringVar(&resourceNameProperty, "filter-resource-names", "",